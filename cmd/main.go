@@ -5,70 +5,151 @@ import (
 	"fmt"
 	"github.com/fasthttp/router"
 	_ "github.com/jackc/pgx/v5"
-	"github.com/labstack/echo/v4"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/valyala/fasthttp"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"technopark-db-semester-project/config"
+	"technopark-db-semester-project/delivery"
+	"technopark-db-semester-project/logger"
+	"technopark-db-semester-project/metrics"
+	"technopark-db-semester-project/middleware"
 	"technopark-db-semester-project/system"
 	"time"
 )
 
-func addTimeLog(next echo.HandlerFunc) echo.HandlerFunc {
-	return func(c echo.Context) error {
-		log.Println("Start. Request:", c.Request().RequestURI)
-		now := time.Now()
-		result := next(c)
-		duration := time.Now().Sub(now)
+func main() {
+	//router := echo.New()
 
-		log.Println("End. Request:", c.Request().RequestURI, "time:", duration, "Status:", c.Response().Status)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
 
-		return result
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalln("config error:", err)
 	}
-}
+	logger.SetLevel(cfg.LogLevel)
 
-func main() {
-	//router := echo.New()
+	registry := metrics.NewRegistry()
+	httpMetrics := metrics.NewHTTPMetrics(registry)
 
-	db := system.InitDb()
-	defer db.Close()
-	userRepo, forumRepo, threadRepo, postRepo, voteRepo, serviceRepo := system.InitRepos(db)
-	userHandler, forumHandler, threadHandler, postHandler, voteHandler, serviceHandler := system.InitHandlers(userRepo, forumRepo, threadRepo, postRepo, voteRepo, serviceRepo)
+	var db *pgxpool.Pool
+	if cfg.Storage == config.StoragePostgres {
+		db = system.InitDb(&cfg.Database)
+		metrics.RegisterPoolStats(registry, db)
+	}
+	repos := system.InstrumentRepos(registry, system.InitRepos(cfg, db, registry))
+	handlers := system.InitHandlers(cfg, repos)
 	fasthttpRouter := router.New()
+	// шаблон маршрута нужен для метки route в метриках
+	fasthttpRouter.SaveMatchedRoutePath = true
 
 	// api routes
 
-	fasthttpRouter.POST("/api/forum/create", forumHandler.Create)
-	fasthttpRouter.GET("/api/forum/{slug}/details", forumHandler.Get)
-	fasthttpRouter.POST("/api/forum/{slug}/create", threadHandler.Create)
-	fasthttpRouter.GET("/api/forum/{slug}/users", forumHandler.GetUsers)
-	fasthttpRouter.GET("/api/forum/{slug}/threads", forumHandler.GetThreads)
-	fasthttpRouter.GET("/api/post/{id}/details", postHandler.Get)
-	fasthttpRouter.POST("/api/post/{id}/details", postHandler.Update)
-
-	fasthttpRouter.POST("/api/thread/{slug_or_id}/create", postHandler.Create)
-	fasthttpRouter.GET("/api/thread/{slug_or_id}/details", threadHandler.Get)
-	fasthttpRouter.POST("/api/thread/{slug_or_id}/details", threadHandler.Update)
-	fasthttpRouter.GET("/api/thread/{slug_or_id}/posts", threadHandler.GetPosts)
-	fasthttpRouter.POST("/api/thread/{slug_or_id}/vote", voteHandler.Create)
-	fasthttpRouter.POST("/api/user/{nickname}/create", userHandler.Create)
-	fasthttpRouter.GET("/api/user/{nickname}/profile", userHandler.Get)
-	fasthttpRouter.POST("/api/user/{nickname}/profile", userHandler.Update)
-
-	fasthttpRouter.GET("/api/service/status", serviceHandler.GetInfo)
-	fasthttpRouter.POST("/api/service/clear", serviceHandler.Clear)
-
-	ctx := context.Background()
-
-	err := fasthttp.ListenAndServe(
-		"0.0.0.0:5000",
-		func(fasthttpCtx *fasthttp.RequestCtx) {
+	fasthttpRouter.POST("/api/forum/create", handlers.Forum.Create)
+	fasthttpRouter.GET("/api/forum/{slug}/details", handlers.Forum.Get)
+	fasthttpRouter.POST("/api/forum/{slug}/create", handlers.Thread.Create)
+	fasthttpRouter.GET("/api/forum/{slug}/users", handlers.Forum.GetUsers)
+	fasthttpRouter.GET("/api/forum/{slug}/threads", handlers.Forum.GetThreads)
+	fasthttpRouter.GET("/api/forum/{slug}/roles", handlers.Role.GetAll)
+	fasthttpRouter.POST("/api/forum/{slug}/roles", handlers.Role.Set)
+	fasthttpRouter.POST("/api/forum/{slug}/subscription", handlers.Notification.SubscribeForum)
+	fasthttpRouter.DELETE("/api/forum/{slug}/subscription", handlers.Notification.UnsubscribeForum)
+	fasthttpRouter.GET("/api/post/{id}/details", handlers.Post.Get)
+	fasthttpRouter.POST("/api/post/{id}/details", handlers.Post.Update)
+	fasthttpRouter.DELETE("/api/post/{id}", handlers.Post.Delete)
+	fasthttpRouter.GET("/api/post/{id}/history", handlers.Post.History)
+	fasthttpRouter.GET("/api/post/{id}/diff", handlers.Post.Diff)
+	fasthttpRouter.POST("/api/post/{id}/split", handlers.Thread.Split)
+	fasthttpRouter.POST("/api/post/{id}/vote", handlers.Vote.CreateForPost)
+
+	fasthttpRouter.POST("/api/thread/{slug_or_id}/create", handlers.Post.Create)
+	fasthttpRouter.GET("/api/thread/{slug_or_id}/details", handlers.Thread.Get)
+	fasthttpRouter.POST("/api/thread/{slug_or_id}/details", handlers.Thread.Update)
+	fasthttpRouter.GET("/api/thread/{slug_or_id}/posts", handlers.Thread.GetPosts)
+	fasthttpRouter.POST("/api/thread/{slug_or_id}/vote", handlers.Vote.Create)
+	fasthttpRouter.GET("/api/thread/{slug_or_id}/votes", handlers.Vote.GetByThread)
+	fasthttpRouter.POST("/api/thread/{slug_or_id}/state", handlers.Thread.SetState)
+	fasthttpRouter.POST("/api/thread/{slug_or_id}/pin", handlers.Thread.SetPin)
+	fasthttpRouter.POST("/api/thread/{slug_or_id}/move", handlers.Thread.Move)
+	fasthttpRouter.POST("/api/thread/{slug_or_id}/merge", handlers.Thread.Merge)
+	fasthttpRouter.POST("/api/thread/{slug_or_id}/subscription", handlers.Notification.SubscribeThread)
+	fasthttpRouter.DELETE("/api/thread/{slug_or_id}/subscription", handlers.Notification.UnsubscribeThread)
+	fasthttpRouter.POST("/api/user/login", handlers.Auth.Login)
+	fasthttpRouter.POST("/api/user/logout", handlers.Auth.Logout)
+	fasthttpRouter.POST("/api/user/{nickname}/create", handlers.User.Create)
+	fasthttpRouter.GET("/api/user/{nickname}/profile", handlers.User.Get)
+	fasthttpRouter.POST("/api/user/{nickname}/profile", handlers.User.Update)
+	fasthttpRouter.POST("/api/user/{nickname}/password", handlers.Auth.SetPassword)
+	fasthttpRouter.POST("/api/user/{nickname}/password/reset", handlers.Auth.ResetPassword)
+	fasthttpRouter.GET("/api/user/{nickname}/votes", handlers.Vote.GetByUser)
+	fasthttpRouter.GET("/api/user/{nickname}/mentions", handlers.Mention.GetByUser)
+	fasthttpRouter.GET("/api/user/{nickname}/subscriptions", handlers.Notification.GetSubscriptions)
+	fasthttpRouter.GET("/api/user/{nickname}/notifications", handlers.Notification.GetNotifications)
+	fasthttpRouter.GET("/api/user/{nickname}/notifications/unread", handlers.Notification.CountUnread)
+	fasthttpRouter.POST("/api/user/{nickname}/notifications/read", handlers.Notification.MarkRead)
+
+	fasthttpRouter.GET("/api/service/status", handlers.Service.GetInfo)
+	fasthttpRouter.POST("/api/service/clear", handlers.Service.Clear)
+
+	fasthttpRouter.GET("/api/search", handlers.Search.Search)
+
+	fasthttpRouter.GET("/metrics", metrics.Handler(registry))
+
+	// общий контекст всех запросов, отменяется при остановке сервера
+	ctx, cancel := context.WithCancel(context.Background())
+
+	handler := middleware.Chain(
+		fasthttpRouter.Handler,
+		middleware.RequestId,
+		middleware.AccessLog(cfg.AccessLogSample),
+		httpMetrics.Middleware,
+		middleware.Auth(handlers.Auth.Resolve, delivery.WriteError),
+	)
+
+	server := &fasthttp.Server{
+		Handler: func(fasthttpCtx *fasthttp.RequestCtx) {
 			fasthttpCtx.SetUserValue("ctx", ctx)
-			fasthttpRouter.Handler(fasthttpCtx)
+			handler(fasthttpCtx)
 		},
-	)
+		ReadTimeout:  time.Duration(cfg.Server.ReadTimeout),
+		WriteTimeout: time.Duration(cfg.Server.WriteTimeout),
+		IdleTimeout:  time.Duration(cfg.Server.IdleTimeout),
 
-	if err != nil {
-		fmt.Println(err)
+		CloseOnShutdown: true,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info("listening on", cfg.Server.Addr)
+		serverErr <- server.ListenAndServe(cfg.Server.Addr)
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case err = <-serverErr:
+		if err != nil {
+			fmt.Println(err)
+		}
+	case sig := <-signals:
+		logger.Info("received", sig, "- shutting down")
+		if err = system.ShutdownServer(server, time.Duration(cfg.Server.ShutdownTimeout)); err != nil {
+			logger.Warn(err)
+		}
+	}
+
+	// отменяем контекст, чтобы оставшиеся запросы к базе прервались и вернули соединения в пул
+	cancel()
+	if db != nil {
+		db.Close()
 	}
+	logger.Info("stopped")
 	/*
 		if err := router.Start("0.0.0.0:5000"); err != nil {
 			log.Fatalln("server error:", err)
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"technopark-db-semester-project/config"
+	"technopark-db-semester-project/db/migrations"
+	"technopark-db-semester-project/system"
+)
+
+const migrateUsage = "usage: main migrate up|down [steps]|status [flags]"
+
+// runMigrate - подкоманда migrate: up применяет все новые миграции, down откатывает последние steps (по умолчанию 1)
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		log.Fatalln(migrateUsage)
+	}
+	action, rest := args[0], args[1:]
+	if action != "up" && action != "down" && action != "status" {
+		log.Fatalln(migrateUsage)
+	}
+
+	steps := 1
+	if action == "down" && len(rest) > 0 {
+		if parsed, err := strconv.Atoi(rest[0]); err == nil {
+			if parsed < 1 {
+				log.Fatalln("steps must be positive")
+			}
+			steps = parsed
+			rest = rest[1:]
+		}
+	}
+
+	cfg, err := config.Load(rest)
+	if err != nil {
+		log.Fatalln("config error:", err)
+	}
+	if cfg.Storage != config.StoragePostgres {
+		log.Fatalln("migrations are only supported for storage", config.StoragePostgres)
+	}
+
+	db := system.InitDb(&cfg.Database)
+	defer db.Close()
+
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		log.Fatalln("load migrations error:", err)
+	}
+
+	ctx := context.Background()
+	switch action {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatalln("migrate up error:", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("no new migrations")
+		}
+	case "down":
+		reverted, err := migrator.Down(ctx, steps)
+		for _, migration := range reverted {
+			fmt.Printf("reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatalln("migrate down error:", err)
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalln("migrate status error:", err)
+		}
+		for _, status := range statuses {
+			if status.Applied {
+				fmt.Printf("%04d_%s\tapplied at %s\n", status.Version, status.Name, status.AppliedAt.Format("2006-01-02 15:04:05"))
+			} else {
+				fmt.Printf("%04d_%s\tpending\n", status.Version, status.Name)
+			}
+		}
+	}
+}
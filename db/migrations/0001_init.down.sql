@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS Votes;
+DROP TABLE IF EXISTS ForumUsers;
+DROP TABLE IF EXISTS Posts;
+DROP TABLE IF EXISTS Threads;
+DROP TABLE IF EXISTS Forums;
+DROP TABLE IF EXISTS Users;
+
+DROP FUNCTION IF EXISTS forum_users_update();
+DROP FUNCTION IF EXISTS set_post_parent_path();
+DROP FUNCTION IF EXISTS add_forum_thread_count();
+DROP FUNCTION IF EXISTS add_forum_posts_count();
+DROP FUNCTION IF EXISTS add_thread_vote();
+DROP FUNCTION IF EXISTS update_thread_vote();
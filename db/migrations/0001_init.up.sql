@@ -1,22 +1,5 @@
 CREATE EXTENSION IF NOT EXISTS citext;
 
-DROP TABLE Users;
-DROP TABLE Forums;
-DROP TABLE Threads;
-DROP TABLE Posts;
-DROP TABLE ForumUsers;
-DROP TABLE Votes;
-
-DROP INDEX for_search_by_slug;
-DROP INDEX for_search_by_forum;
-DROP INDEX for_search_threads_on_forum;
-DROP INDEX for_tree_search;
-DROP INDEX for_parent_tree_search;
-DROP INDEX user_nickname_hash;
-DROP INDEX search_user_vote;
-DROP INDEX forum_slug_hash;
-DROP INDEX forum_users_forum;
-
 -- Tables
 CREATE UNLOGGED TABLE if not exists Users
 (
@@ -144,42 +127,49 @@ END;
 $update_thread_vote$ LANGUAGE plpgsql;
 
 -- Triggers
+DROP TRIGGER IF EXISTS forum_users_for_post ON Posts;
 CREATE TRIGGER forum_users_for_post
     AFTER INSERT
     ON Posts
     FOR EACH ROW
 EXECUTE PROCEDURE forum_users_update();
 
+DROP TRIGGER IF EXISTS forum_users_for_thread ON Threads;
 CREATE TRIGGER forum_users_for_thread
     AFTER INSERT
     ON Threads
     FOR EACH ROW
 EXECUTE PROCEDURE forum_users_update();
 
+DROP TRIGGER IF EXISTS set_post_parent_path_trigger ON Posts;
 CREATE TRIGGER set_post_parent_path_trigger
     BEFORE INSERT
     ON Posts
     FOR EACH ROW
 EXECUTE PROCEDURE set_post_parent_path();
 
+DROP TRIGGER IF EXISTS add_forum_thread_count_trigger ON Threads;
 CREATE TRIGGER add_forum_thread_count_trigger
     AFTER INSERT
     ON Threads
     FOR EACH ROW
 EXECUTE PROCEDURE add_forum_thread_count();
 
+DROP TRIGGER IF EXISTS add_forum_posts_count_trigger ON Posts;
 CREATE TRIGGER add_forum_posts_count_trigger
     AFTER INSERT
     ON Posts
     FOR EACH ROW
 EXECUTE PROCEDURE add_forum_posts_count();
 
+DROP TRIGGER IF EXISTS add_thread_vote_trigger ON Votes;
 CREATE TRIGGER add_thread_vote_trigger
     AFTER INSERT
     ON Votes
     FOR EACH ROW
 EXECUTE PROCEDURE add_thread_vote();
 
+DROP TRIGGER IF EXISTS update_thread_vote_trigger ON Votes;
 CREATE TRIGGER update_thread_vote_trigger
     AFTER UPDATE
     ON Votes
@@ -218,5 +208,3 @@ CREATE INDEX IF NOT EXISTS forum_slug_hash ON Forums using hash (slug);
 
 -- ForumUsers
 CREATE INDEX IF NOT EXISTS forum_users_forum ON ForumUsers (forum, nickname);
-
-VACUUM ANALYZE;
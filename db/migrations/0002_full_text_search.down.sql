@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS threads_search;
+DROP INDEX IF EXISTS posts_search;
+
+ALTER TABLE Threads DROP COLUMN IF EXISTS search;
+ALTER TABLE Posts DROP COLUMN IF EXISTS search;
@@ -0,0 +1,13 @@
+-- Полнотекстовый поиск. Конфигурация simple - без стемминга, чтобы одинаково работать с русским и английским текстом
+
+ALTER TABLE Posts
+    ADD COLUMN IF NOT EXISTS search tsvector GENERATED ALWAYS AS (to_tsvector('simple', message)) STORED;
+
+-- заголовок ветки весит больше текста
+ALTER TABLE Threads
+    ADD COLUMN IF NOT EXISTS search tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', title), 'A') || setweight(to_tsvector('simple', message), 'B')
+        ) STORED;
+
+CREATE INDEX IF NOT EXISTS posts_search ON Posts USING gin (search);
+CREATE INDEX IF NOT EXISTS threads_search ON Threads USING gin (search);
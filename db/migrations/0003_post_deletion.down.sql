@@ -0,0 +1 @@
+ALTER TABLE Posts DROP COLUMN IF EXISTS isDeleted;
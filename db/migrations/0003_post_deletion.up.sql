@@ -0,0 +1,3 @@
+-- удалённый пост остаётся в таблице как надгробие, чтобы не ломать parent_path его потомков
+ALTER TABLE Posts
+    ADD COLUMN IF NOT EXISTS isDeleted boolean NOT NULL DEFAULT false;
@@ -0,0 +1,10 @@
+-- каждая правка поста сохраняет предыдущий текст, чтобы модераторы могли посмотреть, что было изменено
+CREATE UNLOGGED TABLE IF NOT EXISTS PostRevisions
+(
+    post     bigint             NOT NULL REFERENCES Posts (id) ON DELETE CASCADE,
+    revision integer            NOT NULL, -- номер правки поста, начиная с 1
+    editor   citext COLLATE "C" NOT NULL REFERENCES Users (nickname),
+    message  text               NOT NULL, -- текст поста до этой правки
+    created  timestamptz        NOT NULL DEFAULT now(),
+    PRIMARY KEY (post, revision)
+);
@@ -0,0 +1 @@
+ALTER TABLE Threads DROP COLUMN IF EXISTS state;
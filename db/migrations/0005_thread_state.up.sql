@@ -0,0 +1,3 @@
+-- open - можно писать и голосовать, closed - новые сообщения запрещены, locked - запрещены и сообщения, и голоса
+ALTER TABLE Threads
+    ADD COLUMN IF NOT EXISTS state text NOT NULL DEFAULT 'open' CHECK (state IN ('open', 'closed', 'locked'));
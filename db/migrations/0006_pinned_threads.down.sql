@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS for_announcement_threads;
+DROP INDEX IF EXISTS for_pinned_threads_on_forum;
+
+ALTER TABLE Threads
+    DROP COLUMN IF EXISTS announcement,
+    DROP COLUMN IF EXISTS pinned;
@@ -0,0 +1,7 @@
+-- pinned - приоритет закрепления ветки в своём форуме (0 - не закреплена), announcement - ветка показывается во всех форумах
+ALTER TABLE Threads
+    ADD COLUMN IF NOT EXISTS pinned       integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS announcement boolean NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS for_pinned_threads_on_forum ON Threads (forum) WHERE pinned > 0;
+CREATE INDEX IF NOT EXISTS for_announcement_threads ON Threads (created) WHERE announcement;
@@ -0,0 +1,8 @@
+-- назначенные роли пользователей в форумах. Владелец форума берётся из Forums."user", без записи - member
+CREATE UNLOGGED TABLE IF NOT EXISTS ForumRoles
+(
+    forum    citext             NOT NULL REFERENCES Forums (slug),
+    nickname citext COLLATE "C" NOT NULL REFERENCES Users (nickname),
+    role     text               NOT NULL CHECK (role IN ('moderator', 'member', 'banned')),
+    PRIMARY KEY (forum, nickname)
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS Sessions;
+
+ALTER TABLE Users
+    DROP COLUMN IF EXISTS password_hash;
@@ -0,0 +1,13 @@
+-- у пользователей, созданных до появления паролей, password_hash пустой и войти они не могут
+ALTER TABLE Users
+    ADD COLUMN IF NOT EXISTS password_hash bytea;
+
+-- хранится sha256 токена, чтобы утечка таблицы не давала готовых токенов
+CREATE UNLOGGED TABLE IF NOT EXISTS Sessions
+(
+    token_hash bytea              NOT NULL PRIMARY KEY,
+    nickname   citext COLLATE "C" NOT NULL REFERENCES Users (nickname),
+    expires    timestamptz        NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS for_sessions_expiry ON Sessions (expires);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS for_score_tree_search;
+
+DROP TABLE IF EXISTS PostVotes;
+DROP FUNCTION IF EXISTS add_post_vote();
+DROP FUNCTION IF EXISTS update_post_vote();
+
+ALTER TABLE Posts
+    DROP COLUMN IF EXISTS votes;
@@ -0,0 +1,44 @@
+ALTER TABLE Posts
+    ADD COLUMN IF NOT EXISTS votes integer NOT NULL DEFAULT 0;
+
+-- голоса за отдельные посты, по одному на пользователя, как Votes для веток
+CREATE UNLOGGED TABLE IF NOT EXISTS PostVotes
+(
+    post     bigint             NOT NULL REFERENCES Posts (id) ON DELETE CASCADE,
+    nickname citext COLLATE "C" NOT NULL REFERENCES Users (nickname),
+    voice    integer            NOT NULL,
+    PRIMARY KEY (post, nickname)
+);
+
+CREATE OR REPLACE FUNCTION add_post_vote() RETURNS TRIGGER AS
+$add_post_vote$
+BEGIN
+    UPDATE Posts SET votes = Posts.votes + new.voice WHERE id = new.post;
+    return new;
+END;
+$add_post_vote$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION update_post_vote() RETURNS TRIGGER AS
+$update_post_vote$
+BEGIN
+    UPDATE Posts SET votes = Posts.votes + (new.voice - old.voice) WHERE id = new.post;
+    return new;
+END;
+$update_post_vote$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS add_post_vote_trigger ON PostVotes;
+CREATE TRIGGER add_post_vote_trigger
+    AFTER INSERT
+    ON PostVotes
+    FOR EACH ROW
+EXECUTE PROCEDURE add_post_vote();
+
+DROP TRIGGER IF EXISTS update_post_vote_trigger ON PostVotes;
+CREATE TRIGGER update_post_vote_trigger
+    AFTER UPDATE
+    ON PostVotes
+    FOR EACH ROW
+EXECUTE PROCEDURE update_post_vote();
+
+-- сортировка по рейтингу обходит дерево от корней по parent
+CREATE INDEX IF NOT EXISTS for_score_tree_search ON Posts (thread, parent);
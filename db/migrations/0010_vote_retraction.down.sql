@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS for_thread_voters;
+
+DROP TRIGGER IF EXISTS delete_thread_vote_trigger ON Votes;
+DROP TRIGGER IF EXISTS delete_post_vote_trigger ON PostVotes;
+DROP FUNCTION IF EXISTS delete_thread_vote();
+DROP FUNCTION IF EXISTS delete_post_vote();
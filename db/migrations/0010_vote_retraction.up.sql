@@ -0,0 +1,33 @@
+-- голос с voice = 0 удаляет строку, счётчики веток и постов пересчитываются триггерами
+CREATE OR REPLACE FUNCTION delete_thread_vote() RETURNS TRIGGER AS
+$delete_thread_vote$
+BEGIN
+    UPDATE Threads SET votes = Threads.votes - old.voice WHERE id = old.thread;
+    return old;
+END;
+$delete_thread_vote$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION delete_post_vote() RETURNS TRIGGER AS
+$delete_post_vote$
+BEGIN
+    UPDATE Posts SET votes = Posts.votes - old.voice WHERE id = old.post;
+    return old;
+END;
+$delete_post_vote$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS delete_thread_vote_trigger ON Votes;
+CREATE TRIGGER delete_thread_vote_trigger
+    AFTER DELETE
+    ON Votes
+    FOR EACH ROW
+EXECUTE PROCEDURE delete_thread_vote();
+
+DROP TRIGGER IF EXISTS delete_post_vote_trigger ON PostVotes;
+CREATE TRIGGER delete_post_vote_trigger
+    AFTER DELETE
+    ON PostVotes
+    FOR EACH ROW
+EXECUTE PROCEDURE delete_post_vote();
+
+-- список проголосовавших в ветке
+CREATE INDEX IF NOT EXISTS for_thread_voters ON Votes (thread, nickname);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS Notifications;
+DROP TABLE IF EXISTS ForumSubscriptions;
+DROP TABLE IF EXISTS ThreadSubscriptions;
@@ -0,0 +1,31 @@
+-- подписки на ветки и форумы. При слиянии веток подписки переносятся в ветку, в которую слили
+CREATE UNLOGGED TABLE IF NOT EXISTS ThreadSubscriptions
+(
+    thread   bigint             NOT NULL REFERENCES Threads (id) ON DELETE CASCADE,
+    nickname citext COLLATE "C" NOT NULL REFERENCES Users (nickname),
+    PRIMARY KEY (thread, nickname)
+);
+
+CREATE UNLOGGED TABLE IF NOT EXISTS ForumSubscriptions
+(
+    forum    citext             NOT NULL REFERENCES Forums (slug),
+    nickname citext COLLATE "C" NOT NULL REFERENCES Users (nickname),
+    PRIMARY KEY (forum, nickname)
+);
+
+-- уведомления о новых сообщениях. Ветка, форум и автор берутся из Posts при чтении, поэтому после переноса и слияния веток они актуальны
+CREATE UNLOGGED TABLE IF NOT EXISTS Notifications
+(
+    id       bigserial          NOT NULL PRIMARY KEY,
+    nickname citext COLLATE "C" NOT NULL REFERENCES Users (nickname),
+    kind     text               NOT NULL CHECK (kind IN ('reply', 'thread', 'forum')),
+    post     bigint             NOT NULL REFERENCES Posts (id) ON DELETE CASCADE,
+    isRead   boolean            NOT NULL DEFAULT false
+);
+
+CREATE INDEX IF NOT EXISTS for_user_subscriptions ON ThreadSubscriptions (nickname);
+CREATE INDEX IF NOT EXISTS for_user_forum_subscriptions ON ForumSubscriptions (nickname);
+CREATE INDEX IF NOT EXISTS for_user_notifications ON Notifications (nickname, id);
+CREATE INDEX IF NOT EXISTS for_unread_notifications ON Notifications (nickname) WHERE NOT isRead;
+-- каскадное удаление постов ищет их уведомления
+CREATE INDEX IF NOT EXISTS for_post_notifications ON Notifications (post);
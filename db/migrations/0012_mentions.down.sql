@@ -0,0 +1,15 @@
+DROP INDEX IF EXISTS for_thread_notifications;
+
+DELETE FROM Notifications WHERE kind = 'mention' OR post IS NULL;
+ALTER TABLE Notifications
+    DROP CONSTRAINT IF EXISTS notifications_target_check;
+ALTER TABLE Notifications
+    DROP CONSTRAINT IF EXISTS notifications_kind_check;
+ALTER TABLE Notifications
+    ADD CONSTRAINT notifications_kind_check CHECK (kind IN ('reply', 'thread', 'forum'));
+ALTER TABLE Notifications
+    DROP COLUMN IF EXISTS thread;
+ALTER TABLE Notifications
+    ALTER COLUMN post SET NOT NULL;
+
+DROP TABLE IF EXISTS Mentions;
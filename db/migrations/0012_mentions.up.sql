@@ -0,0 +1,29 @@
+-- упоминания @nickname в сообщениях и текстах веток, задано ровно одно из post и thread.
+-- Текст ветки при слиянии становится постом, а пост при выделении - веткой, упоминания переезжают вместе с текстом
+CREATE UNLOGGED TABLE IF NOT EXISTS Mentions
+(
+    id       bigserial          NOT NULL PRIMARY KEY,
+    nickname citext COLLATE "C" NOT NULL REFERENCES Users (nickname),
+    post     bigint REFERENCES Posts (id) ON DELETE CASCADE,
+    thread   bigint REFERENCES Threads (id) ON DELETE CASCADE,
+    created  timestamptz        NOT NULL DEFAULT now(),
+    CHECK ((post IS NULL) != (thread IS NULL)),
+    UNIQUE (post, nickname),
+    UNIQUE (thread, nickname)
+);
+
+CREATE INDEX IF NOT EXISTS for_user_mentions ON Mentions (nickname, id);
+
+-- уведомление об упоминании в тексте ветки ссылается на ветку, а не на пост
+ALTER TABLE Notifications
+    ALTER COLUMN post DROP NOT NULL;
+ALTER TABLE Notifications
+    ADD COLUMN IF NOT EXISTS thread bigint REFERENCES Threads (id) ON DELETE CASCADE;
+ALTER TABLE Notifications
+    DROP CONSTRAINT IF EXISTS notifications_kind_check;
+ALTER TABLE Notifications
+    ADD CONSTRAINT notifications_kind_check CHECK (kind IN ('reply', 'mention', 'thread', 'forum'));
+ALTER TABLE Notifications
+    ADD CONSTRAINT notifications_target_check CHECK ((post IS NULL) != (thread IS NULL));
+
+CREATE INDEX IF NOT EXISTS for_thread_notifications ON Notifications (thread);
@@ -0,0 +1,8 @@
+-- одноразовые токены сброса пароля, их выдаёт администратор. Так задают пароль и пользователи,
+-- созданные до появления паролей (с пустым password_hash). У пользователя действует только последний сброс
+CREATE UNLOGGED TABLE IF NOT EXISTS PasswordResets
+(
+    token_hash bytea              NOT NULL PRIMARY KEY,
+    nickname   citext COLLATE "C" NOT NULL UNIQUE REFERENCES Users (nickname),
+    expires    timestamptz        NOT NULL
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS for_search_threads_on_forum;
+CREATE INDEX IF NOT EXISTS for_search_threads_on_forum ON Threads (forum, created);
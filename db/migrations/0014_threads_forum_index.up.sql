@@ -0,0 +1,3 @@
+-- списки веток форума упорядочены по (created, id), id в индексе нужен для курсоров и одинаковых created
+DROP INDEX IF EXISTS for_search_threads_on_forum;
+CREATE INDEX IF NOT EXISTS for_search_threads_on_forum ON Threads (forum, created, id);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS threads_slug_unique;
@@ -0,0 +1,2 @@
+-- slug ветки уникален, если задан: проверка SELECT перед вставкой не защищает от параллельных создания и разделения веток
+CREATE UNIQUE INDEX IF NOT EXISTS threads_slug_unique ON Threads (slug) WHERE slug <> '';
@@ -0,0 +1,266 @@
+package migrations
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Миграции лежат рядом в файлах вида 0002_add_something.up.sql / 0002_add_something.down.sql.
+// Каждая миграция применяется в своей транзакции, поэтому в них нельзя использовать VACUUM и CREATE INDEX CONCURRENTLY
+
+//go:embed *.sql
+var files embed.FS
+
+const (
+	CreateMigrationsTableCommand = "CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL PRIMARY KEY, name text NOT NULL, applied_at timestamptz NOT NULL DEFAULT now());"
+	GetAppliedMigrationsCommand  = "SELECT version, applied_at FROM schema_migrations ORDER BY version;"
+	InsertMigrationCommand       = "INSERT INTO schema_migrations (version, name) VALUES ($1, $2);"
+	DeleteMigrationCommand       = "DELETE FROM schema_migrations WHERE version = $1;"
+	LockMigrationsCommand        = "SELECT pg_advisory_lock($1);"
+	UnlockMigrationsCommand      = "SELECT pg_advisory_unlock($1);"
+
+	// migrationsLockId - ключ advisory lock, чтобы два экземпляра не мигрировали базу одновременно
+	migrationsLockId = 4242001
+)
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+var (
+	ErrorBadMigrationFile = errors.New("bad migration file")
+	ErrorUnknownMigration = errors.New("database has migration unknown to this binary")
+)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Load читает встроенные в бинарник миграции, отсортированные по версии
+func Load() ([]Migration, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("%w: %s", ErrorBadMigrationFile, entry.Name())
+		}
+
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		body, err := files.ReadFile(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("%w: version %d has two names", ErrorBadMigrationFile, version)
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(body)
+		} else {
+			migration.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("%w: version %d has no up file", ErrorBadMigrationFile, migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+type Migrator struct {
+	Db         *pgxpool.Pool
+	migrations []Migration
+}
+
+func NewMigrator(db *pgxpool.Pool) (*Migrator, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{Db: db, migrations: migrations}, nil
+}
+
+// withLock выполняет f на одном соединении под advisory lock
+func (a *Migrator) withLock(ctx context.Context, f func(conn *pgxpool.Conn) error) error {
+	conn, err := a.Db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err = conn.Exec(ctx, LockMigrationsCommand, migrationsLockId); err != nil {
+		return fmt.Errorf("lock migrations: %w", err)
+	}
+	defer func() {
+		_, _ = conn.Exec(context.Background(), UnlockMigrationsCommand, migrationsLockId)
+	}()
+
+	if _, err = conn.Exec(ctx, CreateMigrationsTableCommand); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	return f(conn)
+}
+
+func getApplied(ctx context.Context, conn *pgxpool.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.Query(ctx, GetAppliedMigrationsCommand)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+func (a *Migrator) known(version int64) bool {
+	for _, migration := range a.migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (a *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, migration *Migration, up bool) error {
+	return conn.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if up {
+			if _, err := tx.Exec(ctx, migration.Up); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, InsertMigrationCommand, migration.Version, migration.Name)
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, migration.Down); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, DeleteMigrationCommand, migration.Version)
+		return err
+	})
+}
+
+// Up применяет все ещё не применённые миграции по возрастанию версии
+func (a *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	done := make([]Migration, 0)
+	err := a.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := getApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for version := range applied {
+			if !a.known(version) {
+				return fmt.Errorf("%w: %d", ErrorUnknownMigration, version)
+			}
+		}
+
+		for ind := range a.migrations {
+			migration := &a.migrations[ind]
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err = a.apply(ctx, conn, migration, true); err != nil {
+				return fmt.Errorf("migration %d_%s up: %w", migration.Version, migration.Name, err)
+			}
+			done = append(done, *migration)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+// Down откатывает steps последних применённых миграций
+func (a *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	done := make([]Migration, 0)
+	err := a.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := getApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for ind := len(a.migrations) - 1; ind >= 0 && len(done) < steps; ind-- {
+			migration := &a.migrations[ind]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+			}
+			if err = a.apply(ctx, conn, migration, false); err != nil {
+				return fmt.Errorf("migration %d_%s down: %w", migration.Version, migration.Name, err)
+			}
+			done = append(done, *migration)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+func (a *Migrator) Status(ctx context.Context) ([]Status, error) {
+	statuses := make([]Status, 0, len(a.migrations))
+	err := a.withLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := getApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range a.migrations {
+			appliedAt, ok := applied[migration.Version]
+			statuses = append(statuses, Status{Migration: migration, Applied: ok, AppliedAt: appliedAt})
+		}
+
+		return nil
+	})
+
+	return statuses, err
+}
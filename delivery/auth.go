@@ -0,0 +1,199 @@
+package delivery
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/valyala/fasthttp"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+	"technopark-db-semester-project/middleware"
+	"time"
+)
+
+// PasswordResetTTL - сколько действует токен сброса пароля
+const PasswordResetTTL = 24 * time.Hour
+
+var ErrorWrongCredentials = domain.NewError(domain.CategoryUnauthorized, "wrong_credentials", "wrong nickname or password")
+
+type AuthHandler struct {
+	authRepo   domain.AuthRepo
+	authorizer *Authorizer
+	ttl        time.Duration
+}
+
+func MakeAuthHandler(authRepo domain.AuthRepo, authorizer *Authorizer, ttl time.Duration) AuthHandler {
+	return AuthHandler{authRepo: authRepo, authorizer: authorizer, ttl: ttl}
+}
+
+// hashToken - в базе хранится только sha256 токена. Токен случайный, поэтому соль не нужна
+func hashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+func newToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashPassword хэширует пароль при регистрации и смене пароля
+func HashPassword(password string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+}
+
+// Resolve возвращает ник владельца действующего токена, для middleware.Auth
+func (a *AuthHandler) Resolve(ctx context.Context, token string) (string, error) {
+	session, err := a.authRepo.GetSession(ctx, hashToken(token))
+	if err != nil {
+		return "", err
+	}
+
+	return session.Nickname, nil
+}
+
+// POST user/login
+func (a *AuthHandler) Login(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("application/json")
+	uctx := ctx.UserValue("ctx").(context.Context)
+
+	var credentials models.Credentials
+	if !decodeBody(ctx, &credentials) {
+		return
+	}
+
+	nickname, hash, err := a.authRepo.GetPasswordHash(uctx, credentials.Nickname)
+	if err != nil && !errors.Is(err, domain.ErrorUserDoesNotExist) {
+		writeError(ctx, err)
+		return
+	}
+	// на неизвестного пользователя и неверный пароль отвечаем одинаково, чтобы не раскрывать существующие ники
+	if err != nil || len(hash) == 0 || bcrypt.CompareHashAndPassword(hash, []byte(credentials.Password)) != nil {
+		writeError(ctx, ErrorWrongCredentials)
+		return
+	}
+
+	session := models.Session{Nickname: nickname, Expires: time.Now().Add(a.ttl)}
+	token, err := newToken()
+	if err == nil {
+		session.TokenHash = hashToken(token)
+		err = a.authRepo.CreateSession(uctx, &session)
+	}
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	body, _ := json.Marshal(models.LoginResult{Token: token, Nickname: session.Nickname, Expires: session.Expires})
+	ctx.SetBody(body)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+// POST user/logout
+func (a *AuthHandler) Logout(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("application/json")
+	uctx := ctx.UserValue("ctx").(context.Context)
+
+	token := middleware.GetToken(ctx)
+	if token == "" {
+		writeError(ctx, ErrorUnauthorized)
+		return
+	}
+
+	if err := a.authRepo.DeleteSession(uctx, hashToken(token)); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusNoContent)
+}
+
+// POST user/{nickname}/password/reset
+// администратор выдаёт одноразовый токен, по которому пользователь задаёт новый пароль. Так получают пароль
+// и пользователи, созданные без него
+func (a *AuthHandler) ResetPassword(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("application/json")
+	uctx := ctx.UserValue("ctx").(context.Context)
+	nickname := ctx.UserValue("nickname").(string)
+
+	if !a.authorizer.AllowAdmin(ctx) {
+		return
+	}
+
+	reset := models.PasswordReset{Nickname: nickname, Expires: time.Now().Add(PasswordResetTTL)}
+	token, err := newToken()
+	if err == nil {
+		reset.TokenHash = hashToken(token)
+		err = a.authRepo.CreatePasswordReset(uctx, &reset)
+	}
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	body, _ := json.Marshal(models.PasswordResetResult{Token: token, Nickname: reset.Nickname, Expires: reset.Expires})
+	ctx.SetBody(body)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+// POST user/{nickname}/password
+// новый пароль задаётся по токену сброса или самим пользователем с текущим паролем. Все сессии пользователя завершаются
+func (a *AuthHandler) SetPassword(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("application/json")
+	uctx := ctx.UserValue("ctx").(context.Context)
+	nickname := ctx.UserValue("nickname").(string)
+
+	var change models.PasswordChange
+	if !decodeBody(ctx, &change) || !writeValidationErrors(ctx, validatePasswordChange(&change)) {
+		return
+	}
+
+	hash, err := HashPassword(change.Password)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	if change.ResetToken != "" {
+		// токен чужого пользователя не тратится: репозиторий удаляет сброс, только если ник совпал
+		if _, err = a.authRepo.UsePasswordReset(uctx, hashToken(change.ResetToken), nickname); err != nil {
+			writeError(ctx, err)
+			return
+		}
+	} else {
+		actor, ok := a.authorizer.actor(ctx)
+		if !ok {
+			return
+		}
+		if !strings.EqualFold(actor, nickname) {
+			writeError(ctx, ErrorForbidden)
+			return
+		}
+
+		_, current, err := a.authRepo.GetPasswordHash(uctx, nickname)
+		if err != nil {
+			writeError(ctx, err)
+			return
+		}
+		if len(current) == 0 || bcrypt.CompareHashAndPassword(current, []byte(change.CurrentPassword)) != nil {
+			writeError(ctx, ErrorWrongCredentials)
+			return
+		}
+	}
+
+	if err = a.authRepo.SetPasswordHash(uctx, nickname, hash); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusNoContent)
+}
@@ -0,0 +1,146 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"strings"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+	"technopark-db-semester-project/middleware"
+)
+
+var (
+	ErrorUnauthorized = domain.NewError(domain.CategoryUnauthorized, "unauthorized", "user is not authenticated")
+	ErrorForbidden    = domain.NewError(domain.CategoryForbidden, "forbidden", "not enough permissions")
+)
+
+// Authorizer проверяет права пользователя запроса по его ролям в форумах
+type Authorizer struct {
+	roleRepo domain.RoleRepo
+	admins   map[string]struct{}
+}
+
+func MakeAuthorizer(roleRepo domain.RoleRepo, admins []string) *Authorizer {
+	authorizer := &Authorizer{roleRepo: roleRepo, admins: make(map[string]struct{}, len(admins))}
+	for _, admin := range admins {
+		authorizer.admins[strings.ToLower(admin)] = struct{}{}
+	}
+
+	return authorizer
+}
+
+// Role возвращает роль пользователя в форуме с учётом администраторов из конфигурации
+func (a *Authorizer) Role(ctx context.Context, forumSlug string, nickname string) (string, error) {
+	if _, ok := a.admins[strings.ToLower(nickname)]; ok {
+		return models.RoleAdmin, nil
+	}
+
+	return a.roleRepo.Get(ctx, forumSlug, nickname)
+}
+
+// actor - ник пользователя, от имени которого выполняется запрос. Берётся только из сессии, проверенной
+// middleware.Auth, заголовкам и телу запроса не доверяем. Для анонимного запроса сам пишет 401
+func (a *Authorizer) actor(ctx *fasthttp.RequestCtx) (string, bool) {
+	nickname := middleware.GetUser(ctx)
+	if nickname == "" {
+		writeError(ctx, ErrorUnauthorized)
+		return "", false
+	}
+
+	return nickname, true
+}
+
+// ActorRole возвращает роль пользователя запроса в форуме. При ошибке сам пишет ответ
+func (a *Authorizer) ActorRole(ctx *fasthttp.RequestCtx, forumSlug string) (string, bool) {
+	nickname, ok := a.actor(ctx)
+	if !ok {
+		return "", false
+	}
+
+	role, err := a.Role(ctx.UserValue("ctx").(context.Context), forumSlug, nickname)
+	if err != nil {
+		writeError(ctx, err)
+		return "", false
+	}
+
+	return role, true
+}
+
+func isModerator(role string) bool {
+	return role == models.RoleModerator || role == models.RoleOwner || role == models.RoleAdmin
+}
+
+// check разрешает действие автору контента author (если он задан) или модератору всех форумов forums
+func (a *Authorizer) check(ctx *fasthttp.RequestCtx, author string, forums ...string) error {
+	uctx := ctx.UserValue("ctx").(context.Context)
+	nickname := middleware.GetUser(ctx)
+	if nickname == "" {
+		return ErrorUnauthorized
+	}
+
+	isAuthor := author != "" && strings.EqualFold(author, nickname)
+	for _, forum := range forums {
+		role, err := a.Role(uctx, forum, nickname)
+		if err != nil {
+			return err
+		}
+		if role == models.RoleBanned || (!isAuthor && !isModerator(role)) {
+			return ErrorForbidden
+		}
+	}
+
+	return nil
+}
+
+// Allow проверяет права так же, как check, и при отказе сам пишет ответ с ошибкой
+func (a *Authorizer) Allow(ctx *fasthttp.RequestCtx, author string, forums ...string) bool {
+	err := a.check(ctx, author, forums...)
+	if err == nil {
+		return true
+	}
+
+	writeError(ctx, err)
+
+	return false
+}
+
+// BindUser подставляет в claimed ник аутентифицированного пользователя. Если в запросе указан
+// другой пользователь, запрос отклоняется, чтобы нельзя было писать от чужого имени
+func (a *Authorizer) BindUser(ctx *fasthttp.RequestCtx, claimed *string) bool {
+	nickname, ok := a.actor(ctx)
+	if !ok {
+		return false
+	}
+	if *claimed != "" && !strings.EqualFold(*claimed, nickname) {
+		writeError(ctx, fmt.Errorf("%w: cannot act on behalf of %s", ErrorForbidden, *claimed))
+		return false
+	}
+	*claimed = nickname
+
+	return true
+}
+
+// AllowSelf пропускает самого пользователя nickname и администраторов из конфигурации
+func (a *Authorizer) AllowSelf(ctx *fasthttp.RequestCtx, nickname string) bool {
+	actor := middleware.GetUser(ctx)
+	if actor != "" && strings.EqualFold(actor, nickname) {
+		return true
+	}
+
+	return a.AllowAdmin(ctx)
+}
+
+// AllowAdmin пропускает только администраторов из конфигурации
+func (a *Authorizer) AllowAdmin(ctx *fasthttp.RequestCtx) bool {
+	nickname, ok := a.actor(ctx)
+	if !ok {
+		return false
+	}
+	if _, ok := a.admins[strings.ToLower(nickname)]; !ok {
+		writeError(ctx, ErrorForbidden)
+		return false
+	}
+
+	return true
+}
@@ -1,9 +1,75 @@
 package delivery
 
+import (
+	"encoding/json"
+	"errors"
+	"github.com/valyala/fasthttp"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/logger"
+	"technopark-db-semester-project/middleware"
+)
+
+const CodeInternal = "internal"
+
 type Error struct {
+	Message string       `json:"message"`
+	Code    string       `json:"code,omitempty"`   // машиночитаемый код ошибки
+	Fields  []FieldError `json:"fields,omitempty"` // ошибки отдельных полей запроса
+}
+
+type FieldError struct {
+	Field   string `json:"field"` // имя поля в json, для элементов массива - с индексом: [1].message
+	Code    string `json:"code"`
 	Message string `json:"message"`
 }
 
 func GetErrorMessage(err error) *Error {
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		return &Error{Message: err.Error(), Code: domainErr.Code}
+	}
+
 	return &Error{Message: err.Error()}
 }
+
+var categoryStatus = map[domain.Category]int{
+	domain.CategoryInvalid:      fasthttp.StatusBadRequest,
+	domain.CategoryUnauthorized: fasthttp.StatusUnauthorized,
+	domain.CategoryForbidden:    fasthttp.StatusForbidden,
+	domain.CategoryNotFound:     fasthttp.StatusNotFound,
+	domain.CategoryConflict:     fasthttp.StatusConflict,
+	domain.CategoryLocked:       fasthttp.StatusLocked,
+}
+
+// writeError - единственное место, где ошибка превращается в ответ: код ответа берётся из категории domain.Error.
+// Остальные ошибки считаются внутренними, пишутся в лог, а клиенту уходит 500 без подробностей
+func writeError(ctx *fasthttp.RequestCtx, err error) {
+	var domainErr *domain.Error
+	status, known := 0, false
+	if errors.As(err, &domainErr) {
+		status, known = categoryStatus[domainErr.Category]
+	}
+
+	if !known {
+		logger.Log(logger.LevelError, "internal error", logger.Fields{
+			"request_id": middleware.GetRequestId(ctx),
+			"method":     string(ctx.Method()),
+			"path":       string(ctx.Path()),
+			"error":      err.Error(),
+		})
+
+		body, _ := json.Marshal(&Error{Message: "internal server error", Code: CodeInternal})
+		ctx.SetBody(body)
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		return
+	}
+
+	body, _ := json.Marshal(GetErrorMessage(err))
+	ctx.SetBody(body)
+	ctx.SetStatusCode(status)
+}
+
+// WriteError - writeError для middleware, которые отвечают до маршрутизации (middleware.ErrorWriter)
+func WriteError(ctx *fasthttp.RequestCtx, err error) {
+	writeError(ctx, err)
+}
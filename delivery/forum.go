@@ -5,18 +5,19 @@ import (
 	"encoding/json"
 	"errors"
 	"github.com/valyala/fasthttp"
-	"strconv"
+	"strings"
 	"technopark-db-semester-project/domain"
 	"technopark-db-semester-project/domain/models"
-	"technopark-db-semester-project/repository/postgresql"
 )
 
 type ForumHandler struct {
-	forumRepo domain.ForumRepo
+	forumRepo  domain.ForumRepo
+	authorizer *Authorizer
+	cursors    *CursorSigner
 }
 
-func MakeForumHandler(forumRepo domain.ForumRepo) ForumHandler {
-	return ForumHandler{forumRepo: forumRepo}
+func MakeForumHandler(forumRepo domain.ForumRepo, authorizer *Authorizer, cursors *CursorSigner) ForumHandler {
+	return ForumHandler{forumRepo: forumRepo, authorizer: authorizer, cursors: cursors}
 }
 
 // POST forum/create
@@ -25,19 +26,23 @@ func (a *ForumHandler) Create(ctx *fasthttp.RequestCtx) {
 	uctx := ctx.UserValue("ctx").(context.Context)
 
 	var forumCreate models.ForumCreate
-	_ = json.Unmarshal(ctx.PostBody(), &forumCreate)
+	if !decodeBody(ctx, &forumCreate) || !writeValidationErrors(ctx, validateForumCreate(&forumCreate)) {
+		return
+	}
+	if !a.authorizer.BindUser(ctx, &forumCreate.User) {
+		return
+	}
 
 	forum, err := a.forumRepo.Create(uctx, &forumCreate)
 
 	if err != nil {
-		if errors.Is(err, postgresql.ErrorUserDoesNotExist) {
-			body, _ := json.Marshal(GetErrorMessage(err))
-			ctx.SetBody(body)
-			ctx.SetStatusCode(fasthttp.StatusNotFound)
-		} else if errors.Is(err, postgresql.ErrorForumAlreadyExist) {
+		// при конфликте по API отдаётся уже существующий форум
+		if errors.Is(err, domain.ErrorForumAlreadyExist) {
 			body, _ := json.Marshal(forum)
 			ctx.SetBody(body)
 			ctx.SetStatusCode(fasthttp.StatusConflict)
+		} else {
+			writeError(ctx, err)
 		}
 
 		return
@@ -58,9 +63,7 @@ func (a *ForumHandler) Get(ctx *fasthttp.RequestCtx) {
 
 	forum, err := a.forumRepo.Get(uctx, slug)
 	if err != nil {
-		body, _ := json.Marshal(GetErrorMessage(err))
-		ctx.SetBody(body)
-		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		writeError(ctx, err)
 		return
 	}
 
@@ -72,78 +75,151 @@ func (a *ForumHandler) Get(ctx *fasthttp.RequestCtx) {
 }
 
 // GET forum/{slug}/users
+// страницы идут по нику, курсоры соседних страниц отдаются в заголовках X-Next-Cursor и X-Prev-Cursor
 func (a *ForumHandler) GetUsers(ctx *fasthttp.RequestCtx) {
 	ctx.SetContentType("application/json")
 	uctx := ctx.UserValue("ctx").(context.Context)
 	slug := ctx.UserValue("slug").(string)
 
-	limit, err := strconv.Atoi(string(ctx.QueryArgs().Peek("limit")))
-	if err != nil {
-		limit = 100
+	if !writeValidationErrors(ctx, validateLimit(ctx.QueryArgs(), MaxPageLimit)) {
+		return
 	}
 
-	since := string(ctx.QueryArgs().Peek("since"))
-
-	desc, err := strconv.ParseBool(string(ctx.QueryArgs().Peek("desc")))
+	request, err := a.cursors.parsePageRequest(ctx.QueryArgs(), "forum/"+strings.ToLower(slug)+"/users")
 	if err != nil {
-		desc = false
+		writeError(ctx, err)
+		return
 	}
 
 	forumUsers := &models.GetForumUsers{
 		Slug:  slug,
-		Limit: int32(limit),
-		Since: since,
-		Desc:  desc,
+		Limit: request.queryLimit(),
+		Since: string(ctx.QueryArgs().Peek("since")),
+		Desc:  request.queryDesc(),
+	}
+	if after := request.after(); after != nil {
+		forumUsers.Since = after.Nickname
 	}
 
 	users, err := a.forumRepo.GetUsers(uctx, forumUsers)
 	if err != nil {
-		body, _ := json.Marshal(GetErrorMessage(err))
-		ctx.SetBody(body)
-		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		writeError(ctx, err)
 		return
 	}
 
-	body, _ := json.Marshal(users)
-	ctx.SetStatusCode(fasthttp.StatusOK)
-	ctx.SetBody(body)
+	page, links := paginate(a.cursors, request, *users, func(user *models.User) models.PageKey {
+		return models.PageKey{Nickname: user.Nickname}
+	}, nil)
+	// у ForumUsers нет готового счётчика, а считать участников на каждую страницу дорого, поэтому total нет
+	writePage(ctx, page, links, nil)
 
 	return
 }
 
 // GET forum/{slug}/threads
+// страницы идут по (created, id). Объявления и закреплённые ветки есть только на первой странице - без since и cursor
+// или пришедшей по prev - и занимают места в limit, остальные ветки дополняют эту страницу до limit.
+// Если закреплённых больше limit, лишние не показываются
 func (a *ForumHandler) GetThreads(ctx *fasthttp.RequestCtx) {
 	ctx.SetContentType("application/json")
 	uctx := ctx.UserValue("ctx").(context.Context)
 	slug := ctx.UserValue("slug").(string)
 
-	limit, err := strconv.Atoi(string(ctx.QueryArgs().Peek("limit")))
-	if err != nil {
-		limit = 100
+	if !writeValidationErrors(ctx, validateLimit(ctx.QueryArgs(), MaxPageLimit)) {
+		return
 	}
 
-	desc, err := strconv.ParseBool(string(ctx.QueryArgs().Peek("desc")))
+	request, err := a.cursors.parsePageRequest(ctx.QueryArgs(), "forum/"+strings.ToLower(slug)+"/threads")
 	if err != nil {
-		desc = false
+		writeError(ctx, err)
+		return
 	}
 
 	forumThreads := &models.GetForumThreads{
-		Limit: int32(limit),
+		Limit: request.queryLimit(),
 		Since: string(ctx.QueryArgs().Peek("since")),
-		Desc:  desc,
+		Desc:  request.queryDesc(),
+		After: request.after(),
+	}
+	if forumThreads.After != nil {
+		forumThreads.Since = ""
 	}
 
 	threads, err := a.forumRepo.GetThreads(uctx, slug, forumThreads)
 	if err != nil {
-		body, _ := json.Marshal(GetErrorMessage(err))
-		ctx.SetBody(body)
-		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		writeError(ctx, err)
 		return
 	}
+	// страница назад, перед которой веток не осталось, упирается в начало списка. Если её ветки помещаются рядом
+	// с закреплёнными, это первая страница и она отдаётся вместе с ними, иначе первая страница - следующая назад
+	atStart := false
+	if request.cursor != nil && request.cursor.Backward && len(*threads) < int(forumThreads.Limit) {
+		first := *forumThreads
+		first.Desc, first.After = request.desc, nil
+		firstThreads, err := a.forumRepo.GetThreads(uctx, slug, &first)
+		if err != nil {
+			writeError(ctx, err)
+			return
+		}
+		if len(*threads) <= request.limit-pinnedShown(countPinned(*firstThreads), request.limit) {
+			request.cursor, forumThreads, threads = nil, &first, firstThreads
+		} else {
+			atStart = true
+		}
+	}
 
-	body, _ := json.Marshal(threads)
-	ctx.SetBody(body)
-	ctx.SetStatusCode(fasthttp.StatusOK)
+	pinned := 0
+	if forumThreads.Since == "" && forumThreads.After == nil {
+		pinned = countPinned(*threads)
+	}
+	shown := pinnedShown(pinned, request.limit)
+
+	// остальные ветки получают места, которые не заняли закреплённые
+	key := func(thread *models.Thread) models.PageKey {
+		return models.PageKey{Created: thread.Created, Id: int64(thread.Id)}
+	}
+	rest := *request
+	rest.limit = request.limit - shown
+	page, links := paginate(a.cursors, &rest, (*threads)[pinned:], key, nil)
+	if rest.limit == 0 && len(*threads) > pinned {
+		// закреплённые заняли всю страницу: следующая начинается с первой из остальных веток, ключ с нулевым id
+		links.next = a.cursors.encode(&pageCursor{List: request.list, Sort: request.sort, Desc: request.desc})
+	}
+	if atStart {
+		links.prev = a.cursors.encode(&pageCursor{List: request.list, Sort: request.sort, Desc: request.desc, Backward: true, Key: key(&page[0])})
+	}
+	page = append((*threads)[:shown:shown], page...)
+
+	// total - ветки форума и объявления других форумов, то есть все, что показывает список
+	var total *int64
+	if wantsEnvelope(ctx) {
+		threadsCount, err := a.forumRepo.CountThreads(uctx, slug)
+		if err != nil {
+			writeError(ctx, err)
+			return
+		}
+		total = &threadsCount
+	}
+	writePage(ctx, page, links, total)
 
 	return
 }
+
+// countPinned - сколько закреплённых веток и объявлений в начале первой страницы
+func countPinned(threads []models.Thread) int {
+	pinned := 0
+	for pinned < len(threads) && (threads[pinned].Pinned > 0 || threads[pinned].Announcement) {
+		pinned++
+	}
+
+	return pinned
+}
+
+// pinnedShown - сколько закреплённых веток поместится на страницу из limit веток
+func pinnedShown(pinned int, limit int) int {
+	if pinned > limit {
+		return limit
+	}
+
+	return pinned
+}
@@ -0,0 +1,145 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"technopark-db-semester-project/domain/models"
+	"technopark-db-semester-project/repository/memory"
+	"testing"
+)
+
+// titles - заголовки веток страницы, по ним удобно сравнивать порядок
+func titles(threads []models.Thread) []string {
+	result := make([]string, 0, len(threads))
+	for _, thread := range threads {
+		result = append(result, thread.Title)
+	}
+
+	return result
+}
+
+func TestForumGetThreadsPinnedOnFirstPage(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		desc  bool
+		first []string // первая страница: объявление, закреплённая ветка и остальные по порядку
+	}{
+		{name: "pinned share page", limit: 3, first: []string{"announce", "pinned", "t1"}},
+		{name: "pinned share page desc", limit: 3, desc: true, first: []string{"announce", "pinned", "t7"}},
+		{name: "pinned fill page", limit: 2, first: []string{"announce", "pinned"}},
+		{name: "pinned fill page desc", limit: 2, desc: true, first: []string{"announce", "pinned"}},
+		{name: "one page", limit: 20, first: []string{"announce", "pinned", "t1", "t2", "t3", "t4", "t5", "t6", "t7"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := newTestEnv(t, false)
+			env.createUser(t, "alice")
+			env.createForum(t, "f1", "alice")
+			env.createForum(t, "f2", "alice")
+			for ind := 1; ind <= 7; ind++ {
+				env.createThread(t, "f1", "alice", fmt.Sprintf("t%d", ind), ind)
+			}
+			threads := memory.NewThreadMemoryRepo(env.storage)
+			env.createThread(t, "f1", "alice", "pinned", 0)
+			if _, err := threads.SetPin(context.Background(), "pinned", &models.ThreadPin{Pinned: 1}); err != nil {
+				t.Fatal("pin thread:", err)
+			}
+			env.createThread(t, "f2", "alice", "announce", 0)
+			if _, err := threads.SetPin(context.Background(), "announce", &models.ThreadPin{Announcement: true}); err != nil {
+				t.Fatal("announce thread:", err)
+			}
+
+			get := func(cursor string) ([]string, string, string) {
+				query := fmt.Sprintf("limit=%d&desc=%t", test.limit, test.desc)
+				if cursor != "" {
+					query = "limit=" + fmt.Sprint(test.limit) + "&cursor=" + cursor
+				}
+				ctx := serve(env.forum.GetThreads, testRequest{values: map[string]string{"slug": "f1"}, query: query})
+				var page []models.Thread
+				decodeResponse(t, ctx, 200, &page)
+
+				return titles(page), string(ctx.Response.Header.Peek(NextCursorHeader)), string(ctx.Response.Header.Peek(PrevCursorHeader))
+			}
+
+			// вперёд до конца списка, затем назад по prev: каждая страница должна совпасть с уже виденной
+			var pages [][]string
+			page, next, prev := get("")
+			if fmt.Sprint(page) != fmt.Sprint(test.first) {
+				t.Fatalf("first page = %v, want %v", page, test.first)
+			}
+			if prev != "" {
+				t.Fatal("first page has prev cursor")
+			}
+			pages = append(pages, page)
+			for next != "" {
+				page, next, prev = get(next)
+				pages = append(pages, page)
+			}
+
+			for ind := len(pages) - 2; ind >= 0; ind-- {
+				if prev == "" {
+					t.Fatalf("page %d has no prev cursor", ind+1)
+				}
+				page, _, prev = get(prev)
+				if fmt.Sprint(page) != fmt.Sprint(pages[ind]) {
+					t.Fatalf("page %d backward = %v, forward %v", ind, page, pages[ind])
+				}
+			}
+			if prev != "" {
+				t.Fatal("first page reached backward has prev cursor")
+			}
+		})
+	}
+}
+
+// TestForumCounters читает форум после каждой записи: с кэшем счётчики должны сбрасываться той записью, которая их меняет
+func TestForumCounters(t *testing.T) {
+	runBackends(t, func(t *testing.T, env *testEnv) {
+		env.createUser(t, "alice")
+		env.createUser(t, "bob")
+		env.createForum(t, "f1", "alice")
+
+		var posts []int64
+		steps := []struct {
+			name    string
+			do      func(t *testing.T)
+			threads int32
+			posts   int64
+		}{
+			{name: "new forum", do: func(t *testing.T) {}},
+			{name: "create thread", threads: 1, do: func(t *testing.T) {
+				ctx := serve(env.thread.Create, testRequest{
+					values: map[string]string{"slug": "F1"},
+					body:   models.ThreadCreate{Title: "t1", Message: "t1", Slug: "t1"},
+					user:   "alice",
+				})
+				decodeResponse(t, ctx, fasthttp.StatusCreated, nil)
+			}},
+			{name: "create posts", threads: 1, posts: 3, do: func(t *testing.T) {
+				posts = append(posts, env.createPost(t, "t1", "alice", 0, "a"))
+				posts = append(posts, env.createPost(t, "T1", "Bob", posts[0], "b"))
+				posts = append(posts, env.createPost(t, "t1", "alice", posts[1], "c"))
+			}},
+			{name: "soft delete", threads: 1, posts: 2, do: func(t *testing.T) {
+				ctx := serve(env.post.Delete, testRequest{values: map[string]string{"id": fmt.Sprint(posts[2])}, user: "alice"})
+				decodeResponse(t, ctx, fasthttp.StatusOK, nil)
+			}},
+			// жёсткое удаление убирает поддерево, уже удалённый мягко пост из счётчика второй раз не вычитается
+			{name: "hard delete", threads: 1, posts: 1, do: func(t *testing.T) {
+				ctx := serve(env.post.Delete, testRequest{values: map[string]string{"id": fmt.Sprint(posts[1])}, query: "hard=true", user: "admin"})
+				decodeResponse(t, ctx, fasthttp.StatusOK, nil)
+			}},
+		}
+
+		for _, step := range steps {
+			step.do(t)
+			forum := getForum(t, env, "f1")
+			if forum.Threads != step.threads || forum.Posts != step.posts {
+				t.Fatalf("after %s: forum = %d threads %d posts, want %d and %d", step.name, forum.Threads, forum.Posts, step.threads, step.posts)
+			}
+		}
+	})
+}
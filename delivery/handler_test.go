@@ -0,0 +1,173 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/valyala/fasthttp"
+	"technopark-db-semester-project/domain/models"
+	"technopark-db-semester-project/metrics"
+	"technopark-db-semester-project/middleware"
+	"technopark-db-semester-project/repository/cached"
+	"technopark-db-semester-project/repository/memory"
+	"testing"
+	"time"
+)
+
+// testEnv - обработчики поверх одного хранилища в памяти
+type testEnv struct {
+	storage *memory.Storage
+	user    UserHandler
+	forum   ForumHandler
+	thread  ThreadHandler
+	post    PostHandler
+	vote    VoteHandler
+}
+
+// newTestEnv собирает обработчики так же, как system.InitHandlers. cachedRepos ставит перед хранилищем кэш,
+// как system.CacheRepos перед Postgres: обработчики должны видеть то же, что и без него
+func newTestEnv(t *testing.T, cachedRepos bool) *testEnv {
+	t.Helper()
+
+	storage := memory.NewStorage()
+	var (
+		userRepo   = memory.NewUserMemoryRepo(storage)
+		forumRepo  = memory.NewForumMemoryRepo(storage)
+		threadRepo = memory.NewThreadMemoryRepo(storage)
+		postRepo   = memory.NewPostMemoryRepo(storage)
+		voteRepo   = memory.NewVoteMemoryRepo(storage)
+	)
+	if cachedRepos {
+		caches := cached.NewCaches(100, time.Hour, cached.NewStats(metrics.NewRegistry()))
+		userRepo = cached.NewUserCachedRepo(userRepo, caches)
+		forumRepo = cached.NewForumCachedRepo(forumRepo, caches)
+		threadRepo = cached.NewThreadCachedRepo(threadRepo, caches)
+		postRepo = cached.NewPostCachedRepo(postRepo, caches)
+		voteRepo = cached.NewVoteCachedRepo(voteRepo, caches)
+	}
+	authorizer := MakeAuthorizer(memory.NewRoleMemoryRepo(storage), []string{"admin"})
+	cursors := MakeCursorSigner("test")
+
+	return &testEnv{
+		storage: storage,
+		user:    MakeUserHandler(userRepo, authorizer),
+		forum:   MakeForumHandler(forumRepo, authorizer, cursors),
+		thread:  MakeThreadHandler(threadRepo, postRepo, authorizer, cursors),
+		post:    MakePostHandler(postRepo, authorizer),
+		vote:    MakeVoteHandler(voteRepo, authorizer),
+	}
+}
+
+// runBackends прогоняет тест на хранилище в памяти без кэша и с кэшем
+func runBackends(t *testing.T, test func(t *testing.T, env *testEnv)) {
+	for _, cachedRepos := range []bool{false, true} {
+		name := "memory"
+		if cachedRepos {
+			name = "cached"
+		}
+		t.Run(name, func(t *testing.T) {
+			test(t, newTestEnv(t, cachedRepos))
+		})
+	}
+}
+
+func (a *testEnv) createUser(t *testing.T, nickname string) {
+	t.Helper()
+
+	user := &models.User{Nickname: nickname, Fullname: nickname, Email: nickname + "@example.com"}
+	if _, err := memory.NewUserMemoryRepo(a.storage).Create(context.Background(), user); err != nil {
+		t.Fatal("create user:", err)
+	}
+}
+
+func (a *testEnv) createForum(t *testing.T, slug string, owner string) {
+	t.Helper()
+
+	forum := &models.ForumCreate{Title: slug, User: owner, Slug: slug}
+	if _, err := memory.NewForumMemoryRepo(a.storage).Create(context.Background(), forum); err != nil {
+		t.Fatal("create forum:", err)
+	}
+}
+
+// createThread создаёт ветку, созданную через minute минут после начала отсчёта
+func (a *testEnv) createThread(t *testing.T, forum string, author string, slug string, minute int) *models.Thread {
+	t.Helper()
+
+	created := time.Date(2022, 1, 1, 0, minute, 0, 0, time.UTC)
+	thread, err := memory.NewThreadMemoryRepo(a.storage).Create(context.Background(), forum,
+		&models.ThreadCreate{Title: slug, Author: author, Message: slug, Slug: slug, Created: created})
+	if err != nil {
+		t.Fatal("create thread:", err)
+	}
+
+	return thread
+}
+
+// createPost создаёт пост от имени автора через обработчик и возвращает его id
+func (a *testEnv) createPost(t *testing.T, thread string, author string, parent int64, message string) int64 {
+	t.Helper()
+
+	ctx := serve(a.post.Create, testRequest{
+		values: map[string]string{"slug_or_id": thread},
+		body:   []models.PostCreate{{Parent: parent, Message: message}},
+		user:   author,
+	})
+	var posts []models.Post
+	decodeResponse(t, ctx, fasthttp.StatusCreated, &posts)
+
+	return posts[0].Id
+}
+
+// testRequest - запрос к обработчику: значения маршрута, query, тело и пользователь сессии
+type testRequest struct {
+	values map[string]string
+	query  string
+	body   interface{}
+	user   string
+}
+
+// serve вызывает обработчик так же, как его вызывают роутер и middleware.Auth
+func serve(handler fasthttp.RequestHandler, request testRequest) *fasthttp.RequestCtx {
+	ctx := &fasthttp.RequestCtx{}
+	ctx.SetUserValue("ctx", context.Background())
+	for name, value := range request.values {
+		ctx.SetUserValue(name, value)
+	}
+	ctx.Request.URI().SetQueryString(request.query)
+	if request.body != nil {
+		body, _ := json.Marshal(request.body)
+		ctx.Request.SetBody(body)
+	}
+	if request.user != "" {
+		ctx.SetUserValue(middleware.UserKey, request.user)
+	}
+
+	handler(ctx)
+
+	return ctx
+}
+
+// decodeResponse разбирает тело ответа, заранее проверив код
+func decodeResponse(t *testing.T, ctx *fasthttp.RequestCtx, status int, dst interface{}) {
+	t.Helper()
+
+	if ctx.Response.StatusCode() != status {
+		t.Fatalf("status = %d, want %d, body %s", ctx.Response.StatusCode(), status, ctx.Response.Body())
+	}
+	if dst == nil {
+		return
+	}
+	if err := json.Unmarshal(ctx.Response.Body(), dst); err != nil {
+		t.Fatalf("decode %s: %v", ctx.Response.Body(), err)
+	}
+}
+
+// decodeError разбирает ответ с ошибкой и проверяет её код
+func decodeError(t *testing.T, ctx *fasthttp.RequestCtx, status int, code string) {
+	t.Helper()
+
+	var response Error
+	decodeResponse(t, ctx, status, &response)
+	if response.Code != code {
+		t.Fatalf("error code = %q, want %q, body %s", response.Code, code, ctx.Response.Body())
+	}
+}
@@ -0,0 +1,62 @@
+package delivery
+
+import (
+	"context"
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"strings"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+)
+
+type MentionHandler struct {
+	mentionRepo domain.MentionRepo
+	cursors     *CursorSigner
+}
+
+func MakeMentionHandler(mentionRepo domain.MentionRepo, cursors *CursorSigner) MentionHandler {
+	return MentionHandler{mentionRepo: mentionRepo, cursors: cursors}
+}
+
+// GET user/{nickname}/mentions
+// страницы идут по id упоминания. Упоминания собраны из открытых сообщений, поэтому список виден всем
+func (a *MentionHandler) GetByUser(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("application/json")
+	uctx := ctx.UserValue("ctx").(context.Context)
+	nickname := ctx.UserValue("nickname").(string)
+
+	if !writeValidationErrors(ctx, validateLimit(ctx.QueryArgs(), MaxPageLimit)) {
+		return
+	}
+
+	request, err := a.cursors.parsePageRequest(ctx.QueryArgs(), "user/"+strings.ToLower(nickname)+"/mentions")
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	since, err := strconv.ParseInt(string(ctx.QueryArgs().Peek("since")), 10, 64)
+	if err != nil {
+		since = 0
+	}
+
+	getMentions := &models.GetMentions{
+		Limit: request.queryLimit(),
+		Since: since,
+		Desc:  request.queryDesc(),
+	}
+	if after := request.after(); after != nil {
+		getMentions.Since = after.Id
+	}
+
+	mentions, err := a.mentionRepo.GetByUser(uctx, nickname, getMentions)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	page, links := paginate(a.cursors, request, *mentions, func(mention *models.Mention) models.PageKey {
+		return models.PageKey{Id: mention.Id}
+	}, nil)
+	writePage(ctx, page, links, nil)
+}
@@ -0,0 +1,222 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"strings"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+)
+
+type NotificationHandler struct {
+	notificationRepo domain.NotificationRepo
+	authorizer       *Authorizer
+	cursors          *CursorSigner
+}
+
+func MakeNotificationHandler(notificationRepo domain.NotificationRepo, authorizer *Authorizer, cursors *CursorSigner) NotificationHandler {
+	return NotificationHandler{notificationRepo: notificationRepo, authorizer: authorizer, cursors: cursors}
+}
+
+// POST thread/{slug_or_id}/subscription
+func (a *NotificationHandler) SubscribeThread(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("application/json")
+	uctx := ctx.UserValue("ctx").(context.Context)
+	slugOrId := ctx.UserValue("slug_or_id").(string)
+
+	var nickname string
+	if !a.authorizer.BindUser(ctx, &nickname) {
+		return
+	}
+
+	subscription, err := a.notificationRepo.SubscribeThread(uctx, nickname, slugOrId)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	body, _ := json.Marshal(subscription)
+	ctx.SetBody(body)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+// DELETE thread/{slug_or_id}/subscription
+func (a *NotificationHandler) UnsubscribeThread(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("application/json")
+	uctx := ctx.UserValue("ctx").(context.Context)
+	slugOrId := ctx.UserValue("slug_or_id").(string)
+
+	var nickname string
+	if !a.authorizer.BindUser(ctx, &nickname) {
+		return
+	}
+
+	if err := a.notificationRepo.UnsubscribeThread(uctx, nickname, slugOrId); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusNoContent)
+}
+
+// POST forum/{slug}/subscription
+func (a *NotificationHandler) SubscribeForum(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("application/json")
+	uctx := ctx.UserValue("ctx").(context.Context)
+	slug := ctx.UserValue("slug").(string)
+
+	var nickname string
+	if !a.authorizer.BindUser(ctx, &nickname) {
+		return
+	}
+
+	subscription, err := a.notificationRepo.SubscribeForum(uctx, nickname, slug)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	body, _ := json.Marshal(subscription)
+	ctx.SetBody(body)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+// DELETE forum/{slug}/subscription
+func (a *NotificationHandler) UnsubscribeForum(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("application/json")
+	uctx := ctx.UserValue("ctx").(context.Context)
+	slug := ctx.UserValue("slug").(string)
+
+	var nickname string
+	if !a.authorizer.BindUser(ctx, &nickname) {
+		return
+	}
+
+	if err := a.notificationRepo.UnsubscribeForum(uctx, nickname, slug); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.SetStatusCode(fasthttp.StatusNoContent)
+}
+
+// GET user/{nickname}/subscriptions
+func (a *NotificationHandler) GetSubscriptions(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("application/json")
+	uctx := ctx.UserValue("ctx").(context.Context)
+	nickname := ctx.UserValue("nickname").(string)
+
+	if !a.authorizer.AllowSelf(ctx, nickname) {
+		return
+	}
+
+	subscriptions, err := a.notificationRepo.GetSubscriptions(uctx, nickname)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	body, _ := json.Marshal(subscriptions)
+	ctx.SetBody(body)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+// GET user/{nickname}/notifications
+// страницы идут по id уведомления, unread=true оставляет только непрочитанные
+func (a *NotificationHandler) GetNotifications(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("application/json")
+	uctx := ctx.UserValue("ctx").(context.Context)
+	nickname := ctx.UserValue("nickname").(string)
+
+	// уведомления видит только сам пользователь и администраторы
+	if !a.authorizer.AllowSelf(ctx, nickname) {
+		return
+	}
+
+	if !writeValidationErrors(ctx, validateLimit(ctx.QueryArgs(), MaxPageLimit)) {
+		return
+	}
+
+	unread, _ := strconv.ParseBool(string(ctx.QueryArgs().Peek("unread")))
+	// фильтр входит в идентификатор списка, чтобы курсор нельзя было перенести в список с другим фильтром
+	request, err := a.cursors.parsePageRequest(ctx.QueryArgs(), "user/"+strings.ToLower(nickname)+"/notifications?unread="+strconv.FormatBool(unread))
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	since, err := strconv.ParseInt(string(ctx.QueryArgs().Peek("since")), 10, 64)
+	if err != nil {
+		since = 0
+	}
+
+	getNotifications := &models.GetNotifications{
+		Limit:  request.queryLimit(),
+		Since:  since,
+		Desc:   request.queryDesc(),
+		Unread: unread,
+	}
+	if after := request.after(); after != nil {
+		getNotifications.Since = after.Id
+	}
+
+	notifications, err := a.notificationRepo.GetNotifications(uctx, nickname, getNotifications)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	page, links := paginate(a.cursors, request, *notifications, func(notification *models.Notification) models.PageKey {
+		return models.PageKey{Id: notification.Id}
+	}, nil)
+	writePage(ctx, page, links, nil)
+}
+
+// GET user/{nickname}/notifications/unread
+func (a *NotificationHandler) CountUnread(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("application/json")
+	uctx := ctx.UserValue("ctx").(context.Context)
+	nickname := ctx.UserValue("nickname").(string)
+
+	if !a.authorizer.AllowSelf(ctx, nickname) {
+		return
+	}
+
+	count, err := a.notificationRepo.CountUnread(uctx, nickname)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	body, _ := json.Marshal(count)
+	ctx.SetBody(body)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+// POST user/{nickname}/notifications/read
+// {"ids": [...]} отмечает прочитанными перечисленные уведомления, {"all": true} - все
+func (a *NotificationHandler) MarkRead(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("application/json")
+	uctx := ctx.UserValue("ctx").(context.Context)
+	nickname := ctx.UserValue("nickname").(string)
+
+	var read models.NotificationsRead
+	if !decodeBody(ctx, &read) || !writeValidationErrors(ctx, validateNotificationsRead(&read)) {
+		return
+	}
+	if !a.authorizer.AllowSelf(ctx, nickname) {
+		return
+	}
+
+	count, err := a.notificationRepo.MarkRead(uctx, nickname, &read)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	body, _ := json.Marshal(count)
+	ctx.SetBody(body)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
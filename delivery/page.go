@@ -0,0 +1,273 @@
+package delivery
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"strings"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+)
+
+const (
+	DefaultPageLimit = 100
+	MaxPageLimit     = 10000
+
+	NextCursorHeader = "X-Next-Cursor"
+	PrevCursorHeader = "X-Prev-Cursor"
+
+	// EnvelopeProfile - профиль в Accept (application/json; profile="envelope"), по которому список отдаётся в конверте
+	EnvelopeProfile = "envelope"
+)
+
+var ErrorBadCursor = domain.NewError(domain.CategoryInvalid, "bad_cursor", "cursor is malformed, forged or belongs to another list")
+
+// pageCursor - содержимое курсора. List привязывает курсор к списку (форуму или ветке), Sort и Desc - порядок
+// списка, Backward - курсор на предыдущую страницу: записи строго перед Key
+type pageCursor struct {
+	List     string         `json:"l"`
+	Sort     string         `json:"s,omitempty"`
+	Desc     bool           `json:"d,omitempty"`
+	Backward bool           `json:"b,omitempty"`
+	Key      models.PageKey `json:"k"`
+}
+
+// CursorSigner подписывает курсоры HMAC, чтобы клиент не мог подставить в запрос произвольный ключ
+type CursorSigner struct {
+	secret []byte
+}
+
+// MakeCursorSigner с пустым секретом генерирует случайный: курсоры тогда не переживают перезапуск
+// и не подходят к другим экземплярам сервера
+func MakeCursorSigner(secret string) *CursorSigner {
+	if secret == "" {
+		buf := make([]byte, 32)
+		_, _ = rand.Read(buf)
+		return &CursorSigner{secret: buf}
+	}
+
+	return &CursorSigner{secret: []byte(secret)}
+}
+
+func (a *CursorSigner) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+func (a *CursorSigner) encode(cursor *pageCursor) string {
+	data, _ := json.Marshal(cursor)
+	payload := base64.RawURLEncoding.EncodeToString(data)
+
+	return payload + "." + base64.RawURLEncoding.EncodeToString(a.sign(payload))
+}
+
+func (a *CursorSigner) decode(value string, list string) (*pageCursor, error) {
+	payload, signature, ok := strings.Cut(value, ".")
+	if !ok {
+		return nil, ErrorBadCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, a.sign(payload)) {
+		return nil, ErrorBadCursor
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrorBadCursor
+	}
+	var cursor pageCursor
+	if err = json.Unmarshal(data, &cursor); err != nil || cursor.List != list {
+		return nil, ErrorBadCursor
+	}
+
+	return &cursor, nil
+}
+
+// pageRequest - общие параметры постраничного списка
+type pageRequest struct {
+	list   string
+	limit  int
+	sort   string // при наличии курсора sort и desc берутся из него
+	desc   bool
+	cursor *pageCursor // nil - страница без курсора (первая или по since)
+}
+
+// parsePageRequest читает limit, sort, desc и cursor. list - идентификатор списка, с которым должен совпасть курсор
+func (a *CursorSigner) parsePageRequest(args *fasthttp.Args, list string) (*pageRequest, error) {
+	request := &pageRequest{list: list, limit: DefaultPageLimit}
+
+	if limit, err := strconv.Atoi(string(args.Peek("limit"))); err == nil && limit > 0 {
+		request.limit = limit
+	}
+	request.sort = string(args.Peek("sort"))
+	request.desc, _ = strconv.ParseBool(string(args.Peek("desc")))
+
+	if value := string(args.Peek("cursor")); value != "" {
+		cursor, err := a.decode(value, list)
+		if err != nil {
+			return nil, err
+		}
+		request.cursor = cursor
+		request.sort, request.desc = cursor.Sort, cursor.Desc
+	}
+
+	return request, nil
+}
+
+// queryLimit - сколько записей просить у репозитория: на одну больше страницы, чтобы узнать, есть ли следующая
+func (a *pageRequest) queryLimit() int32 {
+	return int32(a.limit + 1)
+}
+
+// queryDesc - порядок запроса к репозиторию: страница назад читается в обратном порядке от ключа курсора
+func (a *pageRequest) queryDesc() bool {
+	return a.desc != (a.cursor != nil && a.cursor.Backward)
+}
+
+// after - ключ, после которого репозиторий должен начать выдачу, nil без курсора
+func (a *pageRequest) after() *models.PageKey {
+	if a.cursor == nil {
+		return nil
+	}
+
+	return &a.cursor.Key
+}
+
+// pageLinks - курсоры соседних страниц, пустая строка - страницы нет
+type pageLinks struct {
+	next string
+	prev string
+}
+
+// paginate обрезает записи, полученные с запасом queryLimit, до страницы, возвращает страницу назад в порядке списка
+// и строит курсоры соседних страниц. group объединяет записи, которые занимают в limit одно место
+// (посты одного корня в parent_tree), nil - каждая запись сама по себе
+func paginate[T any](signer *CursorSigner, request *pageRequest, items []T, key func(*T) models.PageKey, group func(*T) int64) ([]T, pageLinks) {
+	groups := make([][]T, 0, len(items))
+	for ind := range items {
+		if last := len(groups) - 1; group != nil && last >= 0 && group(&groups[last][0]) == group(&items[ind]) {
+			groups[last] = append(groups[last], items[ind])
+			continue
+		}
+		groups = append(groups, []T{items[ind]})
+	}
+
+	hasMore := len(groups) > request.limit
+	if hasMore {
+		groups = groups[:request.limit]
+	}
+	backward := request.cursor != nil && request.cursor.Backward
+	if backward {
+		for i, j := 0, len(groups)-1; i < j; i, j = i+1, j-1 {
+			groups[i], groups[j] = groups[j], groups[i]
+		}
+	}
+
+	page := make([]T, 0, len(items))
+	for _, g := range groups {
+		page = append(page, g...)
+	}
+
+	var links pageLinks
+	if len(page) == 0 {
+		return page, links
+	}
+	// в сторону, откуда пришёл курсор, страница есть всегда, в сторону чтения - если репозиторий вернул лишнюю запись
+	hasPrev := request.cursor != nil
+	if backward {
+		hasPrev = hasMore
+	}
+	if hasMore || backward {
+		links.next = signer.encode(&pageCursor{List: request.list, Sort: request.sort, Desc: request.desc, Key: key(&page[len(page)-1])})
+	}
+	if hasPrev {
+		links.prev = signer.encode(&pageCursor{List: request.list, Sort: request.sort, Desc: request.desc, Backward: true, Key: key(&page[0])})
+	}
+
+	return page, links
+}
+
+// pageEnvelope - список с метаданными для клиентов, которым нужны общее число записей и ссылки на соседние страницы
+type pageEnvelope struct {
+	Items   interface{}       `json:"items"`
+	Total   *int64            `json:"total,omitempty"` // всего записей в списке, только там, где есть готовый счётчик
+	HasMore bool              `json:"has_more"`        // есть следующая страница
+	Links   pageEnvelopeLinks `json:"links"`
+}
+
+type pageEnvelopeLinks struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// wantsEnvelope - конверт включается параметром envelope=1 или профилем в Accept, по умолчанию список остаётся массивом
+func wantsEnvelope(ctx *fasthttp.RequestCtx) bool {
+	if enabled, err := strconv.ParseBool(string(ctx.QueryArgs().Peek("envelope"))); err == nil {
+		return enabled
+	}
+
+	for _, part := range strings.Split(string(ctx.Request.Header.Peek(fasthttp.HeaderAccept)), ";") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if ok && strings.EqualFold(name, "profile") && strings.Trim(value, `"`) == EnvelopeProfile {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pageLink - ссылка на ту же страницу списка с другим курсором. since вместе с курсором не нужен
+func pageLink(ctx *fasthttp.RequestCtx, cursor string) string {
+	if cursor == "" {
+		return ""
+	}
+
+	var args fasthttp.Args
+	ctx.QueryArgs().CopyTo(&args)
+	args.Del("since")
+	args.Set("cursor", cursor)
+
+	return string(ctx.Path()) + "?" + args.String()
+}
+
+// writePage отдаёт страницу списка: курсоры соседних страниц всегда в заголовках, а тело - массивом
+// или конвертом, если клиент его попросил
+func writePage(ctx *fasthttp.RequestCtx, items interface{}, links pageLinks, total *int64) {
+	writePageLinks(ctx, links)
+
+	if !wantsEnvelope(ctx) {
+		body, _ := json.Marshal(items)
+		ctx.SetBody(body)
+		ctx.SetStatusCode(fasthttp.StatusOK)
+		return
+	}
+
+	body, _ := json.Marshal(pageEnvelope{
+		Items:   items,
+		Total:   total,
+		HasMore: links.next != "",
+		Links: pageEnvelopeLinks{
+			Self: string(ctx.RequestURI()),
+			Next: pageLink(ctx, links.next),
+			Prev: pageLink(ctx, links.prev),
+		},
+	})
+	ctx.SetContentType(`application/json; profile="` + EnvelopeProfile + `"`)
+	ctx.SetBody(body)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+func writePageLinks(ctx *fasthttp.RequestCtx, links pageLinks) {
+	if links.next != "" {
+		ctx.Response.Header.Set(NextCursorHeader, links.next)
+	}
+	if links.prev != "" {
+		ctx.Response.Header.Set(PrevCursorHeader, links.prev)
+	}
+}
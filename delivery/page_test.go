@@ -0,0 +1,209 @@
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"strings"
+	"technopark-db-semester-project/domain/models"
+	"testing"
+)
+
+func TestCursorSignerDecode(t *testing.T) {
+	signer := MakeCursorSigner("secret")
+	cursor := &pageCursor{List: "forum/f1/threads", Sort: models.Flat, Desc: true, Key: models.PageKey{Id: 42}}
+	value := signer.encode(cursor)
+	payload, signature, _ := strings.Cut(value, ".")
+
+	tests := []struct {
+		name  string
+		value string
+		list  string
+		ok    bool
+	}{
+		{name: "valid", value: value, list: cursor.List, ok: true},
+		{name: "other list", value: value, list: "forum/f2/threads"},
+		{name: "other secret", value: MakeCursorSigner("other").encode(cursor), list: cursor.List},
+		{name: "random secret", value: MakeCursorSigner("").encode(cursor), list: cursor.List},
+		{name: "forged payload", value: signer.encode(&pageCursor{List: cursor.List, Key: models.PageKey{Id: 1}})[:len(payload)] + "." + signature, list: cursor.List},
+		{name: "forged signature", value: payload + "." + signature[1:], list: cursor.List},
+		{name: "no signature", value: payload, list: cursor.List},
+		{name: "not base64", value: "!!!." + signature, list: cursor.List},
+		{name: "empty", value: "", list: cursor.List},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decoded, err := signer.decode(test.value, test.list)
+			if !test.ok {
+				if !errors.Is(err, ErrorBadCursor) {
+					t.Fatalf("decode error = %v, want %v", err, ErrorBadCursor)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("decode:", err)
+			}
+			if fmt.Sprint(*decoded) != fmt.Sprint(*cursor) {
+				t.Fatalf("decoded = %+v, want %+v", *decoded, *cursor)
+			}
+		})
+	}
+}
+
+func TestParsePageRequest(t *testing.T) {
+	signer := MakeCursorSigner("secret")
+	list := "thread/1/posts"
+	cursor := signer.encode(&pageCursor{List: list, Sort: models.Tree, Desc: true, Backward: true, Key: models.PageKey{Path: []int64{1, 2}}})
+
+	tests := []struct {
+		name      string
+		query     string
+		err       error
+		limit     int
+		sort      string
+		desc      bool
+		queryDesc bool
+		after     bool
+	}{
+		{name: "defaults", query: "", limit: DefaultPageLimit},
+		{name: "query params", query: "limit=5&sort=flat&desc=true", limit: 5, sort: models.Flat, desc: true, queryDesc: true},
+		{name: "bad limit falls back to default", query: "limit=abc", limit: DefaultPageLimit},
+		// порядок списка берётся из курсора, а страница назад читается в обратном порядке
+		{name: "cursor overrides sort", query: "limit=5&sort=flat&desc=false&cursor=" + cursor, limit: 5, sort: models.Tree, desc: true, queryDesc: false, after: true},
+		{name: "cursor of another list", query: "cursor=" + signer.encode(&pageCursor{List: "thread/2/posts"}), err: ErrorBadCursor},
+		{name: "forged cursor", query: "cursor=" + cursor[:len(cursor)-2], err: ErrorBadCursor},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var args fasthttp.Args
+			args.Parse(test.query)
+
+			request, err := signer.parsePageRequest(&args, list)
+			if test.err != nil || err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("parse error = %v, want %v", err, test.err)
+				}
+				return
+			}
+			if request.limit != test.limit || request.sort != test.sort || request.desc != test.desc {
+				t.Fatalf("request = limit %d sort %q desc %t, want limit %d sort %q desc %t",
+					request.limit, request.sort, request.desc, test.limit, test.sort, test.desc)
+			}
+			if request.queryDesc() != test.queryDesc {
+				t.Fatalf("queryDesc = %t, want %t", request.queryDesc(), test.queryDesc)
+			}
+			if (request.after() != nil) != test.after {
+				t.Fatalf("after = %v, want set %t", request.after(), test.after)
+			}
+			if request.queryLimit() != int32(test.limit+1) {
+				t.Fatalf("queryLimit = %d, want %d", request.queryLimit(), test.limit+1)
+			}
+		})
+	}
+}
+
+// pageItem - запись списка: id - ключ страницы, root - группа, как корень в parent_tree
+type pageItem struct {
+	id   int64
+	root int64
+}
+
+func TestPaginate(t *testing.T) {
+	signer := MakeCursorSigner("secret")
+	list := "list"
+	key := func(item *pageItem) models.PageKey {
+		return models.PageKey{Id: item.id}
+	}
+	group := func(item *pageItem) int64 {
+		return item.root
+	}
+	items := func(ids ...int64) []pageItem {
+		result := make([]pageItem, 0, len(ids))
+		for _, id := range ids {
+			result = append(result, pageItem{id: id, root: id})
+		}
+		return result
+	}
+
+	tests := []struct {
+		name    string
+		limit   int
+		cursor  *pageCursor
+		items   []pageItem // как их вернул репозиторий, с запасом в одну запись или группу
+		grouped bool
+		page    []int64
+		next    int64 // ключ курсора следующей страницы, -1 - курсора нет
+		prev    int64 // ключ курсора предыдущей страницы, -1 - курсора нет
+	}{
+		{name: "first page with more", limit: 2, items: items(1, 2, 3), page: []int64{1, 2}, next: 2, prev: -1},
+		{name: "only page", limit: 3, items: items(1, 2), page: []int64{1, 2}, next: -1, prev: -1},
+		{name: "empty", limit: 3, items: items(), page: []int64{}, next: -1, prev: -1},
+		{name: "forward middle page", limit: 2, cursor: &pageCursor{Key: models.PageKey{Id: 2}}, items: items(3, 4, 5), page: []int64{3, 4}, next: 4, prev: 3},
+		{name: "forward last page", limit: 2, cursor: &pageCursor{Key: models.PageKey{Id: 4}}, items: items(5), page: []int64{5}, next: -1, prev: 5},
+		// назад репозиторий читает в обратном порядке, страница разворачивается обратно
+		{name: "backward middle page", limit: 2, cursor: &pageCursor{Backward: true, Key: models.PageKey{Id: 5}}, items: items(4, 3, 2), page: []int64{3, 4}, next: 4, prev: 3},
+		{name: "backward first page", limit: 2, cursor: &pageCursor{Backward: true, Key: models.PageKey{Id: 3}}, items: items(2, 1), page: []int64{1, 2}, next: 2, prev: -1},
+		{
+			name:    "groups take one place",
+			limit:   2,
+			items:   []pageItem{{id: 1, root: 1}, {id: 4, root: 1}, {id: 2, root: 2}, {id: 5, root: 2}, {id: 6, root: 2}, {id: 3, root: 3}},
+			grouped: true,
+			page:    []int64{1, 4, 2, 5, 6},
+			next:    6,
+			prev:    -1,
+		},
+		{
+			name:    "groups backward keep inner order",
+			limit:   1,
+			cursor:  &pageCursor{Backward: true, Key: models.PageKey{Id: 3}},
+			items:   []pageItem{{id: 2, root: 2}, {id: 5, root: 2}, {id: 1, root: 1}},
+			grouped: true,
+			page:    []int64{2, 5},
+			next:    5,
+			prev:    2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := &pageRequest{list: list, limit: test.limit, cursor: test.cursor}
+			if test.cursor != nil {
+				test.cursor.List = list
+			}
+			var groupBy func(item *pageItem) int64
+			if test.grouped {
+				groupBy = group
+			}
+
+			page, links := paginate(signer, request, test.items, key, groupBy)
+
+			ids := make([]int64, 0, len(page))
+			for _, item := range page {
+				ids = append(ids, item.id)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(test.page) {
+				t.Fatalf("page = %v, want %v", ids, test.page)
+			}
+			checkLink := func(name string, link string, want int64, backward bool) {
+				t.Helper()
+				if want == -1 {
+					if link != "" {
+						t.Fatalf("%s cursor is set, want none", name)
+					}
+					return
+				}
+				decoded, err := signer.decode(link, list)
+				if err != nil {
+					t.Fatalf("%s cursor: %v", name, err)
+				}
+				if decoded.Key.Id != want || decoded.Backward != backward {
+					t.Fatalf("%s cursor = key %d backward %t, want key %d backward %t", name, decoded.Key.Id, decoded.Backward, want, backward)
+				}
+			}
+			checkLink("next", links.next, test.next, false)
+			checkLink("prev", links.prev, test.prev, true)
+		})
+	}
+}
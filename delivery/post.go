@@ -3,21 +3,38 @@ package delivery
 import (
 	"context"
 	"encoding/json"
-	"errors"
+	"fmt"
 	"github.com/valyala/fasthttp"
 	"strconv"
 	"strings"
+	"technopark-db-semester-project/diff"
 	"technopark-db-semester-project/domain"
 	"technopark-db-semester-project/domain/models"
-	"technopark-db-semester-project/repository/postgresql"
+	"technopark-db-semester-project/middleware"
 )
 
+var ErrorBadPostVersion = domain.NewError(domain.CategoryInvalid, "bad_post_version", "bad post version")
+
 type PostHandler struct {
-	postRepo domain.PostRepo
+	postRepo   domain.PostRepo
+	authorizer *Authorizer
 }
 
-func MakePostHandler(postRepo domain.PostRepo) PostHandler {
-	return PostHandler{postRepo: postRepo}
+func MakePostHandler(postRepo domain.PostRepo, authorizer *Authorizer) PostHandler {
+	return PostHandler{postRepo: postRepo, authorizer: authorizer}
+}
+
+// getPost ищет пост для проверки прав и сам отвечает 404, если его нет
+func (a *PostHandler) getPost(ctx *fasthttp.RequestCtx, id int64) (*models.Post, bool) {
+	uctx := ctx.UserValue("ctx").(context.Context)
+
+	post, err := a.postRepo.Get(uctx, id, &models.PostGetRequest{})
+	if err != nil {
+		writeError(ctx, err)
+		return nil, false
+	}
+
+	return post.Post, true
 }
 
 // POST thread/{slug_or_id}/create
@@ -28,19 +45,18 @@ func (a *PostHandler) Create(ctx *fasthttp.RequestCtx) {
 	slugOrId := ctx.UserValue("slug_or_id").(string)
 	postsCreate := make([]models.PostCreate, 0)
 
-	_ = json.Unmarshal(ctx.PostBody(), &postsCreate)
+	if !decodeBody(ctx, &postsCreate) || !writeValidationErrors(ctx, validatePostsCreate(postsCreate)) {
+		return
+	}
+	for ind := range postsCreate {
+		if !a.authorizer.BindUser(ctx, &postsCreate[ind].Author) {
+			return
+		}
+	}
 
 	posts, err := a.postRepo.Create(uctx, slugOrId, &postsCreate)
 	if err != nil {
-		body, _ := json.Marshal(GetErrorMessage(err))
-		ctx.SetBody(body)
-		if errors.Is(err, postgresql.ErrorThreadDoesNotExist) {
-			ctx.SetStatusCode(fasthttp.StatusNotFound)
-		} else if errors.Is(err, postgresql.ErrorParentPostDoesNotExist) {
-			ctx.SetStatusCode(fasthttp.StatusConflict)
-		} else if errors.Is(err, postgresql.ErrorAuthorDoesNotExist) {
-			ctx.SetStatusCode(fasthttp.StatusNotFound)
-		}
+		writeError(ctx, err)
 		return
 	}
 
@@ -66,9 +82,7 @@ func (a *PostHandler) Get(ctx *fasthttp.RequestCtx) {
 	posts, err := a.postRepo.Get(uctx, int64(id), postGet)
 
 	if err != nil {
-		body, _ := json.Marshal(GetErrorMessage(err))
-		ctx.SetBody(body)
-		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		writeError(ctx, err)
 		return
 	}
 
@@ -86,13 +100,20 @@ func (a *PostHandler) Update(ctx *fasthttp.RequestCtx) {
 	id, _ := strconv.Atoi(ctx.UserValue("id").(string))
 	var postUpdate models.PostUpdate
 
-	_ = json.Unmarshal(ctx.PostBody(), &postUpdate)
+	if !decodeBody(ctx, &postUpdate) || !writeValidationErrors(ctx, validatePostUpdate(&postUpdate)) {
+		return
+	}
+
+	current, ok := a.getPost(ctx, int64(id))
+	if !ok || !a.authorizer.Allow(ctx, current.Author, current.Forum) {
+		return
+	}
+	// правку записываем на того, кто её сделал, а не на переданный в теле ник
+	postUpdate.Editor = middleware.GetUser(ctx)
 
 	post, err := a.postRepo.Update(uctx, int64(id), &postUpdate)
 	if err != nil {
-		body, _ := json.Marshal(GetErrorMessage(err))
-		ctx.SetBody(body)
-		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		writeError(ctx, err)
 		return
 	} else {
 		body, _ := json.Marshal(post)
@@ -101,3 +122,117 @@ func (a *PostHandler) Update(ctx *fasthttp.RequestCtx) {
 		return
 	}
 }
+
+// DELETE post/{id}
+func (a *PostHandler) Delete(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("application/json")
+	uctx := ctx.UserValue("ctx").(context.Context)
+	id, _ := strconv.Atoi(ctx.UserValue("id").(string))
+
+	postDelete := &models.PostDeleteRequest{
+		Hard: string(ctx.QueryArgs().Peek("hard")) == "true",
+	}
+
+	// своё сообщение можно удалить мягко, удалить поддерево целиком может только модератор
+	current, ok := a.getPost(ctx, int64(id))
+	if !ok {
+		return
+	}
+	author := current.Author
+	if postDelete.Hard {
+		author = ""
+	}
+	if !a.authorizer.Allow(ctx, author, current.Forum) {
+		return
+	}
+
+	result, err := a.postRepo.Delete(uctx, int64(id), postDelete)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	body, _ := json.Marshal(result)
+	ctx.SetBody(body)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+// GET post/{id}/history
+func (a *PostHandler) History(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("application/json")
+	uctx := ctx.UserValue("ctx").(context.Context)
+	id, _ := strconv.Atoi(ctx.UserValue("id").(string))
+
+	history, err := a.postRepo.GetHistory(uctx, int64(id))
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	body, _ := json.Marshal(history)
+	ctx.SetBody(body)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+// parsePostVersion читает номер версии поста из query, defaultValue - если параметр не передан
+func parsePostVersion(args *fasthttp.Args, name string, defaultValue int32) (int32, error) {
+	value := string(args.Peek(name))
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	version, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s must be an integer", ErrorBadPostVersion, name)
+	}
+
+	return int32(version), nil
+}
+
+// GET post/{id}/diff?from=&to=
+// версии нумеруются с 0 (исходный текст) до числа правок (текущий текст), по умолчанию сравнивается последняя правка
+func (a *PostHandler) Diff(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("application/json")
+	uctx := ctx.UserValue("ctx").(context.Context)
+	id, _ := strconv.Atoi(ctx.UserValue("id").(string))
+
+	history, err := a.postRepo.GetHistory(uctx, int64(id))
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	latest := int32(len(history.Revisions))
+	to, err := parsePostVersion(ctx.QueryArgs(), "to", latest)
+	var from int32
+	if err == nil {
+		defaultFrom := to - 1
+		if defaultFrom < 0 {
+			defaultFrom = 0
+		}
+		from, err = parsePostVersion(ctx.QueryArgs(), "from", defaultFrom)
+	}
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	fromMessage, okFrom := history.Version(from)
+	toMessage, okTo := history.Version(to)
+	if !okFrom || !okTo {
+		err = fmt.Errorf("%w: versions must be between 0 and %d", ErrorBadPostVersion, latest)
+		writeError(ctx, err)
+		return
+	}
+
+	postDiff := &models.PostDiff{
+		Post:    history.Post.Id,
+		From:    from,
+		To:      to,
+		Changes: diff.Lines(fromMessage, toMessage),
+	}
+
+	body, _ := json.Marshal(postDiff)
+	ctx.SetBody(body)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
@@ -0,0 +1,88 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/valyala/fasthttp"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+)
+
+type RoleHandler struct {
+	roleRepo   domain.RoleRepo
+	authorizer *Authorizer
+}
+
+func MakeRoleHandler(roleRepo domain.RoleRepo, authorizer *Authorizer) RoleHandler {
+	return RoleHandler{roleRepo: roleRepo, authorizer: authorizer}
+}
+
+// GET forum/{slug}/roles
+func (a *RoleHandler) GetAll(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("application/json")
+	uctx := ctx.UserValue("ctx").(context.Context)
+	slug := ctx.UserValue("slug").(string)
+
+	roles, err := a.roleRepo.GetAll(uctx, slug)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	body, _ := json.Marshal(roles)
+	ctx.SetBody(body)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+// canAssign - владелец и администраторы назначают любые роли, модераторы только банят и разбанивают участников.
+// Роли владельца и администраторов через api не меняются
+func canAssign(actorRole string, targetRole string, role string) bool {
+	if targetRole == models.RoleOwner || targetRole == models.RoleAdmin {
+		return false
+	}
+
+	switch actorRole {
+	case models.RoleAdmin, models.RoleOwner:
+		return true
+	case models.RoleModerator:
+		return targetRole != models.RoleModerator && role != models.RoleModerator
+	default:
+		return false
+	}
+}
+
+// POST forum/{slug}/roles
+func (a *RoleHandler) Set(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("application/json")
+	uctx := ctx.UserValue("ctx").(context.Context)
+	slug := ctx.UserValue("slug").(string)
+
+	var role models.ForumRole
+	if !decodeBody(ctx, &role) || !writeValidationErrors(ctx, validateForumRole(&role)) {
+		return
+	}
+
+	actorRole, ok := a.authorizer.ActorRole(ctx, slug)
+	if !ok {
+		return
+	}
+	targetRole, err := a.authorizer.Role(uctx, slug, role.Nickname)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if !canAssign(actorRole, targetRole, role.Role) {
+		writeError(ctx, ErrorForbidden)
+		return
+	}
+
+	assigned, err := a.roleRepo.Set(uctx, slug, &role)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	body, _ := json.Marshal(assigned)
+	ctx.SetBody(body)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
@@ -0,0 +1,142 @@
+package delivery
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+	"time"
+)
+
+const MaxSearchLimit = 100
+
+var (
+	ErrorBadSearchCursor = domain.NewError(domain.CategoryInvalid, "bad_search_cursor", "bad search cursor")
+	ErrorBadSearchParam  = domain.NewError(domain.CategoryInvalid, "bad_search_param", "bad search parameter")
+)
+
+type SearchHandler struct {
+	searchRepo domain.SearchRepo
+}
+
+func MakeSearchHandler(searchRepo domain.SearchRepo) SearchHandler {
+	return SearchHandler{searchRepo: searchRepo}
+}
+
+type searchResponse struct {
+	Hits []models.SearchHit `json:"hits"`
+	Next string             `json:"next,omitempty"` // передаётся в cursor для получения следующей страницы
+}
+
+func encodeSearchCursor(cursor *models.SearchCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSearchCursor(value string) (*models.SearchCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrorBadSearchCursor
+	}
+
+	var cursor models.SearchCursor
+	if err = json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrorBadSearchCursor
+	}
+
+	return &cursor, nil
+}
+
+func parseSearchTime(args *fasthttp.Args, name string) (*time.Time, error) {
+	value := string(args.Peek(name))
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be RFC 3339 time", ErrorBadSearchParam, name)
+	}
+
+	return &parsed, nil
+}
+
+func parseSearchRequest(args *fasthttp.Args) (*models.SearchRequest, error) {
+	request := &models.SearchRequest{
+		Query:  string(args.Peek("q")),
+		Type:   string(args.Peek("type")),
+		Forum:  string(args.Peek("forum")),
+		Thread: string(args.Peek("thread")),
+		Author: string(args.Peek("author")),
+		Sort:   string(args.Peek("sort")),
+	}
+
+	if request.Type != "" && request.Type != models.SearchTypePost && request.Type != models.SearchTypeThread {
+		return nil, fmt.Errorf("%w: type must be post or thread", ErrorBadSearchParam)
+	}
+	if request.Sort == "" {
+		request.Sort = models.SearchSortRelevance
+	}
+	if request.Sort != models.SearchSortRelevance && request.Sort != models.SearchSortDate {
+		return nil, fmt.Errorf("%w: sort must be relevance or date", ErrorBadSearchParam)
+	}
+
+	// limit уже проверен validateLimit, без него отдаётся максимум
+	limit, err := strconv.Atoi(string(args.Peek("limit")))
+	if err != nil {
+		limit = MaxSearchLimit
+	}
+	request.Limit = int32(limit)
+
+	if request.Since, err = parseSearchTime(args, "since"); err != nil {
+		return nil, err
+	}
+	if request.Until, err = parseSearchTime(args, "until"); err != nil {
+		return nil, err
+	}
+
+	if cursor := string(args.Peek("cursor")); cursor != "" {
+		if request.Cursor, err = decodeSearchCursor(cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	return request, nil
+}
+
+// GET search
+func (a *SearchHandler) Search(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("application/json")
+	uctx := ctx.UserValue("ctx").(context.Context)
+
+	if !writeValidationErrors(ctx, validateLimit(ctx.QueryArgs(), MaxSearchLimit)) {
+		return
+	}
+
+	request, err := parseSearchRequest(ctx.QueryArgs())
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	result, err := a.searchRepo.Search(uctx, request)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	response := searchResponse{Hits: result.Hits}
+	if result.Next != nil {
+		response.Next = encodeSearchCursor(result.Next)
+	}
+
+	body, _ := json.Marshal(response)
+	ctx.SetBody(body)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+
+	return
+}
@@ -19,7 +19,11 @@ func MakeServiceHandler(serviceRepo domain.ServiceRepo) ServiceHandler {
 func (a *ServiceHandler) GetInfo(ctx *fasthttp.RequestCtx) {
 	ctx.SetContentType("application/json")
 	uctx := ctx.UserValue("ctx").(context.Context)
-	result, _ := a.serviceRepo.GetInfo(uctx)
+	result, err := a.serviceRepo.GetInfo(uctx)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
 
 	body, _ := json.Marshal(result)
 	ctx.SetBody(body)
@@ -33,7 +37,10 @@ func (a *ServiceHandler) Clear(ctx *fasthttp.RequestCtx) {
 	ctx.SetContentType("application/json")
 	uctx := ctx.UserValue("ctx").(context.Context)
 
-	_ = a.serviceRepo.Clear(uctx)
+	if err := a.serviceRepo.Clear(uctx); err != nil {
+		writeError(ctx, err)
+		return
+	}
 	
 	ctx.SetStatusCode(fasthttp.StatusOK)
 
@@ -6,17 +6,37 @@ import (
 	"errors"
 	"github.com/valyala/fasthttp"
 	"strconv"
+	"strings"
 	"technopark-db-semester-project/domain"
 	"technopark-db-semester-project/domain/models"
-	"technopark-db-semester-project/repository/postgresql"
+)
+
+var (
+	ErrorBadPostSort = domain.NewError(domain.CategoryInvalid, "bad_post_sort", "sort must be flat, tree, parent_tree or score")
 )
 
 type ThreadHandler struct {
 	threadRepo domain.ThreadRepo
+	postRepo   domain.PostRepo
+	authorizer *Authorizer
+	cursors    *CursorSigner
 }
 
-func MakeThreadHandler(threadRepo domain.ThreadRepo) ThreadHandler {
-	return ThreadHandler{threadRepo: threadRepo}
+func MakeThreadHandler(threadRepo domain.ThreadRepo, postRepo domain.PostRepo, authorizer *Authorizer, cursors *CursorSigner) ThreadHandler {
+	return ThreadHandler{threadRepo: threadRepo, postRepo: postRepo, authorizer: authorizer, cursors: cursors}
+}
+
+// getThread ищет ветку для проверки прав и сам отвечает 404, если её нет
+func (a *ThreadHandler) getThread(ctx *fasthttp.RequestCtx, slugOrId string) (*models.Thread, bool) {
+	uctx := ctx.UserValue("ctx").(context.Context)
+
+	thread, err := a.threadRepo.Get(uctx, slugOrId)
+	if err != nil {
+		writeError(ctx, err)
+		return nil, false
+	}
+
+	return thread, true
 }
 
 // POST forum/{slug}/create
@@ -26,21 +46,24 @@ func (a *ThreadHandler) Create(ctx *fasthttp.RequestCtx) {
 	slug := ctx.UserValue("slug").(string)
 
 	var threadCreate models.ThreadCreate
-	_ = json.Unmarshal(ctx.PostBody(), &threadCreate)
+	if !decodeBody(ctx, &threadCreate) || !writeValidationErrors(ctx, validateThreadCreate(&threadCreate)) {
+		return
+	}
+	if !a.authorizer.BindUser(ctx, &threadCreate.Author) {
+		return
+	}
 
 	thread, err := a.threadRepo.Create(uctx, slug, &threadCreate)
 	if err != nil {
-		if errors.Is(err, postgresql.ErrorNoAuthorOrForum) {
-			body, _ := json.Marshal(GetErrorMessage(err))
-			ctx.SetBody(body)
-			ctx.SetStatusCode(fasthttp.StatusNotFound)
-			return
-		} else if errors.Is(err, postgresql.ErrorThreadAlreadyExist) {
+		if errors.Is(err, domain.ErrorThreadAlreadyExist) {
 			body, _ := json.Marshal(thread)
 			ctx.SetBody(body)
 			ctx.SetStatusCode(fasthttp.StatusConflict)
 			return
 		}
+
+		writeError(ctx, err)
+		return
 	}
 
 	body, _ := json.Marshal(thread)
@@ -58,9 +81,7 @@ func (a *ThreadHandler) Get(ctx *fasthttp.RequestCtx) {
 
 	thread, err := a.threadRepo.Get(uctx, slugOrId)
 	if err != nil {
-		body, _ := json.Marshal(GetErrorMessage(err))
-		ctx.SetBody(body)
-		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		writeError(ctx, err)
 		return
 	}
 
@@ -78,13 +99,18 @@ func (a *ThreadHandler) Update(ctx *fasthttp.RequestCtx) {
 	slugOrId := ctx.UserValue("slug_or_id").(string)
 
 	var threadUpdate models.ThreadUpdate
-	_ = json.Unmarshal(ctx.PostBody(), &threadUpdate)
+	if !decodeBody(ctx, &threadUpdate) || !writeValidationErrors(ctx, validateThreadUpdate(&threadUpdate)) {
+		return
+	}
+
+	current, ok := a.getThread(ctx, slugOrId)
+	if !ok || !a.authorizer.Allow(ctx, current.Author, current.Forum) {
+		return
+	}
 
 	thread, err := a.threadRepo.Update(uctx, slugOrId, &threadUpdate)
 	if err != nil {
-		body, _ := json.Marshal(GetErrorMessage(err))
-		ctx.SetBody(body)
-		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		writeError(ctx, err)
 		return
 	}
 
@@ -96,14 +122,21 @@ func (a *ThreadHandler) Update(ctx *fasthttp.RequestCtx) {
 }
 
 // GET thread/{slug_or_id}/posts
+// курсор хранит ключ сортировки: (created, id) для flat, parent_path для tree, id корня для parent_tree.
+// В score рейтинг меняется от голосов, поэтому там курсор - id поста и можно листать только вперёд
 func (a *ThreadHandler) GetPosts(ctx *fasthttp.RequestCtx) {
 	ctx.SetContentType("application/json")
 	uctx := ctx.UserValue("ctx").(context.Context)
 	slugOrId := ctx.UserValue("slug_or_id").(string)
 
-	limit, err := strconv.Atoi(string(ctx.QueryArgs().Peek("limit")))
+	if !writeValidationErrors(ctx, validateLimit(ctx.QueryArgs(), MaxPageLimit)) {
+		return
+	}
+
+	request, err := a.cursors.parsePageRequest(ctx.QueryArgs(), "thread/"+strings.ToLower(slugOrId)+"/posts")
 	if err != nil {
-		limit = 100
+		writeError(ctx, err)
+		return
 	}
 
 	since, err := strconv.Atoi(string(ctx.QueryArgs().Peek("since")))
@@ -111,35 +144,218 @@ func (a *ThreadHandler) GetPosts(ctx *fasthttp.RequestCtx) {
 		since = -1
 	}
 
-	sort := string(ctx.QueryArgs().Peek("sort"))
-	if sort == "" {
-		sort = models.Flat
+	if request.sort == "" {
+		request.sort = models.Flat
 	}
-
-	desc, err := strconv.ParseBool(string(ctx.QueryArgs().Peek("desc")))
-	if err != nil {
-		desc = false
+	key := func(post *models.Post) models.PageKey {
+		return models.PageKey{Id: post.Id}
+	}
+	var group func(post *models.Post) int64
+	switch request.sort {
+	case models.Flat:
+		key = func(post *models.Post) models.PageKey {
+			return models.PageKey{Created: post.Created, Id: post.Id}
+		}
+	case models.Tree:
+		key = func(post *models.Post) models.PageKey {
+			return models.PageKey{Path: post.Path}
+		}
+	case models.ParentTree:
+		group = func(post *models.Post) int64 {
+			return post.Path[0]
+		}
+		key = func(post *models.Post) models.PageKey {
+			return models.PageKey{Id: post.Path[0]}
+		}
+	case models.Score:
+	default:
+		writeError(ctx, ErrorBadPostSort)
+		return
 	}
 
 	threadGetPosts := &models.ThreadPostRequest{
-		Limit: int32(limit),
+		Limit: request.queryLimit(),
 		Since: int64(since),
-		Sort:  sort,
-		Desc:  desc,
+		Sort:  request.sort,
+		Desc:  request.queryDesc(),
+		After: request.after(),
 	}
 
 	posts, err := a.threadRepo.GetPosts(uctx, slugOrId, threadGetPosts)
 
 	if err != nil {
-		body, _ := json.Marshal(GetErrorMessage(err))
-		ctx.SetBody(body)
-		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		writeError(ctx, err)
+		return
+	}
+
+	page, links := paginate(a.cursors, request, *posts, key, group)
+	if request.sort == models.Score {
+		links.prev = ""
+	}
+	// счётчик постов есть только у форума, у ветки его нет, поэтому total не отдаётся
+	writePage(ctx, page, links, nil)
+
+	return
+}
+
+// POST thread/{slug_or_id}/state
+func (a *ThreadHandler) SetState(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("application/json")
+	uctx := ctx.UserValue("ctx").(context.Context)
+	slugOrId := ctx.UserValue("slug_or_id").(string)
+
+	var stateUpdate models.ThreadStateUpdate
+	if !decodeBody(ctx, &stateUpdate) || !writeValidationErrors(ctx, validateThreadState(&stateUpdate)) {
+		return
+	}
+
+	current, ok := a.getThread(ctx, slugOrId)
+	if !ok || !a.authorizer.Allow(ctx, "", current.Forum) {
+		return
+	}
+
+	thread, err := a.threadRepo.SetState(uctx, slugOrId, &stateUpdate)
+	if err != nil {
+		writeError(ctx, err)
 		return
 	}
 
-	body, _ := json.Marshal(posts)
+	body, _ := json.Marshal(thread)
 	ctx.SetBody(body)
 	ctx.SetStatusCode(fasthttp.StatusOK)
+}
 
-	return
+// POST thread/{slug_or_id}/pin
+func (a *ThreadHandler) SetPin(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("application/json")
+	uctx := ctx.UserValue("ctx").(context.Context)
+	slugOrId := ctx.UserValue("slug_or_id").(string)
+
+	var pin models.ThreadPin
+	if !decodeBody(ctx, &pin) || !writeValidationErrors(ctx, validateThreadPin(&pin)) {
+		return
+	}
+
+	// объявление видно во всех форумах, поэтому его ставят и снимают только администраторы
+	current, ok := a.getThread(ctx, slugOrId)
+	if !ok || !a.authorizer.Allow(ctx, "", current.Forum) {
+		return
+	}
+	if pin.Announcement != current.Announcement && !a.authorizer.AllowAdmin(ctx) {
+		return
+	}
+
+	thread, err := a.threadRepo.SetPin(uctx, slugOrId, &pin)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	body, _ := json.Marshal(thread)
+	ctx.SetBody(body)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+// POST thread/{slug_or_id}/move
+func (a *ThreadHandler) Move(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("application/json")
+	uctx := ctx.UserValue("ctx").(context.Context)
+	slugOrId := ctx.UserValue("slug_or_id").(string)
+
+	var move models.ThreadMove
+	if !decodeBody(ctx, &move) || !writeValidationErrors(ctx, validateThreadMove(&move)) {
+		return
+	}
+
+	current, ok := a.getThread(ctx, slugOrId)
+	if !ok || !a.authorizer.Allow(ctx, "", current.Forum, move.Forum) {
+		return
+	}
+
+	thread, err := a.threadRepo.Move(uctx, slugOrId, &move)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	body, _ := json.Marshal(thread)
+	ctx.SetBody(body)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+// POST thread/{slug_or_id}/merge
+func (a *ThreadHandler) Merge(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("application/json")
+	uctx := ctx.UserValue("ctx").(context.Context)
+	slugOrId := ctx.UserValue("slug_or_id").(string)
+
+	var merge models.ThreadMerge
+	if !decodeBody(ctx, &merge) || !writeValidationErrors(ctx, validateThreadMerge(&merge)) {
+		return
+	}
+
+	source, ok := a.getThread(ctx, slugOrId)
+	if !ok {
+		return
+	}
+	target, ok := a.getThread(ctx, merge.Into)
+	if !ok {
+		return
+	}
+	// into может указывать на ту же ветку по slug вместо id, поэтому сравниваются найденные ветки
+	if source.Id == target.Id {
+		writeError(ctx, domain.ErrorMergeSameThread)
+		return
+	}
+	if !a.authorizer.Allow(ctx, "", source.Forum, target.Forum) {
+		return
+	}
+
+	thread, err := a.threadRepo.Merge(uctx, slugOrId, &merge)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	body, _ := json.Marshal(thread)
+	ctx.SetBody(body)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+// POST post/{id}/split
+func (a *ThreadHandler) Split(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("application/json")
+	uctx := ctx.UserValue("ctx").(context.Context)
+	id, _ := strconv.Atoi(ctx.UserValue("id").(string))
+
+	var split models.ThreadSplit
+	if !decodeBody(ctx, &split) || !writeValidationErrors(ctx, validateThreadSplit(&split)) {
+		return
+	}
+
+	post, err := a.postRepo.Get(uctx, int64(id), &models.PostGetRequest{})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if !a.authorizer.Allow(ctx, "", post.Post.Forum) {
+		return
+	}
+
+	thread, err := a.threadRepo.Split(uctx, int64(id), &split)
+	if err != nil {
+		if errors.Is(err, domain.ErrorThreadAlreadyExist) {
+			body, _ := json.Marshal(thread)
+			ctx.SetBody(body)
+			ctx.SetStatusCode(fasthttp.StatusConflict)
+			return
+		}
+
+		writeError(ctx, err)
+		return
+	}
+
+	body, _ := json.Marshal(thread)
+	ctx.SetBody(body)
+	ctx.SetStatusCode(fasthttp.StatusCreated)
 }
@@ -0,0 +1,348 @@
+package delivery
+
+import (
+	"context"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+	"technopark-db-semester-project/repository/memory"
+	"testing"
+)
+
+// createPostTree создаёт в ветке дерево постов: a, b - корни, a1 и a2 - ответы на a, a1x - ответ на a1, b1 - на b.
+// Возвращает id постов по тексту
+func createPostTree(t *testing.T, env *testEnv, thread string) map[string]int64 {
+	t.Helper()
+
+	ids := make(map[string]int64)
+	for _, post := range []struct{ message, parent string }{
+		{"a", ""}, {"b", ""}, {"a1", "a"}, {"b1", "b"}, {"a2", "a"}, {"a1x", "a1"},
+	} {
+		ids[post.message] = env.createPost(t, thread, "alice", ids[post.parent], post.message)
+	}
+
+	return ids
+}
+
+// getPosts отдаёт тексты постов ветки и их родителей по тексту, "" - корень
+func getPosts(t *testing.T, env *testEnv, thread string, query string) ([]string, []string) {
+	t.Helper()
+
+	ctx := serve(env.thread.GetPosts, testRequest{values: map[string]string{"slug_or_id": thread}, query: query})
+	var posts []models.Post
+	decodeResponse(t, ctx, fasthttp.StatusOK, &posts)
+
+	byId := make(map[int64]string)
+	messages := make([]string, 0, len(posts))
+	for _, post := range posts {
+		byId[post.Id] = post.Message
+		messages = append(messages, post.Message)
+	}
+	parents := make([]string, 0, len(posts))
+	for _, post := range posts {
+		parents = append(parents, byId[post.Parent])
+	}
+
+	return messages, parents
+}
+
+// paths - parent_path постов ветки в порядке tree, их нет в ответе обработчика, поэтому они читаются из хранилища
+func paths(t *testing.T, env *testEnv, thread string) map[string][]int64 {
+	t.Helper()
+
+	posts, err := memory.NewThreadMemoryRepo(env.storage).GetPosts(context.Background(), thread,
+		&models.ThreadPostRequest{Limit: 100, Since: -1, Sort: models.Tree})
+	if err != nil {
+		t.Fatal("get posts:", err)
+	}
+	result := make(map[string][]int64)
+	for _, post := range *posts {
+		result[post.Message] = post.Path
+	}
+
+	return result
+}
+
+func getThread(t *testing.T, env *testEnv, slugOrId string) *fasthttp.RequestCtx {
+	t.Helper()
+
+	return serve(env.thread.Get, testRequest{values: map[string]string{"slug_or_id": slugOrId}})
+}
+
+func getForum(t *testing.T, env *testEnv, slug string) models.Forum {
+	t.Helper()
+
+	var forum models.Forum
+	decodeResponse(t, serve(env.forum.Get, testRequest{values: map[string]string{"slug": slug}}), fasthttp.StatusOK, &forum)
+
+	return forum
+}
+
+func TestThreadGetPostsSort(t *testing.T) {
+	tests := []struct {
+		query    string
+		messages []string
+	}{
+		{query: "sort=flat", messages: []string{"a", "b", "a1", "b1", "a2", "a1x"}},
+		{query: "sort=flat&desc=true", messages: []string{"a1x", "a2", "b1", "a1", "b", "a"}},
+		{query: "sort=flat&limit=2", messages: []string{"a", "b"}},
+		{query: "sort=tree", messages: []string{"a", "a1", "a1x", "a2", "b", "b1"}},
+		{query: "sort=tree&desc=true", messages: []string{"b1", "b", "a2", "a1x", "a1", "a"}},
+		{query: "sort=tree&limit=3", messages: []string{"a", "a1", "a1x"}},
+		// limit в parent_tree считает корни, ответы идут за корнем в порядке дерева и при desc
+		{query: "sort=parent_tree&limit=1", messages: []string{"a", "a1", "a1x", "a2"}},
+		{query: "sort=parent_tree&limit=1&desc=true", messages: []string{"b", "b1"}},
+		{query: "sort=parent_tree&desc=true", messages: []string{"b", "b1", "a", "a1", "a1x", "a2"}},
+	}
+
+	runBackends(t, func(t *testing.T, env *testEnv) {
+		env.createUser(t, "alice")
+		env.createForum(t, "f1", "alice")
+		env.createThread(t, "f1", "alice", "t1", 0)
+		createPostTree(t, env, "t1")
+
+		for _, test := range tests {
+			t.Run(test.query, func(t *testing.T) {
+				messages, _ := getPosts(t, env, "t1", test.query)
+				if fmt.Sprint(messages) != fmt.Sprint(test.messages) {
+					t.Fatalf("posts = %v, want %v", messages, test.messages)
+				}
+			})
+		}
+	})
+}
+
+// TestThreadGetPostsPages листает каждую сортировку по одному посту или корню вперёд до конца и обратно
+func TestThreadGetPostsPages(t *testing.T) {
+	runBackends(t, func(t *testing.T, env *testEnv) {
+		env.createUser(t, "alice")
+		env.createForum(t, "f1", "alice")
+		env.createThread(t, "f1", "alice", "t1", 0)
+		createPostTree(t, env, "t1")
+
+		for _, query := range []string{"sort=flat", "sort=flat&desc=true", "sort=tree", "sort=tree&desc=true", "sort=parent_tree", "sort=parent_tree&desc=true"} {
+			t.Run(query, func(t *testing.T) {
+				all, _ := getPosts(t, env, "t1", query)
+				get := func(query string) ([]string, string, string) {
+					ctx := serve(env.thread.GetPosts, testRequest{values: map[string]string{"slug_or_id": "t1"}, query: query})
+					var posts []models.Post
+					decodeResponse(t, ctx, fasthttp.StatusOK, &posts)
+					messages := make([]string, 0, len(posts))
+					for _, post := range posts {
+						messages = append(messages, post.Message)
+					}
+					return messages, string(ctx.Response.Header.Peek(NextCursorHeader)), string(ctx.Response.Header.Peek(PrevCursorHeader))
+				}
+
+				var pages [][]string
+				var seen []string
+				page, next, prev := get(query + "&limit=1")
+				for {
+					pages = append(pages, page)
+					seen = append(seen, page...)
+					if next == "" {
+						break
+					}
+					page, next, prev = get("limit=1&cursor=" + next)
+				}
+				if fmt.Sprint(seen) != fmt.Sprint(all) {
+					t.Fatalf("pages forward = %v, want %v", seen, all)
+				}
+
+				for ind := len(pages) - 2; ind >= 0; ind-- {
+					page, _, prev = get("limit=1&cursor=" + prev)
+					if fmt.Sprint(page) != fmt.Sprint(pages[ind]) {
+						t.Fatalf("page %d backward = %v, forward %v", ind, page, pages[ind])
+					}
+				}
+				if prev != "" {
+					t.Fatal("first page reached backward has prev cursor")
+				}
+			})
+		}
+	})
+}
+
+func TestThreadMerge(t *testing.T) {
+	runBackends(t, func(t *testing.T, env *testEnv) {
+		env.createUser(t, "alice")
+		env.createForum(t, "f1", "alice")
+		env.createForum(t, "f2", "alice")
+		source := env.createThread(t, "f1", "alice", "source", 0)
+		env.createThread(t, "f2", "alice", "target", 1)
+		env.createPost(t, "target", "alice", 0, "c")
+		ids := createPostTree(t, env, "source")
+
+		// ветки и форумы попадают в кэш до слияния
+		decodeResponse(t, getThread(t, env, "source"), fasthttp.StatusOK, nil)
+		decodeResponse(t, getThread(t, env, fmt.Sprint(source.Id)), fasthttp.StatusOK, nil)
+		getForum(t, env, "f1")
+		getForum(t, env, "f2")
+
+		ctx := serve(env.thread.Merge, testRequest{
+			values: map[string]string{"slug_or_id": fmt.Sprint(source.Id)},
+			body:   models.ThreadMerge{Into: "target"},
+			user:   "admin",
+		})
+		decodeResponse(t, ctx, fasthttp.StatusOK, nil)
+
+		// текст исходной ветки стал корнем, её корни - ответами на него, остальное дерево сохранилось
+		messages, parents := getPosts(t, env, "target", "sort=tree")
+		if want := []string{"c", "source", "a", "a1", "a1x", "a2", "b", "b1"}; fmt.Sprint(messages) != fmt.Sprint(want) {
+			t.Fatalf("target posts = %v, want %v", messages, want)
+		}
+		if want := []string{"", "", "source", "a", "a1", "a", "source", "b"}; fmt.Sprint(parents) != fmt.Sprint(want) {
+			t.Fatalf("target parents = %v, want %v", parents, want)
+		}
+		got := paths(t, env, "target")
+		root := got["source"][0]
+		for message, want := range map[string][]int64{
+			"source": {root},
+			"a":      {root, ids["a"]},
+			"a1x":    {root, ids["a"], ids["a1"], ids["a1x"]},
+			"b1":     {root, ids["b"], ids["b1"]},
+		} {
+			if fmt.Sprint(got[message]) != fmt.Sprint(want) {
+				t.Fatalf("path of %s = %v, want %v", message, got[message], want)
+			}
+		}
+
+		// исходной ветки нет ни по slug, ни по id, даже если она лежала в кэше
+		decodeError(t, getThread(t, env, "source"), fasthttp.StatusNotFound, domain.ErrorThreadDoesNotExist.Code)
+		decodeError(t, getThread(t, env, fmt.Sprint(source.Id)), fasthttp.StatusNotFound, domain.ErrorThreadDoesNotExist.Code)
+		if forum := getForum(t, env, "f1"); forum.Threads != 0 || forum.Posts != 0 {
+			t.Fatalf("source forum = %d threads %d posts, want 0 and 0", forum.Threads, forum.Posts)
+		}
+		if forum := getForum(t, env, "f2"); forum.Threads != 1 || forum.Posts != 8 {
+			t.Fatalf("target forum = %d threads %d posts, want 1 and 8", forum.Threads, forum.Posts)
+		}
+	})
+}
+
+func TestThreadSplit(t *testing.T) {
+	runBackends(t, func(t *testing.T, env *testEnv) {
+		env.createUser(t, "alice")
+		env.createForum(t, "f1", "alice")
+		env.createThread(t, "f1", "alice", "t1", 0)
+		ids := createPostTree(t, env, "t1")
+		getForum(t, env, "f1")
+
+		ctx := serve(env.thread.Split, testRequest{
+			values: map[string]string{"id": fmt.Sprint(ids["a"])},
+			body:   models.ThreadSplit{Title: "split", Slug: "split"},
+			user:   "admin",
+		})
+		var thread models.Thread
+		decodeResponse(t, ctx, fasthttp.StatusCreated, &thread)
+		if thread.Message != "a" || thread.Forum != "f1" {
+			t.Fatalf("split thread = %+v, want message a in f1", thread)
+		}
+
+		// ответы на пост стали корнями новой ветки, а пути поддерева потеряли общий префикс
+		messages, parents := getPosts(t, env, "split", "sort=tree")
+		if want := []string{"a1", "a1x", "a2"}; fmt.Sprint(messages) != fmt.Sprint(want) {
+			t.Fatalf("split posts = %v, want %v", messages, want)
+		}
+		if want := []string{"", "a1", ""}; fmt.Sprint(parents) != fmt.Sprint(want) {
+			t.Fatalf("split parents = %v, want %v", parents, want)
+		}
+		got := paths(t, env, "split")
+		for message, want := range map[string][]int64{
+			"a1":  {ids["a1"]},
+			"a1x": {ids["a1"], ids["a1x"]},
+			"a2":  {ids["a2"]},
+		} {
+			if fmt.Sprint(got[message]) != fmt.Sprint(want) {
+				t.Fatalf("path of %s = %v, want %v", message, got[message], want)
+			}
+		}
+
+		if messages, _ = getPosts(t, env, "t1", "sort=tree"); fmt.Sprint(messages) != fmt.Sprint([]string{"b", "b1"}) {
+			t.Fatalf("source posts = %v, want [b b1]", messages)
+		}
+		// сам пост стал текстом ветки и из счётчика постов ушёл
+		if forum := getForum(t, env, "f1"); forum.Threads != 2 || forum.Posts != 5 {
+			t.Fatalf("forum = %d threads %d posts, want 2 and 5", forum.Threads, forum.Posts)
+		}
+
+		ctx = serve(env.thread.Split, testRequest{
+			values: map[string]string{"id": fmt.Sprint(ids["b1"])},
+			body:   models.ThreadSplit{Title: "again", Slug: "SPLIT"},
+			user:   "admin",
+		})
+		decodeResponse(t, ctx, fasthttp.StatusConflict, &thread)
+		if thread.Slug != "split" {
+			t.Fatalf("conflict returned thread %q, want split", thread.Slug)
+		}
+	})
+}
+
+func TestThreadModerationValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(env *testEnv) fasthttp.RequestHandler
+		values  map[string]string
+		body    interface{}
+		status  int
+		code    string
+	}{
+		{name: "merge without into", handler: func(env *testEnv) fasthttp.RequestHandler { return env.thread.Merge },
+			values: map[string]string{"slug_or_id": "t1"}, body: models.ThreadMerge{}, status: fasthttp.StatusBadRequest, code: CodeValidationFailed},
+		{name: "merge into itself by slug", handler: func(env *testEnv) fasthttp.RequestHandler { return env.thread.Merge },
+			values: map[string]string{"slug_or_id": "1"}, body: models.ThreadMerge{Into: "T1"}, status: fasthttp.StatusBadRequest, code: domain.ErrorMergeSameThread.Code},
+		{name: "move without forum", handler: func(env *testEnv) fasthttp.RequestHandler { return env.thread.Move },
+			values: map[string]string{"slug_or_id": "t1"}, body: models.ThreadMove{}, status: fasthttp.StatusBadRequest, code: CodeValidationFailed},
+		{name: "split with numeric slug", handler: func(env *testEnv) fasthttp.RequestHandler { return env.thread.Split },
+			values: map[string]string{"id": "1"}, body: models.ThreadSplit{Title: "split", Slug: "42"}, status: fasthttp.StatusBadRequest, code: CodeValidationFailed},
+		{name: "split without title", handler: func(env *testEnv) fasthttp.RequestHandler { return env.thread.Split },
+			values: map[string]string{"id": "1"}, body: models.ThreadSplit{Slug: "split"}, status: fasthttp.StatusBadRequest, code: CodeValidationFailed},
+		{name: "move to missing forum", handler: func(env *testEnv) fasthttp.RequestHandler { return env.thread.Move },
+			values: map[string]string{"slug_or_id": "t1"}, body: models.ThreadMove{Forum: "missing"}, status: fasthttp.StatusNotFound, code: domain.ErrorForumDoesNotExist.Code},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			env := newTestEnv(t, false)
+			env.createUser(t, "alice")
+			env.createForum(t, "f1", "alice")
+			env.createThread(t, "f1", "alice", "t1", 0)
+			env.createPost(t, "t1", "alice", 0, "a")
+
+			ctx := serve(test.handler(env), testRequest{values: test.values, body: test.body, user: "admin"})
+			decodeError(t, ctx, test.status, test.code)
+		})
+	}
+}
+
+func TestThreadMove(t *testing.T) {
+	runBackends(t, func(t *testing.T, env *testEnv) {
+		env.createUser(t, "alice")
+		env.createForum(t, "f1", "alice")
+		env.createForum(t, "f2", "alice")
+		thread := env.createThread(t, "f1", "alice", "t1", 0)
+		env.createPost(t, "t1", "alice", 0, "a")
+		getForum(t, env, "f1")
+		getForum(t, env, "f2")
+		decodeResponse(t, getThread(t, env, fmt.Sprint(thread.Id)), fasthttp.StatusOK, nil)
+
+		ctx := serve(env.thread.Move, testRequest{
+			values: map[string]string{"slug_or_id": "t1"},
+			body:   models.ThreadMove{Forum: "F2"},
+			user:   "admin",
+		})
+		decodeResponse(t, ctx, fasthttp.StatusOK, nil)
+
+		var moved models.Thread
+		decodeResponse(t, getThread(t, env, fmt.Sprint(thread.Id)), fasthttp.StatusOK, &moved)
+		if moved.Forum != "f2" {
+			t.Fatalf("thread forum = %q, want f2", moved.Forum)
+		}
+		if forum := getForum(t, env, "f1"); forum.Threads != 0 || forum.Posts != 0 {
+			t.Fatalf("source forum = %d threads %d posts, want 0 and 0", forum.Threads, forum.Posts)
+		}
+		if forum := getForum(t, env, "f2"); forum.Threads != 1 || forum.Posts != 1 {
+			t.Fatalf("target forum = %d threads %d posts, want 1 and 1", forum.Threads, forum.Posts)
+		}
+	})
+}
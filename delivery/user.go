@@ -7,15 +7,15 @@ import (
 	"github.com/valyala/fasthttp"
 	"technopark-db-semester-project/domain"
 	"technopark-db-semester-project/domain/models"
-	"technopark-db-semester-project/repository/postgresql"
 )
 
 type UserHandler struct {
-	userRepo domain.UserRepo
+	userRepo   domain.UserRepo
+	authorizer *Authorizer
 }
 
-func MakeUserHandler(userRepo domain.UserRepo) UserHandler {
-	return UserHandler{userRepo: userRepo}
+func MakeUserHandler(userRepo domain.UserRepo, authorizer *Authorizer) UserHandler {
+	return UserHandler{userRepo: userRepo, authorizer: authorizer}
 }
 
 // POST user/{nickname}/create
@@ -25,10 +25,30 @@ func (a *UserHandler) Create(ctx *fasthttp.RequestCtx) {
 
 	nickname := ctx.UserValue("nickname").(string)
 	var user models.User
-	_ = json.Unmarshal(ctx.PostBody(), &user)
+	if !decodeBody(ctx, &user) {
+		return
+	}
 	user.Nickname = nickname
+	if !writeValidationErrors(ctx, validateUserCreate(&user)) {
+		return
+	}
+
+	// пароль необязателен, как и до появления входа: без него войти нельзя, пока администратор не выдаст сброс пароля
+	if user.Password != "" {
+		hash, err := HashPassword(user.Password)
+		if err != nil {
+			writeError(ctx, err)
+			return
+		}
+		user.Password = ""
+		user.PasswordHash = hash
+	}
 
 	userAfterCreate, err := a.userRepo.Create(uctx, &user)
+	if err != nil && !errors.Is(err, domain.ErrorUserAlreadyExist) {
+		writeError(ctx, err)
+		return
+	}
 	if err != nil {
 		body, _ := json.Marshal(userAfterCreate)
 		ctx.SetBody(body)
@@ -51,9 +71,7 @@ func (a *UserHandler) Get(ctx *fasthttp.RequestCtx) {
 	nickname := ctx.UserValue("nickname").(string)
 	user, err := a.userRepo.Get(uctx, nickname)
 	if err != nil {
-		body, _ := json.Marshal(GetErrorMessage(err))
-		ctx.SetBody(body)
-		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		writeError(ctx, err)
 		return
 	}
 
@@ -70,18 +88,17 @@ func (a *UserHandler) Update(ctx *fasthttp.RequestCtx) {
 	uctx := ctx.UserValue("ctx").(context.Context)
 
 	nickname := ctx.UserValue("nickname").(string)
+	if !a.authorizer.AllowSelf(ctx, nickname) {
+		return
+	}
 	var updateData models.UserUpdate
-	_ = json.Unmarshal(ctx.PostBody(), &updateData)
+	if !decodeBody(ctx, &updateData) || !writeValidationErrors(ctx, validateUserUpdate(&updateData)) {
+		return
+	}
 
 	user, err := a.userRepo.Update(uctx, nickname, &updateData)
 	if err != nil {
-		body, _ := json.Marshal(GetErrorMessage(err))
-		ctx.SetBody(body)
-		if errors.Is(err, postgresql.ErrorUserDoesNotExist) {
-			ctx.SetStatusCode(fasthttp.StatusNotFound)
-		} else if errors.Is(err, postgresql.ErrorConflictUpdateUser) {
-			ctx.SetStatusCode(fasthttp.StatusConflict)
-		}
+		writeError(ctx, err)
 		return
 	}
 
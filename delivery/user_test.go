@@ -0,0 +1,87 @@
+package delivery
+
+import (
+	"github.com/valyala/fasthttp"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+	"testing"
+)
+
+// TestUserNicknameCase - ник и почта сравниваются без учёта регистра, как citext в Postgres, а отдаются в исходном
+func TestUserNicknameCase(t *testing.T) {
+	runBackends(t, func(t *testing.T, env *testEnv) {
+		ctx := serve(env.user.Create, testRequest{
+			values: map[string]string{"nickname": "Alice"},
+			body:   models.User{Fullname: "Alice", Email: "Alice@example.com"},
+		})
+		decodeResponse(t, ctx, fasthttp.StatusCreated, nil)
+
+		tests := []struct {
+			name     string
+			nickname string
+			email    string
+			conflict []string // ники пользователей, которых вернёт конфликт
+		}{
+			{name: "same nickname other case", nickname: "alice", email: "other@example.com", conflict: []string{"Alice"}},
+			{name: "same email other case", nickname: "bob", email: "ALICE@example.com", conflict: []string{"Alice"}},
+			{name: "new user", nickname: "Bob", email: "bob@example.com"},
+			{name: "nickname of one, email of another", nickname: "ALICE", email: "BOB@example.com", conflict: []string{"Alice", "Bob"}},
+		}
+		for _, test := range tests {
+			ctx := serve(env.user.Create, testRequest{
+				values: map[string]string{"nickname": test.nickname},
+				body:   models.User{Fullname: test.nickname, Email: test.email},
+			})
+			if test.conflict == nil {
+				decodeResponse(t, ctx, fasthttp.StatusCreated, nil)
+				continue
+			}
+			var users []models.User
+			decodeResponse(t, ctx, fasthttp.StatusConflict, &users)
+			nicknames := make([]string, 0, len(users))
+			for _, user := range users {
+				nicknames = append(nicknames, user.Nickname)
+			}
+			if len(nicknames) != len(test.conflict) {
+				t.Fatalf("%s: conflict with %v, want %v", test.name, nicknames, test.conflict)
+			}
+			for _, want := range test.conflict {
+				found := false
+				for _, nickname := range nicknames {
+					found = found || nickname == want
+				}
+				if !found {
+					t.Fatalf("%s: conflict with %v, want %v", test.name, nicknames, test.conflict)
+				}
+			}
+		}
+
+		for _, nicknameOrEmail := range []string{"alice", "ALICE", "alice@EXAMPLE.com"} {
+			var user models.User
+			decodeResponse(t, serve(env.user.Get, testRequest{values: map[string]string{"nickname": nicknameOrEmail}}), fasthttp.StatusOK, &user)
+			if user.Nickname != "Alice" {
+				t.Fatalf("get %s = %q, want Alice", nicknameOrEmail, user.Nickname)
+			}
+		}
+
+		// профиль, прочитанный до правки, не должен остаться в кэше
+		ctx = serve(env.user.Update, testRequest{
+			values: map[string]string{"nickname": "aLiCe"},
+			body:   models.UserUpdate{About: "updated"},
+			user:   "alice",
+		})
+		decodeResponse(t, ctx, fasthttp.StatusOK, nil)
+		var user models.User
+		decodeResponse(t, serve(env.user.Get, testRequest{values: map[string]string{"nickname": "ALICE"}}), fasthttp.StatusOK, &user)
+		if user.About != "updated" {
+			t.Fatalf("about = %q after update, want updated", user.About)
+		}
+
+		ctx = serve(env.user.Update, testRequest{
+			values: map[string]string{"nickname": "alice"},
+			body:   models.UserUpdate{Email: "bob@EXAMPLE.com"},
+			user:   "alice",
+		})
+		decodeError(t, ctx, fasthttp.StatusConflict, domain.ErrorConflictUpdateUser.Code)
+	})
+}
@@ -0,0 +1,297 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"regexp"
+	"strconv"
+	"strings"
+	"technopark-db-semester-project/domain/models"
+)
+
+const (
+	CodeMalformedJson    = "malformed_json"
+	CodeValidationFailed = "validation_failed"
+)
+
+// коды ошибок отдельных полей
+const (
+	FieldRequired      = "required"
+	FieldInvalidType   = "invalid_type"
+	FieldInvalidFormat = "invalid_format"
+	FieldInvalidValue  = "invalid_value"
+)
+
+var (
+	nicknamePattern = regexp.MustCompile(`^[A-Za-z0-9_.]+$`)
+	slugPattern     = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	numericPattern  = regexp.MustCompile(`^[0-9]+$`)
+	emailPattern    = regexp.MustCompile(`^[^@\s]+@[^@\s]+$`)
+)
+
+type validator struct {
+	prefix string // префикс имён полей, для элементов массива
+	fields []FieldError
+}
+
+func (v *validator) add(field, code, message string) {
+	v.fields = append(v.fields, FieldError{Field: v.prefix + field, Code: code, Message: message})
+}
+
+func (v *validator) required(field, value string) bool {
+	if value == "" {
+		v.add(field, FieldRequired, "is required")
+		return false
+	}
+
+	return true
+}
+
+func (v *validator) match(field, value string, pattern *regexp.Regexp, message string) {
+	if value != "" && !pattern.MatchString(value) {
+		v.add(field, FieldInvalidFormat, message)
+	}
+}
+
+func (v *validator) nickname(field, value string) {
+	v.match(field, value, nicknamePattern, "may contain only latin letters, digits, '_' and '.'")
+}
+
+func (v *validator) slug(field, value string) {
+	v.match(field, value, slugPattern, "may contain only latin letters, digits, '_' and '-'")
+}
+
+// notBlank - необязательное поле обновления, если передано, не может состоять из одних пробелов
+func (v *validator) notBlank(field, value string) {
+	if value != "" && strings.TrimSpace(value) == "" {
+		v.add(field, FieldInvalidValue, "must not be blank")
+	}
+}
+
+// threadSlug - числовой slug нельзя отличить от id в маршрутах thread/{slug_or_id}
+func (v *validator) threadSlug(field, value string) {
+	v.slug(field, value)
+	if numericPattern.MatchString(value) {
+		v.add(field, FieldInvalidFormat, "must not be a number")
+	}
+}
+
+// decodeBody разбирает тело запроса в dst. Пустое тело означает, что поля не переданы.
+// При ошибке сам пишет ответ 400
+func decodeBody(ctx *fasthttp.RequestCtx, dst interface{}) bool {
+	body := ctx.PostBody()
+	if len(body) == 0 {
+		return true
+	}
+
+	err := json.Unmarshal(body, dst)
+	if err == nil {
+		return true
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return writeValidationErrors(ctx, []FieldError{{
+			Field:   typeErr.Field,
+			Code:    FieldInvalidType,
+			Message: fmt.Sprintf("must be %s, got %s", typeErr.Type.String(), typeErr.Value),
+		}})
+	}
+
+	response, _ := json.Marshal(&Error{Message: err.Error(), Code: CodeMalformedJson})
+	ctx.SetBody(response)
+	ctx.SetStatusCode(fasthttp.StatusBadRequest)
+
+	return false
+}
+
+// writeValidationErrors пишет 400 со списком ошибок полей. Возвращает true, если ошибок нет
+func writeValidationErrors(ctx *fasthttp.RequestCtx, fields []FieldError) bool {
+	if len(fields) == 0 {
+		return true
+	}
+
+	response, _ := json.Marshal(&Error{Message: "request validation failed", Code: CodeValidationFailed, Fields: fields})
+	ctx.SetBody(response)
+	ctx.SetStatusCode(fasthttp.StatusBadRequest)
+
+	return false
+}
+
+// validateLimit проверяет limit в query списков: без проверки отрицательный или нулевой limit доходил бы до базы.
+// max - наибольший limit эндпоинта, обычно MaxPageLimit
+func validateLimit(args *fasthttp.Args, max int) []FieldError {
+	v := validator{}
+	if value := args.Peek("limit"); len(value) > 0 {
+		limit, err := strconv.Atoi(string(value))
+		if err != nil || limit <= 0 || limit > max {
+			v.add("limit", FieldInvalidValue, fmt.Sprintf("must be an integer from 1 to %d", max))
+		}
+	}
+
+	return v.fields
+}
+
+func validateUserCreate(user *models.User) []FieldError {
+	v := validator{}
+	v.nickname("nickname", user.Nickname)
+	v.required("fullname", user.Fullname)
+	if v.required("email", user.Email) {
+		v.match("email", user.Email, emailPattern, "must be a valid email address")
+	}
+
+	return v.fields
+}
+
+func validatePasswordChange(change *models.PasswordChange) []FieldError {
+	v := validator{}
+	v.required("password", change.Password)
+	if change.CurrentPassword == "" && change.ResetToken == "" {
+		v.add("current_password", FieldRequired, "is required unless reset_token is given")
+	}
+
+	return v.fields
+}
+
+func validateUserUpdate(update *models.UserUpdate) []FieldError {
+	v := validator{}
+	v.match("email", update.Email, emailPattern, "must be a valid email address")
+
+	return v.fields
+}
+
+func validateForumCreate(forum *models.ForumCreate) []FieldError {
+	v := validator{}
+	v.required("title", forum.Title)
+	if v.required("slug", forum.Slug) {
+		v.slug("slug", forum.Slug)
+	}
+	v.nickname("user", forum.User)
+
+	return v.fields
+}
+
+func validateThreadCreate(thread *models.ThreadCreate) []FieldError {
+	v := validator{}
+	v.required("title", thread.Title)
+	v.required("message", thread.Message)
+	v.nickname("author", thread.Author)
+	v.threadSlug("slug", thread.Slug)
+
+	return v.fields
+}
+
+func validateThreadMove(move *models.ThreadMove) []FieldError {
+	v := validator{}
+	if v.required("forum", move.Forum) {
+		v.slug("forum", move.Forum)
+	}
+
+	return v.fields
+}
+
+func validateThreadMerge(merge *models.ThreadMerge) []FieldError {
+	v := validator{}
+	v.required("into", merge.Into)
+
+	return v.fields
+}
+
+func validateThreadSplit(split *models.ThreadSplit) []FieldError {
+	v := validator{}
+	v.required("title", split.Title)
+	v.threadSlug("slug", split.Slug)
+
+	return v.fields
+}
+
+func validateThreadUpdate(update *models.ThreadUpdate) []FieldError {
+	v := validator{}
+	v.notBlank("title", update.Title)
+	v.notBlank("message", update.Message)
+
+	return v.fields
+}
+
+func validateThreadState(update *models.ThreadStateUpdate) []FieldError {
+	v := validator{}
+	if v.required("state", update.State) {
+		switch update.State {
+		case models.ThreadOpen, models.ThreadClosed, models.ThreadLocked:
+		default:
+			v.add("state", FieldInvalidValue, "must be open, closed or locked")
+		}
+	}
+
+	return v.fields
+}
+
+func validateThreadPin(pin *models.ThreadPin) []FieldError {
+	v := validator{}
+	if pin.Pinned < 0 {
+		v.add("pinned", FieldInvalidValue, "must not be negative")
+	}
+
+	return v.fields
+}
+
+func validatePostUpdate(update *models.PostUpdate) []FieldError {
+	v := validator{}
+	v.notBlank("message", update.Message)
+
+	return v.fields
+}
+
+func validateForumRole(role *models.ForumRole) []FieldError {
+	v := validator{}
+	if v.required("nickname", role.Nickname) {
+		v.nickname("nickname", role.Nickname)
+	}
+	if v.required("role", role.Role) {
+		switch role.Role {
+		case models.RoleModerator, models.RoleMember, models.RoleBanned:
+		default:
+			v.add("role", FieldInvalidValue, "must be moderator, member or banned")
+		}
+	}
+
+	return v.fields
+}
+
+func validatePostsCreate(posts []models.PostCreate) []FieldError {
+	v := validator{}
+	for ind := range posts {
+		v.prefix = fmt.Sprintf("[%d].", ind)
+		v.required("message", posts[ind].Message)
+		v.nickname("author", posts[ind].Author)
+		if posts[ind].Parent < 0 {
+			v.add("parent", FieldInvalidValue, "must not be negative")
+		}
+	}
+
+	return v.fields
+}
+
+func validateVoteCreate(vote *models.VoteCreate) []FieldError {
+	v := validator{}
+	v.nickname("nickname", vote.Nickname)
+	// без voice голос отозвался бы молча, поэтому 0 нужно передать явно
+	if vote.Voice == nil {
+		v.add("voice", FieldRequired, "is required")
+	} else if *vote.Voice < -1 || *vote.Voice > 1 {
+		v.add("voice", FieldInvalidValue, ErrorBadVoice.Error())
+	}
+
+	return v.fields
+}
+
+func validateNotificationsRead(read *models.NotificationsRead) []FieldError {
+	v := validator{}
+	if !read.All && len(read.Ids) == 0 {
+		v.add("ids", FieldRequired, "is required unless all is true")
+	}
+
+	return v.fields
+}
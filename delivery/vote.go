@@ -4,16 +4,20 @@ import (
 	"context"
 	"encoding/json"
 	"github.com/valyala/fasthttp"
+	"strconv"
 	"technopark-db-semester-project/domain"
 	"technopark-db-semester-project/domain/models"
 )
 
+var ErrorBadVoice = domain.NewError(domain.CategoryInvalid, "bad_voice", "voice must be -1, 1 or 0 to retract the vote")
+
 type VoteHandler struct {
-	voteRepo domain.VoteRepo
+	voteRepo   domain.VoteRepo
+	authorizer *Authorizer
 }
 
-func MakeVoteHandler(voteRepo domain.VoteRepo) VoteHandler {
-	return VoteHandler{voteRepo: voteRepo}
+func MakeVoteHandler(voteRepo domain.VoteRepo, authorizer *Authorizer) VoteHandler {
+	return VoteHandler{voteRepo: voteRepo, authorizer: authorizer}
 }
 
 // POST thread/{slug_or_id}/vote
@@ -23,14 +27,17 @@ func (a *VoteHandler) Create(ctx *fasthttp.RequestCtx) {
 
 	slugOrId := ctx.UserValue("slug_or_id").(string)
 	var voteCreate models.VoteCreate
-	_ = json.Unmarshal(ctx.PostBody(), &voteCreate)
+	if !decodeBody(ctx, &voteCreate) || !writeValidationErrors(ctx, validateVoteCreate(&voteCreate)) {
+		return
+	}
+	if !a.authorizer.BindUser(ctx, &voteCreate.Nickname) {
+		return
+	}
 
 	thread, err := a.voteRepo.Create(uctx, slugOrId, &voteCreate)
 
 	if err != nil {
-		body, _ := json.Marshal(GetErrorMessage(err))
-		ctx.SetBody(body)
-		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		writeError(ctx, err)
 		return
 	}
 
@@ -40,3 +47,122 @@ func (a *VoteHandler) Create(ctx *fasthttp.RequestCtx) {
 
 	return
 }
+
+// POST post/{id}/vote
+func (a *VoteHandler) CreateForPost(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("application/json")
+	uctx := ctx.UserValue("ctx").(context.Context)
+	id, _ := strconv.Atoi(ctx.UserValue("id").(string))
+
+	var voteCreate models.VoteCreate
+	if !decodeBody(ctx, &voteCreate) || !writeValidationErrors(ctx, validateVoteCreate(&voteCreate)) {
+		return
+	}
+	if !a.authorizer.BindUser(ctx, &voteCreate.Nickname) {
+		return
+	}
+
+	post, err := a.voteRepo.CreateForPost(uctx, int64(id), &voteCreate)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	body, _ := json.Marshal(post)
+	ctx.SetBody(body)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+// GET thread/{slug_or_id}/votes
+func (a *VoteHandler) GetByThread(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("application/json")
+	uctx := ctx.UserValue("ctx").(context.Context)
+	slugOrId := ctx.UserValue("slug_or_id").(string)
+
+	if !writeValidationErrors(ctx, validateLimit(ctx.QueryArgs(), MaxPageLimit)) {
+		return
+	}
+
+	limit, err := strconv.Atoi(string(ctx.QueryArgs().Peek("limit")))
+	if err != nil {
+		limit = 100
+	}
+
+	desc, err := strconv.ParseBool(string(ctx.QueryArgs().Peek("desc")))
+	if err != nil {
+		desc = false
+	}
+
+	voice := 0
+	if value := ctx.QueryArgs().Peek("voice"); len(value) > 0 {
+		voice, err = strconv.Atoi(string(value))
+		if err != nil || (voice != -1 && voice != 1) {
+			writeError(ctx, ErrorBadVoice)
+			return
+		}
+	}
+
+	threadVotes := &models.GetThreadVotes{
+		Limit: int32(limit),
+		Since: string(ctx.QueryArgs().Peek("since")),
+		Desc:  desc,
+		Voice: int32(voice),
+	}
+
+	votes, err := a.voteRepo.GetByThread(uctx, slugOrId, threadVotes)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	body, _ := json.Marshal(votes)
+	ctx.SetBody(body)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
+
+// GET user/{nickname}/votes
+func (a *VoteHandler) GetByUser(ctx *fasthttp.RequestCtx) {
+	ctx.SetContentType("application/json")
+	uctx := ctx.UserValue("ctx").(context.Context)
+	nickname := ctx.UserValue("nickname").(string)
+
+	// свои голоса видит только сам пользователь и администраторы
+	if !a.authorizer.AllowSelf(ctx, nickname) {
+		return
+	}
+
+	if !writeValidationErrors(ctx, validateLimit(ctx.QueryArgs(), MaxPageLimit)) {
+		return
+	}
+
+	limit, err := strconv.Atoi(string(ctx.QueryArgs().Peek("limit")))
+	if err != nil {
+		limit = 100
+	}
+
+	since, err := strconv.Atoi(string(ctx.QueryArgs().Peek("since")))
+	if err != nil {
+		since = 0
+	}
+
+	desc, err := strconv.ParseBool(string(ctx.QueryArgs().Peek("desc")))
+	if err != nil {
+		desc = false
+	}
+
+	userVotes := &models.GetUserVotes{
+		Limit: int32(limit),
+		Since: int32(since),
+		Desc:  desc,
+	}
+
+	votes, err := a.voteRepo.GetByUser(uctx, nickname, userVotes)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	body, _ := json.Marshal(votes)
+	ctx.SetBody(body)
+	ctx.SetStatusCode(fasthttp.StatusOK)
+}
@@ -0,0 +1,61 @@
+package delivery
+
+import (
+	"fmt"
+	"github.com/valyala/fasthttp"
+	"technopark-db-semester-project/domain/models"
+	"testing"
+)
+
+// TestVoteReplace - повторный голос заменяет прежний, 0 его отзывает. Рейтинг из ответа и из ветки должен совпадать
+func TestVoteReplace(t *testing.T) {
+	voice := func(value int32) *int32 {
+		return &value
+	}
+
+	runBackends(t, func(t *testing.T, env *testEnv) {
+		env.createUser(t, "alice")
+		env.createUser(t, "bob")
+		env.createForum(t, "f1", "alice")
+		thread := env.createThread(t, "f1", "alice", "t1", 0)
+
+		steps := []struct {
+			user  string
+			voice *int32
+			votes int32
+		}{
+			{user: "alice", voice: voice(1), votes: 1},
+			{user: "ALICE", voice: voice(1), votes: 1},
+			{user: "alice", voice: voice(-1), votes: -1},
+			{user: "bob", voice: voice(-1), votes: -2},
+			{user: "alice", voice: voice(0), votes: -1},
+			{user: "alice", voice: voice(0), votes: -1},
+			{user: "bob", voice: voice(1), votes: 1},
+		}
+		for ind, step := range steps {
+			// ветка читается до голоса, чтобы с кэшем она в нём лежала
+			decodeResponse(t, getThread(t, env, "t1"), fasthttp.StatusOK, nil)
+
+			var voted models.Thread
+			ctx := serve(env.vote.Create, testRequest{
+				values: map[string]string{"slug_or_id": "T1"},
+				body:   models.VoteCreate{Voice: step.voice},
+				user:   step.user,
+			})
+			decodeResponse(t, ctx, fasthttp.StatusOK, &voted)
+			if voted.Votes != step.votes {
+				t.Fatalf("step %d: vote returned %d votes, want %d", ind, voted.Votes, step.votes)
+			}
+			for _, slugOrId := range []string{"t1", fmt.Sprint(thread.Id)} {
+				var current models.Thread
+				decodeResponse(t, getThread(t, env, slugOrId), fasthttp.StatusOK, &current)
+				if current.Id != thread.Id || current.Votes != step.votes {
+					t.Fatalf("step %d: thread %s has %d votes, want %d", ind, slugOrId, current.Votes, step.votes)
+				}
+			}
+		}
+
+		ctx := serve(env.vote.Create, testRequest{values: map[string]string{"slug_or_id": "t1"}, body: models.VoteCreate{}, user: "alice"})
+		decodeError(t, ctx, fasthttp.StatusBadRequest, CodeValidationFailed)
+	})
+}
@@ -0,0 +1,63 @@
+package diff
+
+import (
+	"strings"
+	"technopark-db-semester-project/domain/models"
+)
+
+// Lines строит построчную разницу между двумя текстами через наибольшую общую подпоследовательность строк.
+// Соседние строки с одной операцией склеиваются в одно изменение
+func Lines(from, to string) []models.DiffChange {
+	a := strings.Split(from, "\n")
+	b := strings.Split(to, "\n")
+
+	// lcs[i][j] - длина наибольшей общей подпоследовательности a[i:] и b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	changes := make([]models.DiffChange, 0)
+	add := func(op, line string) {
+		if last := len(changes) - 1; last >= 0 && changes[last].Op == op {
+			changes[last].Text += "\n" + line
+			return
+		}
+		changes = append(changes, models.DiffChange{Op: op, Text: line})
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			add(models.DiffEqual, a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			add(models.DiffDelete, a[i])
+			i++
+		default:
+			add(models.DiffInsert, b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		add(models.DiffDelete, a[i])
+	}
+	for ; j < len(b); j++ {
+		add(models.DiffInsert, b[j])
+	}
+
+	return changes
+}
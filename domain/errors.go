@@ -0,0 +1,77 @@
+package domain
+
+// Category - класс ошибки, по нему delivery выбирает код ответа
+type Category string
+
+const (
+	CategoryInvalid      Category = "invalid"
+	CategoryUnauthorized Category = "unauthorized"
+	CategoryForbidden    Category = "forbidden"
+	CategoryNotFound     Category = "not_found"
+	CategoryConflict     Category = "conflict"
+	CategoryLocked       Category = "locked"
+	CategoryInternal     Category = "internal"
+)
+
+// Error - ошибка предметной области с машиночитаемым кодом. Исходная ошибка (например, от pgx) доступна через Unwrap,
+// но в текст не попадает, чтобы детали хранилища не уходили клиенту
+type Error struct {
+	Code     string
+	Category Category
+	Message  string
+	Err      error
+}
+
+func NewError(category Category, code string, message string) *Error {
+	return &Error{Code: code, Category: category, Message: message}
+}
+
+func (a *Error) Error() string {
+	return a.Message
+}
+
+func (a *Error) Unwrap() error {
+	return a.Err
+}
+
+// Is сравнивает ошибки по коду, поэтому errors.Is(err, ErrorX) работает и для копий, созданных Wrap
+func (a *Error) Is(target error) bool {
+	other, ok := target.(*Error)
+	return ok && other.Code == a.Code
+}
+
+// Wrap возвращает копию ошибки с причиной err
+func (a *Error) Wrap(err error) *Error {
+	wrapped := *a
+	wrapped.Err = err
+
+	return &wrapped
+}
+
+var (
+	ErrorUserAlreadyExist   = NewError(CategoryConflict, "user_already_exists", "user already exist")
+	ErrorUserDoesNotExist   = NewError(CategoryNotFound, "user_not_found", "user does not exist")
+	ErrorConflictUpdateUser = NewError(CategoryConflict, "user_conflict", "data conflicts with existing users")
+	ErrorUserBanned         = NewError(CategoryForbidden, "user_banned", "user is banned in this forum")
+
+	ErrorSessionDoesNotExist       = NewError(CategoryUnauthorized, "session_not_found", "session does not exist or expired")
+	ErrorPasswordResetDoesNotExist = NewError(CategoryUnauthorized, "reset_token_not_found", "password reset token does not exist or expired")
+
+	ErrorForumAlreadyExist = NewError(CategoryConflict, "forum_already_exists", "forum already exist")
+	ErrorForumDoesNotExist = NewError(CategoryNotFound, "forum_not_found", "forum does not exist")
+
+	ErrorNoAuthorOrForum    = NewError(CategoryNotFound, "author_or_forum_not_found", "author or forum does not exist")
+	ErrorThreadAlreadyExist = NewError(CategoryConflict, "thread_already_exists", "thread already exist")
+	ErrorThreadDoesNotExist = NewError(CategoryNotFound, "thread_not_found", "thread does not exist")
+	ErrorThreadClosed       = NewError(CategoryForbidden, "thread_closed", "thread is closed")
+	ErrorThreadLocked       = NewError(CategoryLocked, "thread_locked", "thread is locked")
+	ErrorMergeSameThread    = NewError(CategoryInvalid, "merge_same_thread", "thread cannot be merged into itself")
+	ErrorSplitPostMoving    = NewError(CategoryConflict, "split_post_moving", "post keeps moving between threads, retry the split")
+
+	ErrorPostDoesNotExist       = NewError(CategoryNotFound, "post_not_found", "post does not exist")
+	ErrorAuthorDoesNotExist     = NewError(CategoryNotFound, "author_not_found", "author does not exist")
+	ErrorParentPostDoesNotExist = NewError(CategoryConflict, "parent_not_found", "parent post does not exist") // по API это 409, а не 404
+	ErrorPostIsDeleted          = NewError(CategoryConflict, "post_deleted", "post is deleted")
+
+	ErrorEmptySearchQuery = NewError(CategoryInvalid, "empty_search_query", "search query is empty")
+)
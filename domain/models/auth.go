@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+type Credentials struct {
+	Nickname string `json:"nickname"`
+	Password string `json:"password"`
+}
+
+type Session struct {
+	TokenHash []byte    `json:"-"` // sha256 токена, сам токен не хранится
+	Nickname  string    `json:"nickname"`
+	Expires   time.Time `json:"expires"`
+}
+
+type LoginResult struct {
+	Token    string    `json:"token"` // передаётся в заголовке Authorization: Bearer <token>
+	Nickname string    `json:"nickname"`
+	Expires  time.Time `json:"expires"`
+}
+
+// PasswordReset - одноразовый токен сброса пароля, его выдаёт администратор
+type PasswordReset struct {
+	TokenHash []byte    `json:"-"` // sha256 токена, как у сессий
+	Nickname  string    `json:"nickname"`
+	Expires   time.Time `json:"expires"`
+}
+
+type PasswordResetResult struct {
+	Token    string    `json:"token"` // передаётся пользователю, он задаёт по нему новый пароль
+	Nickname string    `json:"nickname"`
+	Expires  time.Time `json:"expires"`
+}
+
+// PasswordChange - новый пароль и подтверждение права его задать: текущий пароль или токен сброса
+type PasswordChange struct {
+	Password        string `json:"password"`
+	CurrentPassword string `json:"current_password,omitempty"`
+	ResetToken      string `json:"reset_token,omitempty"`
+}
@@ -22,7 +22,8 @@ type GetForumUsers struct {
 }
 
 type GetForumThreads struct {
-	Limit int32  `json:"limit,omitempty"` // default 100
-	Since string `json:"since,omitempty"` // nickname пользователя, с которого будем выводить результат
-	Desc  bool   `json:"desc,omitempty"`
+	Limit int32    `json:"limit,omitempty"` // default 100
+	Since string   `json:"since,omitempty"` // nickname пользователя, с которого будем выводить результат
+	Desc  bool     `json:"desc,omitempty"`
+	After *PageKey `json:"-"` // (created, id) последней выданной ветки, если задан - since не учитывается
 }
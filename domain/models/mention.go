@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+type Mention struct {
+	Id       int64     `json:"id"`
+	Nickname string    `json:"nickname"`       // кого упомянули
+	Post     int64     `json:"post,omitempty"` // id сообщения, 0 - упоминание в тексте ветки
+	Thread   int32     `json:"thread"`
+	Forum    string    `json:"forum"`
+	Author   string    `json:"author"`  // nickname автора сообщения или ветки
+	Created  time.Time `json:"created"` // когда упоминание появилось: при создании или правке текста
+}
+
+type GetMentions struct {
+	Limit int32 `json:"limit"` // default 100
+	Since int64 `json:"since"` // id упоминания, после которого выводить, 0 - с начала
+	Desc  bool  `json:"desc"`
+}
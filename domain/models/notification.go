@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Subscription - подписка пользователя на ветку или на все ветки форума, задано ровно одно из Thread и Forum
+type Subscription struct {
+	Nickname string `json:"nickname"`
+	Thread   int32  `json:"thread,omitempty"`
+	Forum    string `json:"forum,omitempty"`
+}
+
+type Notification struct {
+	Id      int64     `json:"id"`
+	Kind    string    `json:"kind"`           // reply, mention, thread или forum - почему пользователь получил уведомление
+	Post    int64     `json:"post,omitempty"` // id сообщения, 0 - упоминание в тексте ветки
+	Thread  int32     `json:"thread"`
+	Forum   string    `json:"forum"`
+	Author  string    `json:"author"` // nickname автора сообщения
+	Created time.Time `json:"created"`
+	Read    bool      `json:"read"`
+}
+
+// причины уведомления. Если их несколько, остаётся первая по этому списку: ответ, упоминание, подписка на ветку, подписка на форум
+const (
+	NotificationReply   = "reply"
+	NotificationMention = "mention"
+	NotificationThread  = "thread"
+	NotificationForum   = "forum"
+)
+
+type GetNotifications struct {
+	Limit  int32 `json:"limit"` // default 100
+	Since  int64 `json:"since"` // id уведомления, после которого выводить, 0 - с начала
+	Desc   bool  `json:"desc"`
+	Unread bool  `json:"unread"` // только непрочитанные
+}
+
+type NotificationsRead struct {
+	Ids []int64 `json:"ids"` // какие уведомления отметить прочитанными
+	All bool    `json:"all"` // отметить все, Ids тогда не учитываются
+}
+
+type NotificationsCount struct {
+	Unread int64 `json:"unread"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// PageKey - ключ сортировки записи на границе страницы, следующая страница начинается строго после него.
+// Какие поля заполнены, зависит от списка: ник для пользователей форума, (created, id) для веток и постов в flat,
+// parent_path для tree, id корневого поста для parent_tree и id поста для score
+type PageKey struct {
+	Nickname string    `json:"n,omitempty"`
+	Created  time.Time `json:"c,omitempty"`
+	Id       int64     `json:"i,omitempty"`
+	Path     []int64   `json:"p,omitempty"`
+}
@@ -3,14 +3,18 @@ package models
 import "time"
 
 type Post struct {
-	Id       int64     `json:"id"`
-	Parent   int64     `json:"parent"` // id родительского сообщения. 0 - корневое сообщение обсуждения
-	Author   string    `json:"author"` // nickname автора сообщения
-	Message  string    `json:"message"`
-	IsEdited bool      `json:"isEdited"` // true, если сообщение было изменено
-	Forum    string    `json:"forum"`    // slug форума данного сообщения
-	Thread   int32     `json:"thread"`   // id ветви данного сообщения
-	Created  time.Time `json:"created"`
+	Id        int64     `json:"id"`
+	Parent    int64     `json:"parent"` // id родительского сообщения. 0 - корневое сообщение обсуждения
+	Author    string    `json:"author"` // nickname автора сообщения
+	Message   string    `json:"message"`
+	IsEdited  bool      `json:"isEdited"` // true, если сообщение было изменено
+	Forum     string    `json:"forum"`    // slug форума данного сообщения
+	Thread    int32     `json:"thread"`   // id ветви данного сообщения
+	Created   time.Time `json:"created"`
+	IsDeleted bool      `json:"isDeleted,omitempty"` // true, если сообщение удалено: текст стёрт, но пост остаётся на своём месте в дереве
+	Votes     int32     `json:"votes"`               // сумма голосов за сообщение
+
+	Path []int64 `json:"-"` // parent_path: id предков от корня и самого сообщения, заполняется только в списке сообщений ветки
 }
 
 type PostCreate struct {
@@ -32,8 +36,60 @@ type PostGetRequest struct {
 
 type PostUpdate struct {
 	Message string `json:"message,omitempty"`
+	Editor  string `json:"editor,omitempty"` // nickname правящего, по умолчанию автор поста
+}
+
+type PostDeleteRequest struct {
+	Hard bool `json:"hard,omitempty"` // удалить пост вместе со всеми ответами на него
+}
+
+type PostDeleteResult struct {
+	Post    *Post `json:"post,omitempty"` // надгробие, только для мягкого удаления
+	Deleted int64 `json:"deleted"`        // сколько постов убрано из счётчика форума
+}
+
+type PostRevision struct {
+	Revision int32     `json:"revision"` // номер правки, начиная с 1
+	Editor   string    `json:"editor"`   // nickname того, кто сделал правку
+	Message  string    `json:"message"`  // текст сообщения до правки
+	Created  time.Time `json:"created"`  // время правки
+}
+
+type PostHistory struct {
+	Post      *Post          `json:"post"`
+	Revisions []PostRevision `json:"revisions"`
 }
 
+// Version возвращает текст сообщения в версии version: 0 - исходный текст, len(Revisions) - текущий
+func (a *PostHistory) Version(version int32) (string, bool) {
+	switch {
+	case version < 0 || int(version) > len(a.Revisions):
+		return "", false
+	case int(version) == len(a.Revisions):
+		return a.Post.Message, true
+	default:
+		return a.Revisions[version].Message, true
+	}
+}
+
+type PostDiff struct {
+	Post    int64        `json:"post"`
+	From    int32        `json:"from"`
+	To      int32        `json:"to"`
+	Changes []DiffChange `json:"changes"`
+}
+
+type DiffChange struct {
+	Op   string `json:"op"` // equal, insert или delete
+	Text string `json:"text"`
+}
+
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
 const (
 	RelatedUser   = "user"
 	RelatedThread = "thread"
@@ -0,0 +1,16 @@
+package models
+
+type ForumRole struct {
+	Nickname string `json:"nickname"`
+	Role     string `json:"role"` // admin, owner, moderator, member или banned
+}
+
+// роли по возрастанию прав. admin задаётся в конфигурации и действует во всех форумах,
+// owner - создатель форума, остальные роли назначаются в форуме через api
+const (
+	RoleBanned    = "banned"
+	RoleMember    = "member"
+	RoleModerator = "moderator"
+	RoleOwner     = "owner"
+	RoleAdmin     = "admin"
+)
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+type SearchRequest struct {
+	Query  string     `json:"q"`
+	Type   string     `json:"type,omitempty"`   // post, thread или пусто - искать везде
+	Forum  string     `json:"forum,omitempty"`  // slug форума
+	Thread string     `json:"thread,omitempty"` // slug или id ветки, ищем только её сообщения
+	Author string     `json:"author,omitempty"`
+	Since  *time.Time `json:"since,omitempty"`
+	Until  *time.Time `json:"until,omitempty"`
+	Sort   string     `json:"sort,omitempty"` // relevance или date
+	Limit  int32      `json:"limit,omitempty"`
+	Cursor *SearchCursor
+}
+
+// SearchCursor - ключ последней выданной записи, следующая страница начинается строго после него
+type SearchCursor struct {
+	Rank    float32   `json:"r"`
+	Created time.Time `json:"c"`
+	Id      int64     `json:"i"`
+	Type    string    `json:"t"`
+}
+
+type SearchHit struct {
+	Type    string    `json:"type"` // post или thread
+	Id      int64     `json:"id"`
+	Thread  int32     `json:"thread"`
+	Forum   string    `json:"forum"`
+	Author  string    `json:"author"`
+	Title   string    `json:"title,omitempty"` // только для веток
+	Snippet string    `json:"snippet"`         // фрагмент текста, найденные слова обёрнуты в <b></b>
+	Rank    float32   `json:"rank"`
+	Created time.Time `json:"created"`
+}
+
+type SearchResult struct {
+	Hits []SearchHit   `json:"hits"`
+	Next *SearchCursor `json:"-"`
+}
+
+const (
+	SearchTypePost   = "post"
+	SearchTypeThread = "thread"
+
+	SearchSortRelevance = "relevance"
+	SearchSortDate      = "date"
+)
@@ -11,6 +11,10 @@ type Thread struct {
 	Votes   int32     `json:"votes"`          // кол-во голосов за данное сообщение
 	Slug    string    `json:"slug,omitempty"` // в данной структуре может быть а может и не быть
 	Created time.Time `json:"created"`        // время создания ветки
+	State   string    `json:"state"`          // open, closed или locked
+
+	Pinned       int32 `json:"pinned,omitempty"`       // приоритет закрепления, 0 - ветка не закреплена
+	Announcement bool  `json:"announcement,omitempty"` // объявление показывается в списках веток всех форумов
 }
 
 type ThreadCreate struct {
@@ -27,15 +31,45 @@ type ThreadUpdate struct {
 	Message string `json:"message,omitempty"`
 }
 
+type ThreadStateUpdate struct {
+	State string `json:"state"`
+}
+
+type ThreadPin struct {
+	Pinned       int32 `json:"pinned"`
+	Announcement bool  `json:"announcement"`
+}
+
+type ThreadMove struct {
+	Forum string `json:"forum"` // slug форума, в который переносится ветка
+}
+
+type ThreadMerge struct {
+	Into string `json:"into"` // slug или id ветки, в которую переносятся сообщения
+}
+
+type ThreadSplit struct {
+	Title string `json:"title"`
+	Slug  string `json:"slug,omitempty"`
+}
+
 type ThreadPostRequest struct {
-	Limit int32  `json:"limit,omitempty"`
-	Since int64  `json:"since"`
-	Sort  string `json:"sort"` // flat, tree или parent_tree
-	Desc  bool   `json:"desc"` // флаг сортировки по убыванию
+	Limit int32    `json:"limit,omitempty"`
+	Since int64    `json:"since"`
+	Sort  string   `json:"sort"` // flat, tree, parent_tree или score
+	Desc  bool     `json:"desc"` // флаг сортировки по убыванию
+	After *PageKey `json:"-"`    // ключ последнего выданного поста, если задан - since не учитывается
 }
 
 const (
 	Flat       = "flat"
 	Tree       = "tree"
 	ParentTree = "parent_tree"
+	Score      = "score" // дерево, в котором ответы одного уровня идут по рейтингу, по убыванию - при desc, как и в остальных сортировках
+)
+
+const (
+	ThreadOpen   = "open"   // можно писать сообщения и голосовать
+	ThreadClosed = "closed" // новые сообщения запрещены, голосовать можно
+	ThreadLocked = "locked" // запрещены и новые сообщения, и голоса
 )
@@ -5,6 +5,9 @@ type User struct {
 	Fullname string `json:"fullname"`
 	About    string `json:"about"`
 	Email    string `json:"email"`
+
+	Password     string `json:"password,omitempty"` // только при регистрации, в ответах не возвращается. Необязателен
+	PasswordHash []byte `json:"-"`                  // bcrypt-хэш пароля, пустой - пароль не задан и войти нельзя
 }
 
 type UserUpdate struct {
@@ -8,5 +8,18 @@ type Vote struct {
 
 type VoteCreate struct {
 	Nickname string `json:"nickname" db:"nickname"` // автор голоса
-	Voice    int32  `json:"voice" db:"voice"`       // -1 или 1, голос
+	Voice    *int32 `json:"voice" db:"voice"`       // -1 или 1, голос. 0 отзывает ранее отданный голос. Обязателен, nil - поле не передано
+}
+
+type GetThreadVotes struct {
+	Limit int32  `json:"limit"` // default 100
+	Since string `json:"since"` // nickname, после которого выводить голоса
+	Desc  bool   `json:"desc"`
+	Voice int32  `json:"voice"` // -1 или 1 - только такие голоса, 0 - все
+}
+
+type GetUserVotes struct {
+	Limit int32 `json:"limit"` // default 100
+	Since int32 `json:"since"` // id ветки, после которой выводить голоса, 0 - с начала
+	Desc  bool  `json:"desc"`
 }
@@ -11,17 +11,32 @@ type UserRepo interface {
 	Get(ctx context.Context, nicknameOrEmail string) (*models.User, error)
 }
 
+type AuthRepo interface {
+	GetPasswordHash(ctx context.Context, nickname string) (string, []byte, error) // ник в исходном регистре и хэш пароля
+	CreateSession(ctx context.Context, session *models.Session) error
+	GetSession(ctx context.Context, tokenHash []byte) (*models.Session, error) // только не истёкшие сессии
+	DeleteSession(ctx context.Context, tokenHash []byte) error
+	// SetPasswordHash меняет пароль и завершает все сессии и сбросы пароля пользователя
+	SetPasswordHash(ctx context.Context, nickname string, hash []byte) error
+	CreatePasswordReset(ctx context.Context, reset *models.PasswordReset) error // заменяет прежний сброс пользователя
+	// UsePasswordReset удаляет сброс, только если он выдан пользователю nickname, и возвращает его, только если он не истёк
+	UsePasswordReset(ctx context.Context, tokenHash []byte, nickname string) (*models.PasswordReset, error)
+}
+
 type ForumRepo interface {
 	Create(ctx context.Context, forum *models.ForumCreate) (*models.Forum, error)
 	Get(ctx context.Context, slug string) (*models.Forum, error)
 	GetUsers(ctx context.Context, getSettings *models.GetForumUsers) (*[]models.User, error)                    // получение пользователей форума
 	GetThreads(ctx context.Context, slug string, getSettings *models.GetForumThreads) (*[]models.Thread, error) // получение веток обсуждения форума
+	CountThreads(ctx context.Context, slug string) (int64, error)                                               // сколько веток в списке форума, с объявлениями других форумов
 }
 
 type PostRepo interface {
 	Get(ctx context.Context, id int64, getSettings *models.PostGetRequest) (*models.PostGetResult, error)
 	Update(ctx context.Context, id int64, updateDate *models.PostUpdate) (*models.Post, error)
 	Create(ctx context.Context, threadSlugOrId string, posts *[]models.PostCreate) (*[]models.Post, error) // создание постов для ветки. created у post'ов должен быть одинаковый
+	Delete(ctx context.Context, id int64, deleteSettings *models.PostDeleteRequest) (*models.PostDeleteResult, error)
+	GetHistory(ctx context.Context, id int64) (*models.PostHistory, error) // пост и все его правки по порядку
 }
 
 type ThreadRepo interface {
@@ -29,13 +44,46 @@ type ThreadRepo interface {
 	Get(ctx context.Context, threadSlugOrId string) (*models.Thread, error)
 	Update(ctx context.Context, threadSlugOrId string, updateData *models.ThreadUpdate) (*models.Thread, error)
 	GetPosts(ctx context.Context, slugOrId string, getSettings *models.ThreadPostRequest) (*[]models.Post, error) // все сообщения данной ветки
+	SetState(ctx context.Context, threadSlugOrId string, stateUpdate *models.ThreadStateUpdate) (*models.Thread, error)
+	SetPin(ctx context.Context, threadSlugOrId string, pin *models.ThreadPin) (*models.Thread, error)    // закрепление ветки и объявления
+	Move(ctx context.Context, threadSlugOrId string, move *models.ThreadMove) (*models.Thread, error)    // перенос ветки в другой форум
+	Merge(ctx context.Context, threadSlugOrId string, merge *models.ThreadMerge) (*models.Thread, error) // слияние ветки с другой, вернёт ветку, в которую слили
+	Split(ctx context.Context, postId int64, split *models.ThreadSplit) (*models.Thread, error)          // вынос поддерева поста в новую ветку
+}
+
+type RoleRepo interface {
+	Get(ctx context.Context, forumSlug string, nickname string) (string, error) // роль пользователя в форуме без учёта администраторов
+	GetAll(ctx context.Context, forumSlug string) (*[]models.ForumRole, error)  // владелец и все назначенные роли
+	Set(ctx context.Context, forumSlug string, role *models.ForumRole) (*models.ForumRole, error)
 }
 
 type VoteRepo interface {
-	Create(ctx context.Context, threadSlugOrId string, vote *models.VoteCreate) (*models.Thread, error) // пользователь должен учитываться только один раз
+	Create(ctx context.Context, threadSlugOrId string, vote *models.VoteCreate) (*models.Thread, error)                 // пользователь должен учитываться только один раз
+	CreateForPost(ctx context.Context, postId int64, vote *models.VoteCreate) (*models.Post, error)                     // голос за пост, повторный голос заменяет предыдущий
+	GetByThread(ctx context.Context, threadSlugOrId string, getSettings *models.GetThreadVotes) (*[]models.Vote, error) // проголосовавшие в ветке по нику
+	GetByUser(ctx context.Context, nickname string, getSettings *models.GetUserVotes) (*[]models.Vote, error)           // голоса пользователя по id ветки
+}
+
+type NotificationRepo interface {
+	SubscribeThread(ctx context.Context, nickname string, threadSlugOrId string) (*models.Subscription, error) // повторная подписка ничего не меняет
+	UnsubscribeThread(ctx context.Context, nickname string, threadSlugOrId string) error
+	SubscribeForum(ctx context.Context, nickname string, forumSlug string) (*models.Subscription, error)
+	UnsubscribeForum(ctx context.Context, nickname string, forumSlug string) error
+	GetSubscriptions(ctx context.Context, nickname string) (*[]models.Subscription, error)                                       // сначала форумы по slug, затем ветки по id
+	GetNotifications(ctx context.Context, nickname string, getSettings *models.GetNotifications) (*[]models.Notification, error) // уведомления пользователя по id
+	MarkRead(ctx context.Context, nickname string, read *models.NotificationsRead) (*models.NotificationsCount, error)           // вернёт, сколько непрочитанных осталось
+	CountUnread(ctx context.Context, nickname string) (*models.NotificationsCount, error)
+}
+
+type MentionRepo interface {
+	GetByUser(ctx context.Context, nickname string, getSettings *models.GetMentions) (*[]models.Mention, error) // упоминания пользователя по id
 }
 
 type ServiceRepo interface {
 	GetInfo(ctx context.Context) (*models.Service, error)
 	Clear(ctx context.Context) error
 }
+
+type SearchRepo interface {
+	Search(ctx context.Context, request *models.SearchRequest) (*models.SearchResult, error) // полнотекстовый поиск по сообщениям и веткам
+}
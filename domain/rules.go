@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"regexp"
+	"strings"
+	"technopark-db-semester-project/domain/models"
+)
+
+// правила, которые одинаково соблюдают все хранилища
+
+// MaxMentions - сколько разных ников из одного текста учитывается, остальные упоминания игнорируются
+const MaxMentions = 50
+
+// @nickname не должен идти сразу после буквы или цифры, иначе email считался бы упоминанием
+var mentionPattern = regexp.MustCompile(`(?:^|[^A-Za-z0-9_.@])@([A-Za-z0-9_.]+)`)
+
+// CheckWritable возвращает ошибку, если в ветке в состоянии state нельзя писать сообщения (votes = false) или голосовать (votes = true)
+func CheckWritable(state string, votes bool) error {
+	switch {
+	case state == models.ThreadLocked:
+		return ErrorThreadLocked
+	case state == models.ThreadClosed && !votes:
+		return ErrorThreadClosed
+	}
+
+	return nil
+}
+
+// ParseMentions возвращает ники из @nickname в message по порядку появления, без повторов с точностью до регистра.
+// Точки в конце ника отбрасываются, чтобы "@alice." в конце предложения упоминало alice
+func ParseMentions(message string) []string {
+	nicknames := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, match := range mentionPattern.FindAllStringSubmatch(message, -1) {
+		nickname := strings.TrimRight(match[1], ".")
+		if _, ok := seen[strings.ToLower(nickname)]; ok || nickname == "" {
+			continue
+		}
+		if len(nicknames) == MaxMentions {
+			break
+		}
+		seen[strings.ToLower(nickname)] = struct{}{}
+		nicknames = append(nicknames, nickname)
+	}
+
+	return nicknames
+}
@@ -0,0 +1,111 @@
+package logger
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	LevelDebug int32 = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[string]int32{
+	"debug": LevelDebug,
+	"info":  LevelInfo,
+	"warn":  LevelWarn,
+	"error": LevelError,
+}
+
+var levelLabels = map[int32]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+var level = LevelInfo
+
+var (
+	outputMu sync.Mutex
+	output   io.Writer = os.Stderr
+)
+
+// Fields - поля структурированной записи лога
+type Fields map[string]any
+
+// SetLevel выставляет минимальный уровень логирования, неизвестные уровни игнорируются
+func SetLevel(name string) {
+	if value, ok := levelNames[name]; ok {
+		atomic.StoreInt32(&level, value)
+	}
+}
+
+func Enabled(lvl int32) bool {
+	return lvl >= atomic.LoadInt32(&level)
+}
+
+func Debug(v ...any) {
+	if Enabled(LevelDebug) {
+		log.Println(append([]any{"DEBUG"}, v...)...)
+	}
+}
+
+func Info(v ...any) {
+	if Enabled(LevelInfo) {
+		log.Println(append([]any{"INFO"}, v...)...)
+	}
+}
+
+func Warn(v ...any) {
+	if Enabled(LevelWarn) {
+		log.Println(append([]any{"WARN"}, v...)...)
+	}
+}
+
+func Error(v ...any) {
+	if Enabled(LevelError) {
+		log.Println(append([]any{"ERROR"}, v...)...)
+	}
+}
+
+// SetOutput задаёт, куда пишутся структурированные записи, по умолчанию stderr
+func SetOutput(w io.Writer) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+
+	output = w
+}
+
+// Log пишет одну строку json с полями time, level и msg и переданными fields
+func Log(lvl int32, msg string, fields Fields) {
+	if !Enabled(lvl) {
+		return
+	}
+
+	entry := make(Fields, len(fields)+3)
+	for name, value := range fields {
+		entry[name] = value
+	}
+	entry["time"] = time.Now().Format(time.RFC3339Nano)
+	entry["level"] = levelLabels[lvl]
+	entry["msg"] = msg
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		Error("marshal log entry:", err)
+		return
+	}
+
+	outputMu.Lock()
+	defer outputMu.Unlock()
+
+	_, _ = output.Write(append(line, '\n'))
+}
@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"github.com/fasthttp/router"
+	"github.com/valyala/fasthttp"
+	"strconv"
+	"time"
+)
+
+// UnmatchedRoute - значение метки route для запросов, не попавших ни в один маршрут, чтобы не плодить метки по путям
+const UnmatchedRoute = "unmatched"
+
+type HTTPMetrics struct {
+	requests *CounterVec
+	duration *HistogramVec
+}
+
+func NewHTTPMetrics(registry *Registry) *HTTPMetrics {
+	return &HTTPMetrics{
+		requests: registry.NewCounterVec("http_requests_total", "Number of handled HTTP requests.", "method", "route", "status"),
+		duration: registry.NewHistogramVec("http_request_duration_seconds", "HTTP request latency.", DefaultBuckets, "method", "route"),
+	}
+}
+
+// Route возвращает шаблон маршрута (например /api/thread/{slug_or_id}/posts), для этого у роутера
+// должен быть включён SaveMatchedRoutePath
+func Route(ctx *fasthttp.RequestCtx) string {
+	if route, ok := ctx.UserValue(router.MatchedRoutePathParam).(string); ok {
+		return route
+	}
+
+	return UnmatchedRoute
+}
+
+func (a *HTTPMetrics) Middleware(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		start := time.Now()
+		next(ctx)
+
+		method := string(ctx.Method())
+		route := Route(ctx)
+		a.requests.Inc(method, route, strconv.Itoa(ctx.Response.StatusCode()))
+		a.duration.Observe(time.Since(start).Seconds(), method, route)
+	}
+}
+
+// Handler отдаёт все метрики реестра, GET /metrics
+func Handler(registry *Registry) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		ctx.SetContentType("text/plain; version=0.0.4; charset=utf-8")
+		_, _ = registry.WriteTo(ctx)
+		ctx.SetStatusCode(fasthttp.StatusOK)
+	}
+}
@@ -0,0 +1,256 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Минимальная реализация текстового формата Prometheus (version 0.0.4): счётчики и гистограммы с метками
+// и значения, вычисляемые в момент запроса /metrics
+
+var DefaultBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (a *Registry) register(m metric) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.metrics = append(a.metrics, m)
+}
+
+// WriteTo пишет все метрики в порядке регистрации
+func (a *Registry) WriteTo(w io.Writer) (int64, error) {
+	a.mu.Lock()
+	metrics := append([]metric(nil), a.metrics...)
+	a.mu.Unlock()
+
+	counting := &countingWriter{w: w}
+	buffered := bufio.NewWriter(counting)
+	for _, m := range metrics {
+		m.write(buffered)
+	}
+	err := buffered.Flush()
+
+	return counting.n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (a *countingWriter) Write(p []byte) (int, error) {
+	n, err := a.w.Write(p)
+	a.n += int64(n)
+	return n, err
+}
+
+func writeHeader(w *bufio.Writer, name string, help string, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(names []string, values []string, extra ...string) string {
+	if len(names) == 0 && len(extra) == 0 {
+		return ""
+	}
+
+	parts := make([]string, 0, len(names)+len(extra)/2)
+	for ind, name := range names {
+		parts = append(parts, name+`="`+labelValueReplacer.Replace(values[ind])+`"`)
+	}
+	for ind := 0; ind+1 < len(extra); ind += 2 {
+		parts = append(parts, extra[ind]+`="`+labelValueReplacer.Replace(extra[ind+1])+`"`)
+	}
+
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+// labelKey склеивает значения меток в ключ map, \xff не встречается в валидном utf-8
+func labelKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+type CounterVec struct {
+	name       string
+	help       string
+	labelNames []string
+
+	mu     sync.Mutex
+	values map[string]*counterValue
+}
+
+type counterValue struct {
+	labels []string
+	value  float64
+}
+
+func (a *Registry) NewCounterVec(name string, help string, labelNames ...string) *CounterVec {
+	counter := &CounterVec{name: name, help: help, labelNames: labelNames, values: make(map[string]*counterValue)}
+	a.register(counter)
+
+	return counter
+}
+
+func (a *CounterVec) Add(value float64, labels ...string) {
+	if len(labels) != len(a.labelNames) {
+		panic("metrics: wrong number of labels for " + a.name)
+	}
+
+	lk := labelKey(labels)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entry, ok := a.values[lk]
+	if !ok {
+		entry = &counterValue{labels: append([]string(nil), labels...)}
+		a.values[lk] = entry
+	}
+	entry.value += value
+}
+
+func (a *CounterVec) Inc(labels ...string) {
+	a.Add(1, labels...)
+}
+
+func (a *CounterVec) write(w *bufio.Writer) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	writeHeader(w, a.name, a.help, "counter")
+	for _, lk := range sortedKeys(a.values) {
+		entry := a.values[lk]
+		fmt.Fprintf(w, "%s%s %s\n", a.name, formatLabels(a.labelNames, entry.labels), formatFloat(entry.value))
+	}
+}
+
+type HistogramVec struct {
+	name       string
+	help       string
+	labelNames []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	values map[string]*histogramValue
+}
+
+type histogramValue struct {
+	labels []string
+	counts []uint64 // не накопительные, по одной на бакет
+	count  uint64
+	sum    float64
+}
+
+func (a *Registry) NewHistogramVec(name string, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+
+	histogram := &HistogramVec{name: name, help: help, labelNames: labelNames, buckets: sorted, values: make(map[string]*histogramValue)}
+	a.register(histogram)
+
+	return histogram
+}
+
+func (a *HistogramVec) Observe(value float64, labels ...string) {
+	if len(labels) != len(a.labelNames) {
+		panic("metrics: wrong number of labels for " + a.name)
+	}
+
+	lk := labelKey(labels)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entry, ok := a.values[lk]
+	if !ok {
+		entry = &histogramValue{labels: append([]string(nil), labels...), counts: make([]uint64, len(a.buckets))}
+		a.values[lk] = entry
+	}
+
+	ind := sort.SearchFloat64s(a.buckets, value)
+	if ind < len(a.buckets) {
+		entry.counts[ind]++
+	}
+	entry.count++
+	entry.sum += value
+}
+
+func (a *HistogramVec) write(w *bufio.Writer) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	writeHeader(w, a.name, a.help, "histogram")
+	for _, lk := range sortedKeys(a.values) {
+		entry := a.values[lk]
+		var cumulative uint64
+		for ind, bound := range a.buckets {
+			cumulative += entry.counts[ind]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", a.name, formatLabels(a.labelNames, entry.labels, "le", formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", a.name, formatLabels(a.labelNames, entry.labels, "le", "+Inf"), entry.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", a.name, formatLabels(a.labelNames, entry.labels), formatFloat(entry.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", a.name, formatLabels(a.labelNames, entry.labels), entry.count)
+	}
+}
+
+// valueFunc - метрика без меток, значение которой вычисляется при каждом чтении
+type valueFunc struct {
+	name string
+	help string
+	kind string
+	f    func() float64
+}
+
+func (a *Registry) NewGaugeFunc(name string, help string, f func() float64) {
+	a.register(&valueFunc{name: name, help: help, kind: "gauge", f: f})
+}
+
+func (a *Registry) NewCounterFunc(name string, help string, f func() float64) {
+	a.register(&valueFunc{name: name, help: help, kind: "counter", f: f})
+}
+
+func (a *valueFunc) write(w *bufio.Writer) {
+	writeHeader(w, a.name, a.help, a.kind)
+	fmt.Fprintf(w, "%s %s\n", a.name, formatFloat(a.f()))
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for lk := range values {
+		keys = append(keys, lk)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
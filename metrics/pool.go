@@ -0,0 +1,31 @@
+package metrics
+
+import "github.com/jackc/pgx/v5/pgxpool"
+
+// RegisterPoolStats добавляет в реестр статистику пула соединений, она читается при каждом запросе /metrics
+func RegisterPoolStats(registry *Registry, db *pgxpool.Pool) {
+	registry.NewGaugeFunc("pgxpool_acquired_conns", "Connections currently acquired from the pool.", func() float64 {
+		return float64(db.Stat().AcquiredConns())
+	})
+	registry.NewGaugeFunc("pgxpool_idle_conns", "Idle connections in the pool.", func() float64 {
+		return float64(db.Stat().IdleConns())
+	})
+	registry.NewGaugeFunc("pgxpool_total_conns", "Total connections in the pool.", func() float64 {
+		return float64(db.Stat().TotalConns())
+	})
+	registry.NewGaugeFunc("pgxpool_max_conns", "Maximum size of the pool.", func() float64 {
+		return float64(db.Stat().MaxConns())
+	})
+	registry.NewCounterFunc("pgxpool_acquire_total", "Successful connection acquires.", func() float64 {
+		return float64(db.Stat().AcquireCount())
+	})
+	registry.NewCounterFunc("pgxpool_empty_acquire_total", "Acquires that had to wait because the pool was empty.", func() float64 {
+		return float64(db.Stat().EmptyAcquireCount())
+	})
+	registry.NewCounterFunc("pgxpool_canceled_acquire_total", "Acquires canceled by context.", func() float64 {
+		return float64(db.Stat().CanceledAcquireCount())
+	})
+	registry.NewCounterFunc("pgxpool_acquire_duration_seconds_total", "Total time spent waiting for connection acquires.", func() float64 {
+		return db.Stat().AcquireDuration().Seconds()
+	})
+}
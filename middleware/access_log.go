@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"github.com/valyala/fasthttp"
+	"math/rand"
+	"regexp"
+	"technopark-db-semester-project/logger"
+	"technopark-db-semester-project/metrics"
+	"time"
+)
+
+var routeParamPattern = regexp.MustCompile(`\{(\w+)}`)
+
+// routeParams достаёт значения параметров пути по шаблону маршрута, например slug_or_id для /api/thread/{slug_or_id}/posts
+func routeParams(ctx *fasthttp.RequestCtx, route string) map[string]string {
+	matches := routeParamPattern.FindAllStringSubmatch(route, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	params := make(map[string]string, len(matches))
+	for _, match := range matches {
+		if value, ok := ctx.UserValue(match[1]).(string); ok {
+			params[match[1]] = value
+		}
+	}
+
+	return params
+}
+
+// AccessLog пишет json-запись о каждом запросе. Ответы 5xx пишутся с уровнем error, 4xx - warn, остальные - info.
+// Успешные запросы попадают в лог с вероятностью sample, ошибки - всегда
+func AccessLog(sample float64) Middleware {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			start := time.Now()
+			next(ctx)
+			latency := time.Since(start)
+
+			status := ctx.Response.StatusCode()
+			lvl := logger.LevelInfo
+			switch {
+			case status >= fasthttp.StatusInternalServerError:
+				lvl = logger.LevelError
+			case status >= fasthttp.StatusBadRequest:
+				lvl = logger.LevelWarn
+			}
+
+			if !logger.Enabled(lvl) {
+				return
+			}
+			if lvl == logger.LevelInfo && sample < 1 && rand.Float64() >= sample {
+				return
+			}
+
+			route := metrics.Route(ctx)
+			logger.Log(lvl, "request", logger.Fields{
+				"request_id":    GetRequestId(ctx),
+				"method":        string(ctx.Method()),
+				"route":         route,
+				"path":          string(ctx.Path()),
+				"params":        routeParams(ctx, route),
+				"status":        status,
+				"latency_ms":    float64(latency.Microseconds()) / 1000,
+				"response_size": len(ctx.Response.Body()),
+			})
+		}
+	}
+}
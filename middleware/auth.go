@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"github.com/valyala/fasthttp"
+	"strings"
+	"technopark-db-semester-project/domain"
+)
+
+const (
+	AuthHeader   = "Authorization"
+	BearerPrefix = "Bearer "
+	UserKey      = "user"  // ключ user value с ником аутентифицированного пользователя
+	TokenKey     = "token" // ключ user value с проверенным токеном
+)
+
+// TokenResolver возвращает ник владельца токена, для недействительного токена - domain.ErrorSessionDoesNotExist
+type TokenResolver func(ctx context.Context, token string) (string, error)
+
+// ErrorWriter пишет ответ с ошибкой. Middleware получают его из delivery, чтобы ошибки отдавались тем же
+// общим обработчиком, что и в handlers: с кодом по категории и без внутренних подробностей
+type ErrorWriter func(ctx *fasthttp.RequestCtx, err error)
+
+// Auth проверяет токен из заголовка Authorization: Bearer <token> и сохраняет ник его владельца.
+// Запрос без токена проходит как анонимный, с недействительным токеном - отклоняется с 401
+func Auth(resolve TokenResolver, writeError ErrorWriter) Middleware {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			header := string(ctx.Request.Header.Peek(AuthHeader))
+			if header == "" {
+				next(ctx)
+				return
+			}
+
+			token := strings.TrimSpace(strings.TrimPrefix(header, BearerPrefix))
+			if !strings.HasPrefix(header, BearerPrefix) || token == "" {
+				writeError(ctx, domain.ErrorSessionDoesNotExist)
+				return
+			}
+			nickname, err := resolve(ctx.UserValue("ctx").(context.Context), token)
+			if err != nil {
+				writeError(ctx, err)
+				return
+			}
+
+			ctx.SetUserValue(UserKey, nickname)
+			ctx.SetUserValue(TokenKey, token)
+			next(ctx)
+		}
+	}
+}
+
+// GetUser возвращает ник аутентифицированного пользователя или пустую строку для анонимного запроса
+func GetUser(ctx *fasthttp.RequestCtx) string {
+	nickname, _ := ctx.UserValue(UserKey).(string)
+	return nickname
+}
+
+// GetToken возвращает проверенный токен запроса или пустую строку для анонимного запроса
+func GetToken(ctx *fasthttp.RequestCtx) string {
+	token, _ := ctx.UserValue(TokenKey).(string)
+	return token
+}
@@ -0,0 +1,14 @@
+package middleware
+
+import "github.com/valyala/fasthttp"
+
+type Middleware func(next fasthttp.RequestHandler) fasthttp.RequestHandler
+
+// Chain оборачивает handler в middlewares, первый из них выполняется первым
+func Chain(handler fasthttp.RequestHandler, middlewares ...Middleware) fasthttp.RequestHandler {
+	for ind := len(middlewares) - 1; ind >= 0; ind-- {
+		handler = middlewares[ind](handler)
+	}
+
+	return handler
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	RequestIdHeader = "X-Request-Id"
+	RequestIdKey    = "request_id" // ключ user value с id запроса
+)
+
+func newRequestId() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+
+	return hex.EncodeToString(buf)
+}
+
+// RequestId берёт id запроса из X-Request-Id или генерирует новый и возвращает его в ответе
+func RequestId(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		requestId := string(ctx.Request.Header.Peek(RequestIdHeader))
+		if requestId == "" || len(requestId) > 128 {
+			requestId = newRequestId()
+		}
+
+		ctx.SetUserValue(RequestIdKey, requestId)
+		ctx.Response.Header.Set(RequestIdHeader, requestId)
+
+		next(ctx)
+	}
+}
+
+func GetRequestId(ctx *fasthttp.RequestCtx) string {
+	requestId, _ := ctx.UserValue(RequestIdKey).(string)
+	return requestId
+}
@@ -0,0 +1,73 @@
+package cached
+
+import (
+	"strconv"
+	"strings"
+	"technopark-db-semester-project/domain/models"
+	"technopark-db-semester-project/metrics"
+	"time"
+)
+
+// Stats - попадания, промахи и вытеснения кэша по сущностям
+type Stats struct {
+	requests  *metrics.CounterVec
+	evictions *metrics.CounterVec
+}
+
+func NewStats(registry *metrics.Registry) *Stats {
+	return &Stats{
+		requests:  registry.NewCounterVec("repository_cache_requests_total", "Repository cache lookups.", "entity", "result"),
+		evictions: registry.NewCounterVec("repository_cache_evictions_total", "Entries evicted from repository cache because it was full.", "entity"),
+	}
+}
+
+func (a *Stats) hit(entity string) {
+	a.requests.Inc(entity, "hit")
+}
+
+func (a *Stats) miss(entity string) {
+	a.requests.Inc(entity, "miss")
+}
+
+func (a *Stats) evict(entity string) {
+	a.evictions.Inc(entity)
+}
+
+// Caches - кэши форумов, веток и пользователей, общие для всех декораторов: запись через один репозиторий
+// (например, новый пост) сбрасывает данные, которые отдаёт другой (счётчик постов форума).
+// Ключи в нижнем регистре, потому что slug и ник в базе citext
+type Caches struct {
+	forums  *lru[models.Forum]
+	threads *lru[models.Thread]
+	users   *lru[models.User]
+}
+
+// NewCaches создаёт кэши, в каждом из которых не больше size записей, живущих не дольше ttl
+func NewCaches(size int, ttl time.Duration, stats *Stats) *Caches {
+	return &Caches{
+		forums:  newLRU[models.Forum]("forum", size, ttl, stats),
+		threads: newLRU[models.Thread]("thread", size, ttl, stats),
+		users:   newLRU[models.User]("user", size, ttl, stats),
+	}
+}
+
+func cacheKey(value string) string {
+	return strings.ToLower(value)
+}
+
+// threadKeys - ветку ищут и по id, и по slug, поэтому она лежит под обоими ключами
+func threadKeys(thread *models.Thread) []string {
+	keys := []string{strconv.Itoa(int(thread.Id))}
+	if thread.Slug != "" {
+		keys = append(keys, cacheKey(thread.Slug))
+	}
+
+	return keys
+}
+
+// Purge сбрасывает все кэши, например после очистки базы
+func (a *Caches) Purge() {
+	a.forums.purge()
+	a.threads.purge()
+	a.users.purge()
+}
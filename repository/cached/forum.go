@@ -0,0 +1,52 @@
+package cached
+
+import (
+	"context"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+)
+
+type ForumCachedRepo struct {
+	repo   domain.ForumRepo
+	caches *Caches
+}
+
+func NewForumCachedRepo(repo domain.ForumRepo, caches *Caches) domain.ForumRepo {
+	return &ForumCachedRepo{repo: repo, caches: caches}
+}
+
+func (a *ForumCachedRepo) Create(ctx context.Context, forum *models.ForumCreate) (*models.Forum, error) {
+	created, err := a.repo.Create(ctx, forum)
+	if err == nil {
+		a.caches.forums.set(*created, cacheKey(created.Slug))
+	}
+
+	return created, err
+}
+
+func (a *ForumCachedRepo) Get(ctx context.Context, slug string) (*models.Forum, error) {
+	cachedForum, version, ok := a.caches.forums.get(cacheKey(slug))
+	if ok {
+		return &cachedForum, nil
+	}
+
+	forum, err := a.repo.Get(ctx, slug)
+	if err == nil {
+		a.caches.forums.fill(version, *forum, cacheKey(forum.Slug))
+	}
+
+	return forum, err
+}
+
+func (a *ForumCachedRepo) GetUsers(ctx context.Context, getSettings *models.GetForumUsers) (*[]models.User, error) {
+	return a.repo.GetUsers(ctx, getSettings)
+}
+
+func (a *ForumCachedRepo) GetThreads(ctx context.Context, slug string, getSettings *models.GetForumThreads) (*[]models.Thread, error) {
+	return a.repo.GetThreads(ctx, slug, getSettings)
+}
+
+// CountThreads не кэшируется: total должен совпадать со списком, который тоже читается мимо кэша
+func (a *ForumCachedRepo) CountThreads(ctx context.Context, slug string) (int64, error) {
+	return a.repo.CountThreads(ctx, slug)
+}
@@ -0,0 +1,116 @@
+package cached
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// lru - кэш ограниченного размера: при переполнении вытесняется запись, которую дольше всех не читали,
+// запись старше ttl считается отсутствующей. Одно значение может лежать под несколькими ключами (ветка по id и по slug)
+type lru[V any] struct {
+	entity string
+	size   int
+	ttl    time.Duration
+	stats  *Stats
+
+	mu      sync.Mutex
+	order   *list.List // от недавно использованных к давно использованным
+	entries map[string]*list.Element
+	version uint64 // растёт при каждом изменении через set, remove и purge
+}
+
+type lruEntry[V any] struct {
+	key     string
+	value   V
+	expires time.Time
+}
+
+func newLRU[V any](entity string, size int, ttl time.Duration, stats *Stats) *lru[V] {
+	return &lru[V]{entity: entity, size: size, ttl: ttl, stats: stats, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// get возвращает значение и версию кэша на момент чтения, версию нужно передать в fill после похода в базу
+func (a *lru[V]) get(key string) (V, uint64, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if elem, ok := a.entries[key]; ok {
+		entry := elem.Value.(*lruEntry[V])
+		if time.Now().Before(entry.expires) {
+			a.order.MoveToFront(elem)
+			a.stats.hit(a.entity)
+			return entry.value, a.version, true
+		}
+		a.removeElement(elem)
+	}
+	a.stats.miss(a.entity)
+
+	var empty V
+	return empty, a.version, false
+}
+
+// fill кладёт прочитанное из базы значение, только если с момента get кэш не менялся. Иначе за время запроса
+// значение могли изменить, и в кэш попала бы устаревшая запись
+func (a *lru[V]) fill(version uint64, value V, keys ...string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if version != a.version {
+		return
+	}
+	a.put(value, keys)
+}
+
+// set кладёт значение, полученное из пишущего метода, оно заведомо свежее любых читающих запросов
+func (a *lru[V]) set(value V, keys ...string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.version++
+	a.put(value, keys)
+}
+
+func (a *lru[V]) remove(keys ...string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.version++
+	for _, key := range keys {
+		if elem, ok := a.entries[key]; ok {
+			a.removeElement(elem)
+		}
+	}
+}
+
+func (a *lru[V]) purge() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.version++
+	a.order.Init()
+	a.entries = make(map[string]*list.Element)
+}
+
+func (a *lru[V]) put(value V, keys []string) {
+	expires := time.Now().Add(a.ttl)
+	for _, key := range keys {
+		if elem, ok := a.entries[key]; ok {
+			entry := elem.Value.(*lruEntry[V])
+			entry.value, entry.expires = value, expires
+			a.order.MoveToFront(elem)
+			continue
+		}
+
+		a.entries[key] = a.order.PushFront(&lruEntry[V]{key: key, value: value, expires: expires})
+		for a.order.Len() > a.size {
+			a.removeElement(a.order.Back())
+			a.stats.evict(a.entity)
+		}
+	}
+}
+
+func (a *lru[V]) removeElement(elem *list.Element) {
+	a.order.Remove(elem)
+	delete(a.entries, elem.Value.(*lruEntry[V]).key)
+}
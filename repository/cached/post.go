@@ -0,0 +1,54 @@
+package cached
+
+import (
+	"context"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+)
+
+// PostCachedRepo сами посты не кэширует, он только сбрасывает счётчик постов форума
+type PostCachedRepo struct {
+	repo   domain.PostRepo
+	caches *Caches
+}
+
+func NewPostCachedRepo(repo domain.PostRepo, caches *Caches) domain.PostRepo {
+	return &PostCachedRepo{repo: repo, caches: caches}
+}
+
+// Get не кэширует пост, а автора, ветку и форум репозиторий Postgres берёт через Resolver, то есть из этого кэша
+func (a *PostCachedRepo) Get(ctx context.Context, id int64, getSettings *models.PostGetRequest) (*models.PostGetResult, error) {
+	return a.repo.Get(ctx, id, getSettings)
+}
+
+func (a *PostCachedRepo) Update(ctx context.Context, id int64, updateDate *models.PostUpdate) (*models.Post, error) {
+	return a.repo.Update(ctx, id, updateDate)
+}
+
+// Create сбрасывает форум со счётчиком постов. Ветку и авторов запись проверяет в своей транзакции, мимо кэша
+func (a *PostCachedRepo) Create(ctx context.Context, threadSlugOrId string, posts *[]models.PostCreate) (*[]models.Post, error) {
+	created, err := a.repo.Create(ctx, threadSlugOrId, posts)
+	if err == nil && len(*created) > 0 {
+		a.caches.forums.remove(cacheKey((*created)[0].Forum))
+	}
+
+	return created, err
+}
+
+// Delete - при жёстком удалении форум поста в результат не попадает, поэтому сбрасываются все форумы
+func (a *PostCachedRepo) Delete(ctx context.Context, id int64, deleteSettings *models.PostDeleteRequest) (*models.PostDeleteResult, error) {
+	result, err := a.repo.Delete(ctx, id, deleteSettings)
+	if err == nil && result.Deleted > 0 {
+		if result.Post != nil {
+			a.caches.forums.remove(cacheKey(result.Post.Forum))
+		} else {
+			a.caches.forums.purge()
+		}
+	}
+
+	return result, err
+}
+
+func (a *PostCachedRepo) GetHistory(ctx context.Context, id int64) (*models.PostHistory, error) {
+	return a.repo.GetHistory(ctx, id)
+}
@@ -0,0 +1,26 @@
+package cached
+
+import (
+	"context"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+)
+
+type ServiceCachedRepo struct {
+	repo   domain.ServiceRepo
+	caches *Caches
+}
+
+func NewServiceCachedRepo(repo domain.ServiceRepo, caches *Caches) domain.ServiceRepo {
+	return &ServiceCachedRepo{repo: repo, caches: caches}
+}
+
+func (a *ServiceCachedRepo) GetInfo(ctx context.Context) (*models.Service, error) {
+	return a.repo.GetInfo(ctx)
+}
+
+// Clear сбрасывает кэш даже при ошибке: часть таблиц уже могла быть очищена
+func (a *ServiceCachedRepo) Clear(ctx context.Context) error {
+	defer a.caches.Purge()
+	return a.repo.Clear(ctx)
+}
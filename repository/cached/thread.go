@@ -0,0 +1,99 @@
+package cached
+
+import (
+	"context"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+)
+
+type ThreadCachedRepo struct {
+	repo   domain.ThreadRepo
+	caches *Caches
+}
+
+func NewThreadCachedRepo(repo domain.ThreadRepo, caches *Caches) domain.ThreadRepo {
+	return &ThreadCachedRepo{repo: repo, caches: caches}
+}
+
+// Create - новая ветка меняет счётчик веток форума
+func (a *ThreadCachedRepo) Create(ctx context.Context, forumSlug string, thread *models.ThreadCreate) (*models.Thread, error) {
+	created, err := a.repo.Create(ctx, forumSlug, thread)
+	if err == nil {
+		a.caches.forums.remove(cacheKey(created.Forum))
+		a.caches.threads.set(*created, threadKeys(created)...)
+	}
+
+	return created, err
+}
+
+func (a *ThreadCachedRepo) Get(ctx context.Context, threadSlugOrId string) (*models.Thread, error) {
+	cachedThread, version, ok := a.caches.threads.get(cacheKey(threadSlugOrId))
+	if ok {
+		return &cachedThread, nil
+	}
+
+	thread, err := a.repo.Get(ctx, threadSlugOrId)
+	if err == nil {
+		a.caches.threads.fill(version, *thread, threadKeys(thread)...)
+	}
+
+	return thread, err
+}
+
+func (a *ThreadCachedRepo) Update(ctx context.Context, threadSlugOrId string, updateData *models.ThreadUpdate) (*models.Thread, error) {
+	return a.setThread(a.repo.Update(ctx, threadSlugOrId, updateData))
+}
+
+// GetPosts - сами посты не кэшируются, ветку репозиторий Postgres находит через Resolver, то есть через этот кэш
+func (a *ThreadCachedRepo) GetPosts(ctx context.Context, slugOrId string, getSettings *models.ThreadPostRequest) (*[]models.Post, error) {
+	return a.repo.GetPosts(ctx, slugOrId, getSettings)
+}
+
+func (a *ThreadCachedRepo) SetState(ctx context.Context, threadSlugOrId string, stateUpdate *models.ThreadStateUpdate) (*models.Thread, error) {
+	return a.setThread(a.repo.SetState(ctx, threadSlugOrId, stateUpdate))
+}
+
+func (a *ThreadCachedRepo) SetPin(ctx context.Context, threadSlugOrId string, pin *models.ThreadPin) (*models.Thread, error) {
+	return a.setThread(a.repo.SetPin(ctx, threadSlugOrId, pin))
+}
+
+// Move, Merge и Split меняют счётчики сразу двух форумов, а исходный форум из результата не узнать,
+// поэтому кэш форумов сбрасывается целиком. Операции модераторские и редкие
+func (a *ThreadCachedRepo) Move(ctx context.Context, threadSlugOrId string, move *models.ThreadMove) (*models.Thread, error) {
+	thread, err := a.repo.Move(ctx, threadSlugOrId, move)
+	if err == nil {
+		a.caches.forums.purge()
+	}
+
+	return a.setThread(thread, err)
+}
+
+// Merge удаляет исходную ветку, а в кэше она могла лежать под ключом, которого нет в запросе (id вместо slug),
+// так что сбрасываются и ветки
+func (a *ThreadCachedRepo) Merge(ctx context.Context, threadSlugOrId string, merge *models.ThreadMerge) (*models.Thread, error) {
+	thread, err := a.repo.Merge(ctx, threadSlugOrId, merge)
+	if err == nil {
+		a.caches.forums.purge()
+		a.caches.threads.purge()
+	}
+
+	return a.setThread(thread, err)
+}
+
+func (a *ThreadCachedRepo) Split(ctx context.Context, postId int64, split *models.ThreadSplit) (*models.Thread, error) {
+	thread, err := a.repo.Split(ctx, postId, split)
+	if err == nil {
+		a.caches.forums.purge()
+	}
+
+	return a.setThread(thread, err)
+}
+
+// setThread кладёт в кэш ветку, которую вернул пишущий метод
+func (a *ThreadCachedRepo) setThread(thread *models.Thread, err error) (*models.Thread, error) {
+	if err == nil {
+		a.caches.threads.set(*thread, threadKeys(thread)...)
+	}
+
+	return thread, err
+}
@@ -0,0 +1,50 @@
+package cached
+
+import (
+	"context"
+	"strings"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+)
+
+type UserCachedRepo struct {
+	repo   domain.UserRepo
+	caches *Caches
+}
+
+func NewUserCachedRepo(repo domain.UserRepo, caches *Caches) domain.UserRepo {
+	return &UserCachedRepo{repo: repo, caches: caches}
+}
+
+func (a *UserCachedRepo) Create(ctx context.Context, user *models.User) (*[]models.User, error) {
+	return a.repo.Create(ctx, user)
+}
+
+func (a *UserCachedRepo) Update(ctx context.Context, nickname string, updateData *models.UserUpdate) (*models.User, error) {
+	user, err := a.repo.Update(ctx, nickname, updateData)
+	if err == nil {
+		a.caches.users.set(*user, cacheKey(user.Nickname))
+	}
+
+	return user, err
+}
+
+// Get кэширует только поиск по нику: почта может смениться, и старый ключ пришлось бы искать по всему кэшу.
+// В нике @ быть не может, так что по ней запросы различаются однозначно
+func (a *UserCachedRepo) Get(ctx context.Context, nicknameOrEmail string) (*models.User, error) {
+	if strings.Contains(nicknameOrEmail, "@") {
+		return a.repo.Get(ctx, nicknameOrEmail)
+	}
+
+	cachedUser, version, ok := a.caches.users.get(cacheKey(nicknameOrEmail))
+	if ok {
+		return &cachedUser, nil
+	}
+
+	user, err := a.repo.Get(ctx, nicknameOrEmail)
+	if err == nil {
+		a.caches.users.fill(version, *user, cacheKey(user.Nickname))
+	}
+
+	return user, err
+}
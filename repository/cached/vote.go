@@ -0,0 +1,38 @@
+package cached
+
+import (
+	"context"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+)
+
+type VoteCachedRepo struct {
+	repo   domain.VoteRepo
+	caches *Caches
+}
+
+func NewVoteCachedRepo(repo domain.VoteRepo, caches *Caches) domain.VoteRepo {
+	return &VoteCachedRepo{repo: repo, caches: caches}
+}
+
+// Create сбрасывает ветку из кэша, а не кладёт туда возвращённую: параллельный голос мог изменить рейтинг после его чтения
+func (a *VoteCachedRepo) Create(ctx context.Context, threadSlugOrId string, vote *models.VoteCreate) (*models.Thread, error) {
+	thread, err := a.repo.Create(ctx, threadSlugOrId, vote)
+	if err == nil {
+		a.caches.threads.remove(threadKeys(thread)...)
+	}
+
+	return thread, err
+}
+
+func (a *VoteCachedRepo) CreateForPost(ctx context.Context, postId int64, vote *models.VoteCreate) (*models.Post, error) {
+	return a.repo.CreateForPost(ctx, postId, vote)
+}
+
+func (a *VoteCachedRepo) GetByThread(ctx context.Context, threadSlugOrId string, getSettings *models.GetThreadVotes) (*[]models.Vote, error) {
+	return a.repo.GetByThread(ctx, threadSlugOrId, getSettings)
+}
+
+func (a *VoteCachedRepo) GetByUser(ctx context.Context, nickname string, getSettings *models.GetUserVotes) (*[]models.Vote, error) {
+	return a.repo.GetByUser(ctx, nickname, getSettings)
+}
@@ -0,0 +1,52 @@
+package instrumented
+
+import (
+	"context"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+	"time"
+)
+
+type AuthInstrumentedRepo struct {
+	repo    domain.AuthRepo
+	metrics *QueryMetrics
+}
+
+func NewAuthInstrumentedRepo(repo domain.AuthRepo, metrics *QueryMetrics) domain.AuthRepo {
+	return &AuthInstrumentedRepo{repo: repo, metrics: metrics}
+}
+
+func (a *AuthInstrumentedRepo) GetPasswordHash(ctx context.Context, nickname string) (string, []byte, error) {
+	defer a.metrics.observe("auth", "GetPasswordHash", time.Now())
+	return a.repo.GetPasswordHash(ctx, nickname)
+}
+
+func (a *AuthInstrumentedRepo) CreateSession(ctx context.Context, session *models.Session) error {
+	defer a.metrics.observe("auth", "CreateSession", time.Now())
+	return a.repo.CreateSession(ctx, session)
+}
+
+func (a *AuthInstrumentedRepo) GetSession(ctx context.Context, tokenHash []byte) (*models.Session, error) {
+	defer a.metrics.observe("auth", "GetSession", time.Now())
+	return a.repo.GetSession(ctx, tokenHash)
+}
+
+func (a *AuthInstrumentedRepo) DeleteSession(ctx context.Context, tokenHash []byte) error {
+	defer a.metrics.observe("auth", "DeleteSession", time.Now())
+	return a.repo.DeleteSession(ctx, tokenHash)
+}
+
+func (a *AuthInstrumentedRepo) SetPasswordHash(ctx context.Context, nickname string, hash []byte) error {
+	defer a.metrics.observe("auth", "SetPasswordHash", time.Now())
+	return a.repo.SetPasswordHash(ctx, nickname, hash)
+}
+
+func (a *AuthInstrumentedRepo) CreatePasswordReset(ctx context.Context, reset *models.PasswordReset) error {
+	defer a.metrics.observe("auth", "CreatePasswordReset", time.Now())
+	return a.repo.CreatePasswordReset(ctx, reset)
+}
+
+func (a *AuthInstrumentedRepo) UsePasswordReset(ctx context.Context, tokenHash []byte, nickname string) (*models.PasswordReset, error) {
+	defer a.metrics.observe("auth", "UsePasswordReset", time.Now())
+	return a.repo.UsePasswordReset(ctx, tokenHash, nickname)
+}
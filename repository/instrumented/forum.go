@@ -0,0 +1,42 @@
+package instrumented
+
+import (
+	"context"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+	"time"
+)
+
+type ForumInstrumentedRepo struct {
+	repo    domain.ForumRepo
+	metrics *QueryMetrics
+}
+
+func NewForumInstrumentedRepo(repo domain.ForumRepo, metrics *QueryMetrics) domain.ForumRepo {
+	return &ForumInstrumentedRepo{repo: repo, metrics: metrics}
+}
+
+func (a *ForumInstrumentedRepo) Create(ctx context.Context, forum *models.ForumCreate) (*models.Forum, error) {
+	defer a.metrics.observe("forum", "Create", time.Now())
+	return a.repo.Create(ctx, forum)
+}
+
+func (a *ForumInstrumentedRepo) Get(ctx context.Context, slug string) (*models.Forum, error) {
+	defer a.metrics.observe("forum", "Get", time.Now())
+	return a.repo.Get(ctx, slug)
+}
+
+func (a *ForumInstrumentedRepo) GetUsers(ctx context.Context, getSettings *models.GetForumUsers) (*[]models.User, error) {
+	defer a.metrics.observe("forum", "GetUsers", time.Now())
+	return a.repo.GetUsers(ctx, getSettings)
+}
+
+func (a *ForumInstrumentedRepo) GetThreads(ctx context.Context, slug string, getSettings *models.GetForumThreads) (*[]models.Thread, error) {
+	defer a.metrics.observe("forum", "GetThreads", time.Now())
+	return a.repo.GetThreads(ctx, slug, getSettings)
+}
+
+func (a *ForumInstrumentedRepo) CountThreads(ctx context.Context, slug string) (int64, error) {
+	defer a.metrics.observe("forum", "CountThreads", time.Now())
+	return a.repo.CountThreads(ctx, slug)
+}
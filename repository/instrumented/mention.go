@@ -0,0 +1,22 @@
+package instrumented
+
+import (
+	"context"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+	"time"
+)
+
+type MentionInstrumentedRepo struct {
+	repo    domain.MentionRepo
+	metrics *QueryMetrics
+}
+
+func NewMentionInstrumentedRepo(repo domain.MentionRepo, metrics *QueryMetrics) domain.MentionRepo {
+	return &MentionInstrumentedRepo{repo: repo, metrics: metrics}
+}
+
+func (a *MentionInstrumentedRepo) GetByUser(ctx context.Context, nickname string, getSettings *models.GetMentions) (*[]models.Mention, error) {
+	defer a.metrics.observe("mention", "GetByUser", time.Now())
+	return a.repo.GetByUser(ctx, nickname, getSettings)
+}
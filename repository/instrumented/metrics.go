@@ -0,0 +1,21 @@
+package instrumented
+
+import (
+	"technopark-db-semester-project/metrics"
+	"time"
+)
+
+// QueryMetrics - длительность вызовов методов репозиториев, метки repo и method
+type QueryMetrics struct {
+	duration *metrics.HistogramVec
+}
+
+func NewQueryMetrics(registry *metrics.Registry) *QueryMetrics {
+	return &QueryMetrics{
+		duration: registry.NewHistogramVec("repository_query_duration_seconds", "Duration of repository calls.", metrics.DefaultBuckets, "repo", "method"),
+	}
+}
+
+func (a *QueryMetrics) observe(repo string, method string, start time.Time) {
+	a.duration.Observe(time.Since(start).Seconds(), repo, method)
+}
@@ -0,0 +1,57 @@
+package instrumented
+
+import (
+	"context"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+	"time"
+)
+
+type NotificationInstrumentedRepo struct {
+	repo    domain.NotificationRepo
+	metrics *QueryMetrics
+}
+
+func NewNotificationInstrumentedRepo(repo domain.NotificationRepo, metrics *QueryMetrics) domain.NotificationRepo {
+	return &NotificationInstrumentedRepo{repo: repo, metrics: metrics}
+}
+
+func (a *NotificationInstrumentedRepo) SubscribeThread(ctx context.Context, nickname string, threadSlugOrId string) (*models.Subscription, error) {
+	defer a.metrics.observe("notification", "SubscribeThread", time.Now())
+	return a.repo.SubscribeThread(ctx, nickname, threadSlugOrId)
+}
+
+func (a *NotificationInstrumentedRepo) UnsubscribeThread(ctx context.Context, nickname string, threadSlugOrId string) error {
+	defer a.metrics.observe("notification", "UnsubscribeThread", time.Now())
+	return a.repo.UnsubscribeThread(ctx, nickname, threadSlugOrId)
+}
+
+func (a *NotificationInstrumentedRepo) SubscribeForum(ctx context.Context, nickname string, forumSlug string) (*models.Subscription, error) {
+	defer a.metrics.observe("notification", "SubscribeForum", time.Now())
+	return a.repo.SubscribeForum(ctx, nickname, forumSlug)
+}
+
+func (a *NotificationInstrumentedRepo) UnsubscribeForum(ctx context.Context, nickname string, forumSlug string) error {
+	defer a.metrics.observe("notification", "UnsubscribeForum", time.Now())
+	return a.repo.UnsubscribeForum(ctx, nickname, forumSlug)
+}
+
+func (a *NotificationInstrumentedRepo) GetSubscriptions(ctx context.Context, nickname string) (*[]models.Subscription, error) {
+	defer a.metrics.observe("notification", "GetSubscriptions", time.Now())
+	return a.repo.GetSubscriptions(ctx, nickname)
+}
+
+func (a *NotificationInstrumentedRepo) GetNotifications(ctx context.Context, nickname string, getSettings *models.GetNotifications) (*[]models.Notification, error) {
+	defer a.metrics.observe("notification", "GetNotifications", time.Now())
+	return a.repo.GetNotifications(ctx, nickname, getSettings)
+}
+
+func (a *NotificationInstrumentedRepo) MarkRead(ctx context.Context, nickname string, read *models.NotificationsRead) (*models.NotificationsCount, error) {
+	defer a.metrics.observe("notification", "MarkRead", time.Now())
+	return a.repo.MarkRead(ctx, nickname, read)
+}
+
+func (a *NotificationInstrumentedRepo) CountUnread(ctx context.Context, nickname string) (*models.NotificationsCount, error) {
+	defer a.metrics.observe("notification", "CountUnread", time.Now())
+	return a.repo.CountUnread(ctx, nickname)
+}
@@ -0,0 +1,42 @@
+package instrumented
+
+import (
+	"context"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+	"time"
+)
+
+type PostInstrumentedRepo struct {
+	repo    domain.PostRepo
+	metrics *QueryMetrics
+}
+
+func NewPostInstrumentedRepo(repo domain.PostRepo, metrics *QueryMetrics) domain.PostRepo {
+	return &PostInstrumentedRepo{repo: repo, metrics: metrics}
+}
+
+func (a *PostInstrumentedRepo) Get(ctx context.Context, id int64, getSettings *models.PostGetRequest) (*models.PostGetResult, error) {
+	defer a.metrics.observe("post", "Get", time.Now())
+	return a.repo.Get(ctx, id, getSettings)
+}
+
+func (a *PostInstrumentedRepo) Update(ctx context.Context, id int64, updateDate *models.PostUpdate) (*models.Post, error) {
+	defer a.metrics.observe("post", "Update", time.Now())
+	return a.repo.Update(ctx, id, updateDate)
+}
+
+func (a *PostInstrumentedRepo) Create(ctx context.Context, threadSlugOrId string, posts *[]models.PostCreate) (*[]models.Post, error) {
+	defer a.metrics.observe("post", "Create", time.Now())
+	return a.repo.Create(ctx, threadSlugOrId, posts)
+}
+
+func (a *PostInstrumentedRepo) Delete(ctx context.Context, id int64, deleteSettings *models.PostDeleteRequest) (*models.PostDeleteResult, error) {
+	defer a.metrics.observe("post", "Delete", time.Now())
+	return a.repo.Delete(ctx, id, deleteSettings)
+}
+
+func (a *PostInstrumentedRepo) GetHistory(ctx context.Context, id int64) (*models.PostHistory, error) {
+	defer a.metrics.observe("post", "GetHistory", time.Now())
+	return a.repo.GetHistory(ctx, id)
+}
@@ -0,0 +1,32 @@
+package instrumented
+
+import (
+	"context"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+	"time"
+)
+
+type RoleInstrumentedRepo struct {
+	repo    domain.RoleRepo
+	metrics *QueryMetrics
+}
+
+func NewRoleInstrumentedRepo(repo domain.RoleRepo, metrics *QueryMetrics) domain.RoleRepo {
+	return &RoleInstrumentedRepo{repo: repo, metrics: metrics}
+}
+
+func (a *RoleInstrumentedRepo) Get(ctx context.Context, forumSlug string, nickname string) (string, error) {
+	defer a.metrics.observe("role", "Get", time.Now())
+	return a.repo.Get(ctx, forumSlug, nickname)
+}
+
+func (a *RoleInstrumentedRepo) GetAll(ctx context.Context, forumSlug string) (*[]models.ForumRole, error) {
+	defer a.metrics.observe("role", "GetAll", time.Now())
+	return a.repo.GetAll(ctx, forumSlug)
+}
+
+func (a *RoleInstrumentedRepo) Set(ctx context.Context, forumSlug string, role *models.ForumRole) (*models.ForumRole, error) {
+	defer a.metrics.observe("role", "Set", time.Now())
+	return a.repo.Set(ctx, forumSlug, role)
+}
@@ -0,0 +1,22 @@
+package instrumented
+
+import (
+	"context"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+	"time"
+)
+
+type SearchInstrumentedRepo struct {
+	repo    domain.SearchRepo
+	metrics *QueryMetrics
+}
+
+func NewSearchInstrumentedRepo(repo domain.SearchRepo, metrics *QueryMetrics) domain.SearchRepo {
+	return &SearchInstrumentedRepo{repo: repo, metrics: metrics}
+}
+
+func (a *SearchInstrumentedRepo) Search(ctx context.Context, request *models.SearchRequest) (*models.SearchResult, error) {
+	defer a.metrics.observe("search", "Search", time.Now())
+	return a.repo.Search(ctx, request)
+}
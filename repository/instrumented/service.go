@@ -0,0 +1,27 @@
+package instrumented
+
+import (
+	"context"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+	"time"
+)
+
+type ServiceInstrumentedRepo struct {
+	repo    domain.ServiceRepo
+	metrics *QueryMetrics
+}
+
+func NewServiceInstrumentedRepo(repo domain.ServiceRepo, metrics *QueryMetrics) domain.ServiceRepo {
+	return &ServiceInstrumentedRepo{repo: repo, metrics: metrics}
+}
+
+func (a *ServiceInstrumentedRepo) GetInfo(ctx context.Context) (*models.Service, error) {
+	defer a.metrics.observe("service", "GetInfo", time.Now())
+	return a.repo.GetInfo(ctx)
+}
+
+func (a *ServiceInstrumentedRepo) Clear(ctx context.Context) error {
+	defer a.metrics.observe("service", "Clear", time.Now())
+	return a.repo.Clear(ctx)
+}
@@ -0,0 +1,62 @@
+package instrumented
+
+import (
+	"context"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+	"time"
+)
+
+type ThreadInstrumentedRepo struct {
+	repo    domain.ThreadRepo
+	metrics *QueryMetrics
+}
+
+func NewThreadInstrumentedRepo(repo domain.ThreadRepo, metrics *QueryMetrics) domain.ThreadRepo {
+	return &ThreadInstrumentedRepo{repo: repo, metrics: metrics}
+}
+
+func (a *ThreadInstrumentedRepo) Create(ctx context.Context, forumSlug string, thread *models.ThreadCreate) (*models.Thread, error) {
+	defer a.metrics.observe("thread", "Create", time.Now())
+	return a.repo.Create(ctx, forumSlug, thread)
+}
+
+func (a *ThreadInstrumentedRepo) Get(ctx context.Context, threadSlugOrId string) (*models.Thread, error) {
+	defer a.metrics.observe("thread", "Get", time.Now())
+	return a.repo.Get(ctx, threadSlugOrId)
+}
+
+func (a *ThreadInstrumentedRepo) Update(ctx context.Context, threadSlugOrId string, updateData *models.ThreadUpdate) (*models.Thread, error) {
+	defer a.metrics.observe("thread", "Update", time.Now())
+	return a.repo.Update(ctx, threadSlugOrId, updateData)
+}
+
+func (a *ThreadInstrumentedRepo) GetPosts(ctx context.Context, slugOrId string, getSettings *models.ThreadPostRequest) (*[]models.Post, error) {
+	defer a.metrics.observe("thread", "GetPosts", time.Now())
+	return a.repo.GetPosts(ctx, slugOrId, getSettings)
+}
+
+func (a *ThreadInstrumentedRepo) SetState(ctx context.Context, threadSlugOrId string, stateUpdate *models.ThreadStateUpdate) (*models.Thread, error) {
+	defer a.metrics.observe("thread", "SetState", time.Now())
+	return a.repo.SetState(ctx, threadSlugOrId, stateUpdate)
+}
+
+func (a *ThreadInstrumentedRepo) SetPin(ctx context.Context, threadSlugOrId string, pin *models.ThreadPin) (*models.Thread, error) {
+	defer a.metrics.observe("thread", "SetPin", time.Now())
+	return a.repo.SetPin(ctx, threadSlugOrId, pin)
+}
+
+func (a *ThreadInstrumentedRepo) Move(ctx context.Context, threadSlugOrId string, move *models.ThreadMove) (*models.Thread, error) {
+	defer a.metrics.observe("thread", "Move", time.Now())
+	return a.repo.Move(ctx, threadSlugOrId, move)
+}
+
+func (a *ThreadInstrumentedRepo) Merge(ctx context.Context, threadSlugOrId string, merge *models.ThreadMerge) (*models.Thread, error) {
+	defer a.metrics.observe("thread", "Merge", time.Now())
+	return a.repo.Merge(ctx, threadSlugOrId, merge)
+}
+
+func (a *ThreadInstrumentedRepo) Split(ctx context.Context, postId int64, split *models.ThreadSplit) (*models.Thread, error) {
+	defer a.metrics.observe("thread", "Split", time.Now())
+	return a.repo.Split(ctx, postId, split)
+}
@@ -0,0 +1,32 @@
+package instrumented
+
+import (
+	"context"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+	"time"
+)
+
+type UserInstrumentedRepo struct {
+	repo    domain.UserRepo
+	metrics *QueryMetrics
+}
+
+func NewUserInstrumentedRepo(repo domain.UserRepo, metrics *QueryMetrics) domain.UserRepo {
+	return &UserInstrumentedRepo{repo: repo, metrics: metrics}
+}
+
+func (a *UserInstrumentedRepo) Create(ctx context.Context, user *models.User) (*[]models.User, error) {
+	defer a.metrics.observe("user", "Create", time.Now())
+	return a.repo.Create(ctx, user)
+}
+
+func (a *UserInstrumentedRepo) Update(ctx context.Context, nickname string, updateData *models.UserUpdate) (*models.User, error) {
+	defer a.metrics.observe("user", "Update", time.Now())
+	return a.repo.Update(ctx, nickname, updateData)
+}
+
+func (a *UserInstrumentedRepo) Get(ctx context.Context, nicknameOrEmail string) (*models.User, error) {
+	defer a.metrics.observe("user", "Get", time.Now())
+	return a.repo.Get(ctx, nicknameOrEmail)
+}
@@ -0,0 +1,37 @@
+package instrumented
+
+import (
+	"context"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+	"time"
+)
+
+type VoteInstrumentedRepo struct {
+	repo    domain.VoteRepo
+	metrics *QueryMetrics
+}
+
+func NewVoteInstrumentedRepo(repo domain.VoteRepo, metrics *QueryMetrics) domain.VoteRepo {
+	return &VoteInstrumentedRepo{repo: repo, metrics: metrics}
+}
+
+func (a *VoteInstrumentedRepo) Create(ctx context.Context, threadSlugOrId string, vote *models.VoteCreate) (*models.Thread, error) {
+	defer a.metrics.observe("vote", "Create", time.Now())
+	return a.repo.Create(ctx, threadSlugOrId, vote)
+}
+
+func (a *VoteInstrumentedRepo) CreateForPost(ctx context.Context, postId int64, vote *models.VoteCreate) (*models.Post, error) {
+	defer a.metrics.observe("vote", "CreateForPost", time.Now())
+	return a.repo.CreateForPost(ctx, postId, vote)
+}
+
+func (a *VoteInstrumentedRepo) GetByThread(ctx context.Context, threadSlugOrId string, getSettings *models.GetThreadVotes) (*[]models.Vote, error) {
+	defer a.metrics.observe("vote", "GetByThread", time.Now())
+	return a.repo.GetByThread(ctx, threadSlugOrId, getSettings)
+}
+
+func (a *VoteInstrumentedRepo) GetByUser(ctx context.Context, nickname string, getSettings *models.GetUserVotes) (*[]models.Vote, error) {
+	defer a.metrics.observe("vote", "GetByUser", time.Now())
+	return a.repo.GetByUser(ctx, nickname, getSettings)
+}
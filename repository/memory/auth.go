@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"context"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+	"time"
+)
+
+type AuthMemoryRepo struct {
+	Storage *Storage
+}
+
+func NewAuthMemoryRepo(storage *Storage) domain.AuthRepo {
+	return &AuthMemoryRepo{Storage: storage}
+}
+
+func (a *AuthMemoryRepo) GetPasswordHash(ctx context.Context, nickname string) (string, []byte, error) {
+	a.Storage.mu.RLock()
+	defer a.Storage.mu.RUnlock()
+
+	user, ok := a.Storage.getUser(nickname)
+	if !ok {
+		return "", nil, domain.ErrorUserDoesNotExist
+	}
+
+	return user.Nickname, user.PasswordHash, nil
+}
+
+func (a *AuthMemoryRepo) CreateSession(ctx context.Context, session *models.Session) error {
+	a.Storage.mu.Lock()
+	defer a.Storage.mu.Unlock()
+
+	now := time.Now()
+	for token, stored := range a.Storage.sessions {
+		if key(stored.Nickname) == key(session.Nickname) && !stored.Expires.After(now) {
+			delete(a.Storage.sessions, token)
+		}
+	}
+	a.Storage.sessions[string(session.TokenHash)] = *session
+
+	return nil
+}
+
+func (a *AuthMemoryRepo) GetSession(ctx context.Context, tokenHash []byte) (*models.Session, error) {
+	a.Storage.mu.RLock()
+	defer a.Storage.mu.RUnlock()
+
+	session, ok := a.Storage.sessions[string(tokenHash)]
+	if !ok || !session.Expires.After(time.Now()) {
+		return nil, domain.ErrorSessionDoesNotExist
+	}
+
+	return &session, nil
+}
+
+func (a *AuthMemoryRepo) DeleteSession(ctx context.Context, tokenHash []byte) error {
+	a.Storage.mu.Lock()
+	defer a.Storage.mu.Unlock()
+
+	delete(a.Storage.sessions, string(tokenHash))
+
+	return nil
+}
+
+func (a *AuthMemoryRepo) SetPasswordHash(ctx context.Context, nickname string, hash []byte) error {
+	a.Storage.mu.Lock()
+	defer a.Storage.mu.Unlock()
+
+	user, ok := a.Storage.getUser(nickname)
+	if !ok {
+		return domain.ErrorUserDoesNotExist
+	}
+	user.PasswordHash = hash
+
+	for token, session := range a.Storage.sessions {
+		if key(session.Nickname) == key(user.Nickname) {
+			delete(a.Storage.sessions, token)
+		}
+	}
+	a.Storage.deletePasswordResets(user.Nickname)
+
+	return nil
+}
+
+func (a *AuthMemoryRepo) CreatePasswordReset(ctx context.Context, reset *models.PasswordReset) error {
+	a.Storage.mu.Lock()
+	defer a.Storage.mu.Unlock()
+
+	user, ok := a.Storage.getUser(reset.Nickname)
+	if !ok {
+		return domain.ErrorUserDoesNotExist
+	}
+	reset.Nickname = user.Nickname
+
+	a.Storage.deletePasswordResets(user.Nickname)
+	a.Storage.passwordResets[string(reset.TokenHash)] = *reset
+
+	return nil
+}
+
+func (a *AuthMemoryRepo) UsePasswordReset(ctx context.Context, tokenHash []byte, nickname string) (*models.PasswordReset, error) {
+	a.Storage.mu.Lock()
+	defer a.Storage.mu.Unlock()
+
+	reset, ok := a.Storage.passwordResets[string(tokenHash)]
+	if !ok || key(reset.Nickname) != key(nickname) {
+		return nil, domain.ErrorPasswordResetDoesNotExist
+	}
+	delete(a.Storage.passwordResets, string(tokenHash))
+	if !reset.Expires.After(time.Now()) {
+		return nil, domain.ErrorPasswordResetDoesNotExist
+	}
+
+	return &reset, nil
+}
+
+// deletePasswordResets удаляет сбросы пароля пользователя, вызывать под блокировкой
+func (a *Storage) deletePasswordResets(nickname string) {
+	for token, reset := range a.passwordResets {
+		if key(reset.Nickname) == key(nickname) {
+			delete(a.passwordResets, token)
+		}
+	}
+}
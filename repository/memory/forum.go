@@ -0,0 +1,198 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+	"time"
+)
+
+type ForumMemoryRepo struct {
+	Storage *Storage
+}
+
+func NewForumMemoryRepo(storage *Storage) domain.ForumRepo {
+	return &ForumMemoryRepo{Storage: storage}
+}
+
+func (a *ForumMemoryRepo) Create(ctx context.Context, forum *models.ForumCreate) (*models.Forum, error) {
+	a.Storage.mu.Lock()
+	defer a.Storage.mu.Unlock()
+
+	user, ok := a.Storage.getUser(forum.User)
+	if !ok {
+		return nil, domain.ErrorUserDoesNotExist
+	}
+
+	if existing, ok := a.Storage.forums[key(forum.Slug)]; ok {
+		forumAlreadyExist := *existing
+		return &forumAlreadyExist, domain.ErrorForumAlreadyExist
+	}
+
+	created := &models.Forum{
+		Title:   forum.Title,
+		User:    user.Nickname,
+		Slug:    forum.Slug,
+		Posts:   0,
+		Threads: 0,
+	}
+	a.Storage.forums[key(forum.Slug)] = created
+
+	forumToReturn := *created
+
+	return &forumToReturn, nil
+}
+
+func (a *ForumMemoryRepo) Get(ctx context.Context, slug string) (*models.Forum, error) {
+	a.Storage.mu.RLock()
+	defer a.Storage.mu.RUnlock()
+
+	forum, ok := a.Storage.forums[key(slug)]
+	if !ok {
+		return nil, domain.ErrorForumDoesNotExist
+	}
+
+	forumToReturn := *forum
+
+	return &forumToReturn, nil
+}
+
+func (a *ForumMemoryRepo) GetUsers(ctx context.Context, getSettings *models.GetForumUsers) (*[]models.User, error) {
+	a.Storage.mu.RLock()
+	defer a.Storage.mu.RUnlock()
+
+	if _, ok := a.Storage.forums[key(getSettings.Slug)]; !ok {
+		return nil, domain.ErrorForumDoesNotExist
+	}
+
+	// ForumUsers.nickname - citext COLLATE "C", то есть побайтовое сравнение ников в нижнем регистре
+	since := key(getSettings.Since)
+	users := make([]models.User, 0)
+	for nicknameKey, user := range a.Storage.forumUsers[key(getSettings.Slug)] {
+		if getSettings.Since != "" {
+			if getSettings.Desc && nicknameKey >= since {
+				continue
+			}
+			if !getSettings.Desc && nicknameKey <= since {
+				continue
+			}
+		}
+		users = append(users, user)
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		if getSettings.Desc {
+			return key(users[i].Nickname) > key(users[j].Nickname)
+		}
+		return key(users[i].Nickname) < key(users[j].Nickname)
+	})
+
+	if int(getSettings.Limit) < len(users) {
+		users = users[:getSettings.Limit]
+	}
+
+	return &users, nil
+}
+
+func (a *ForumMemoryRepo) GetThreads(ctx context.Context, slug string, getSettings *models.GetForumThreads) (*[]models.Thread, error) {
+	a.Storage.mu.RLock()
+	defer a.Storage.mu.RUnlock()
+
+	if _, ok := a.Storage.forums[key(slug)]; !ok {
+		return nil, domain.ErrorForumDoesNotExist
+	}
+
+	var since time.Time
+	if getSettings.Since != "" {
+		var err error
+		since, err = time.Parse(time.RFC3339Nano, getSettings.Since)
+		if err != nil {
+			return nil, domain.ErrorForumDoesNotExist
+		}
+	}
+
+	pinned := make([]models.Thread, 0)
+	threads := make([]models.Thread, 0)
+	for _, thread := range a.Storage.threads {
+		if thread.Announcement || (thread.Pinned > 0 && key(thread.Forum) == key(slug)) {
+			if getSettings.Since == "" && getSettings.After == nil {
+				pinned = append(pinned, *thread)
+			}
+			continue
+		}
+		if key(thread.Forum) != key(slug) {
+			continue
+		}
+		if after := getSettings.After; after != nil && after.Id != 0 {
+			cmp := compareCreated(thread.Created, int64(thread.Id), after.Created, after.Id)
+			if getSettings.Desc && cmp >= 0 {
+				continue
+			}
+			if !getSettings.Desc && cmp <= 0 {
+				continue
+			}
+		} else if getSettings.Since != "" {
+			if getSettings.Desc && thread.Created.After(since) {
+				continue
+			}
+			if !getSettings.Desc && thread.Created.Before(since) {
+				continue
+			}
+		}
+		threads = append(threads, *thread)
+	}
+
+	sort.Slice(threads, func(i, j int) bool {
+		if !threads[i].Created.Equal(threads[j].Created) {
+			if getSettings.Desc {
+				return threads[i].Created.After(threads[j].Created)
+			}
+			return threads[i].Created.Before(threads[j].Created)
+		}
+		if getSettings.Desc {
+			return threads[i].Id > threads[j].Id
+		}
+		return threads[i].Id < threads[j].Id
+	})
+
+	if int(getSettings.Limit) < len(threads) {
+		threads = threads[:getSettings.Limit]
+	}
+
+	// объявления и закреплённые ветки идут перед остальными, места в limit между ними распределяет delivery
+	sort.Slice(pinned, func(i, j int) bool {
+		if pinned[i].Announcement != pinned[j].Announcement {
+			return pinned[i].Announcement
+		}
+		if pinned[i].Pinned != pinned[j].Pinned {
+			return pinned[i].Pinned > pinned[j].Pinned
+		}
+		if !pinned[i].Created.Equal(pinned[j].Created) {
+			return pinned[i].Created.After(pinned[j].Created)
+		}
+		return pinned[i].Id > pinned[j].Id
+	})
+	threads = append(pinned, threads...)
+
+	return &threads, nil
+}
+
+func (a *ForumMemoryRepo) CountThreads(ctx context.Context, slug string) (int64, error) {
+	a.Storage.mu.RLock()
+	defer a.Storage.mu.RUnlock()
+
+	forum, ok := a.Storage.forums[key(slug)]
+	if !ok {
+		return 0, domain.ErrorForumDoesNotExist
+	}
+
+	count := int64(forum.Threads)
+	for _, thread := range a.Storage.threads {
+		if thread.Announcement && key(thread.Forum) != key(slug) {
+			count++
+		}
+	}
+
+	return count, nil
+}
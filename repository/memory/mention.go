@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"context"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+	"time"
+)
+
+type MentionMemoryRepo struct {
+	Storage *Storage
+}
+
+func NewMentionMemoryRepo(storage *Storage) domain.MentionRepo {
+	return &MentionMemoryRepo{Storage: storage}
+}
+
+func (a *MentionMemoryRepo) GetByUser(ctx context.Context, nickname string, getSettings *models.GetMentions) (*[]models.Mention, error) {
+	a.Storage.mu.RLock()
+	defer a.Storage.mu.RUnlock()
+
+	user, ok := a.Storage.getUser(nickname)
+	if !ok {
+		return nil, domain.ErrorUserDoesNotExist
+	}
+
+	stored := a.Storage.mentions
+	mentions := make([]models.Mention, 0)
+	for ind := range stored {
+		mention := stored[ind]
+		if getSettings.Desc {
+			mention = stored[len(stored)-1-ind]
+		}
+
+		if key(mention.nickname) != key(user.Nickname) {
+			continue
+		}
+		if getSettings.Since != 0 {
+			if getSettings.Desc && mention.id >= getSettings.Since {
+				continue
+			}
+			if !getSettings.Desc && mention.id <= getSettings.Since {
+				continue
+			}
+		}
+		// упоминания удалённых постов и веток пропускаются, как ON DELETE CASCADE
+		result := models.Mention{Id: mention.id, Nickname: mention.nickname, Post: mention.post, Created: mention.created}
+		if mention.post != 0 {
+			post, ok := a.Storage.posts[mention.post]
+			if !ok {
+				continue
+			}
+			result.Thread, result.Forum, result.Author = post.post.Thread, post.post.Forum, post.post.Author
+		} else {
+			thread, ok := a.Storage.threads[mention.thread]
+			if !ok {
+				continue
+			}
+			result.Thread, result.Forum, result.Author = thread.Id, thread.Forum, thread.Author
+		}
+		if int32(len(mentions)) == getSettings.Limit {
+			break
+		}
+
+		mentions = append(mentions, result)
+	}
+
+	return &mentions, nil
+}
+
+// setMentions - аналог DeletePostMentionsCommand и AddPostMentionsCommand: приводит упоминания поста post
+// (или, если post = 0, текста ветки thread) к тексту message. Вернёт ники новых упомянутых, вызывать под блокировкой
+func (a *Storage) setMentions(post int64, thread int32, author string, message string) []string {
+	mentioned := make(map[string]string)
+	for _, nickname := range domain.ParseMentions(message) {
+		if user, ok := a.getUser(nickname); ok && key(user.Nickname) != key(author) {
+			mentioned[key(user.Nickname)] = user.Nickname
+		}
+	}
+
+	remaining := make([]*storedMention, 0, len(a.mentions))
+	for _, mention := range a.mentions {
+		if mention.post == post && mention.thread == thread {
+			if _, ok := mentioned[key(mention.nickname)]; !ok {
+				continue
+			}
+			delete(mentioned, key(mention.nickname))
+		}
+		remaining = append(remaining, mention)
+	}
+	a.mentions = remaining
+
+	added := sortedNicknames(mentioned)
+	created := time.Unix(0, time.Now().UnixNano()/1e6*1e6)
+	for _, nickname := range added {
+		a.lastMentionId++
+		a.mentions = append(a.mentions, &storedMention{id: a.lastMentionId, nickname: nickname, post: post, thread: thread, created: created})
+	}
+
+	return added
+}
+
+// notifyMentioned уведомляет об упоминании при создании ветки и правке текста, вызывать под блокировкой
+func (a *Storage) notifyMentioned(post int64, thread int32, mentioned []string) {
+	for _, nickname := range mentioned {
+		a.addNotification(nickname, &storedNotification{kind: models.NotificationMention, post: post, thread: thread})
+	}
+}
+
+// moveMentions - аналог ThreadMentionsToPostCommand и PostMentionsToThreadCommand: упоминания и уведомления
+// переезжают вместе с текстом из поста в ветку и обратно. Вызывать под блокировкой
+func (a *Storage) moveMentions(fromPost int64, fromThread int32, toPost int64, toThread int32) {
+	for _, mention := range a.mentions {
+		if mention.post == fromPost && mention.thread == fromThread {
+			mention.post, mention.thread = toPost, toThread
+		}
+	}
+	for _, notifications := range a.notifications {
+		for _, notification := range notifications {
+			if notification.post == fromPost && (fromPost != 0 || notification.thread == fromThread) {
+				notification.post, notification.thread = toPost, toThread
+			}
+		}
+	}
+}
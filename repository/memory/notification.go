@@ -0,0 +1,273 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+)
+
+type NotificationMemoryRepo struct {
+	Storage *Storage
+}
+
+func NewNotificationMemoryRepo(storage *Storage) domain.NotificationRepo {
+	return &NotificationMemoryRepo{Storage: storage}
+}
+
+func (a *NotificationMemoryRepo) SubscribeThread(ctx context.Context, nickname string, threadSlugOrId string) (*models.Subscription, error) {
+	a.Storage.mu.Lock()
+	defer a.Storage.mu.Unlock()
+
+	thread, err := a.Storage.getThread(threadSlugOrId)
+	if err != nil {
+		return nil, err
+	}
+	user, ok := a.Storage.getUser(nickname)
+	if !ok {
+		return nil, domain.ErrorUserDoesNotExist
+	}
+
+	subscribers, ok := a.Storage.threadSubscriptions[thread.Id]
+	if !ok {
+		subscribers = make(map[string]string)
+		a.Storage.threadSubscriptions[thread.Id] = subscribers
+	}
+	subscribers[key(user.Nickname)] = user.Nickname
+
+	return &models.Subscription{Nickname: user.Nickname, Thread: thread.Id}, nil
+}
+
+func (a *NotificationMemoryRepo) UnsubscribeThread(ctx context.Context, nickname string, threadSlugOrId string) error {
+	a.Storage.mu.Lock()
+	defer a.Storage.mu.Unlock()
+
+	thread, err := a.Storage.getThread(threadSlugOrId)
+	if err != nil {
+		return err
+	}
+	delete(a.Storage.threadSubscriptions[thread.Id], key(nickname))
+
+	return nil
+}
+
+func (a *NotificationMemoryRepo) SubscribeForum(ctx context.Context, nickname string, forumSlug string) (*models.Subscription, error) {
+	a.Storage.mu.Lock()
+	defer a.Storage.mu.Unlock()
+
+	forum, ok := a.Storage.forums[key(forumSlug)]
+	if !ok {
+		return nil, domain.ErrorForumDoesNotExist
+	}
+	user, ok := a.Storage.getUser(nickname)
+	if !ok {
+		return nil, domain.ErrorUserDoesNotExist
+	}
+
+	subscribers, ok := a.Storage.forumSubscriptions[key(forum.Slug)]
+	if !ok {
+		subscribers = make(map[string]string)
+		a.Storage.forumSubscriptions[key(forum.Slug)] = subscribers
+	}
+	subscribers[key(user.Nickname)] = user.Nickname
+
+	return &models.Subscription{Nickname: user.Nickname, Forum: forum.Slug}, nil
+}
+
+func (a *NotificationMemoryRepo) UnsubscribeForum(ctx context.Context, nickname string, forumSlug string) error {
+	a.Storage.mu.Lock()
+	defer a.Storage.mu.Unlock()
+
+	if _, ok := a.Storage.forums[key(forumSlug)]; !ok {
+		return domain.ErrorForumDoesNotExist
+	}
+	delete(a.Storage.forumSubscriptions[key(forumSlug)], key(nickname))
+
+	return nil
+}
+
+func (a *NotificationMemoryRepo) GetSubscriptions(ctx context.Context, nickname string) (*[]models.Subscription, error) {
+	a.Storage.mu.RLock()
+	defer a.Storage.mu.RUnlock()
+
+	user, ok := a.Storage.getUser(nickname)
+	if !ok {
+		return nil, domain.ErrorUserDoesNotExist
+	}
+
+	subscriptions := make([]models.Subscription, 0)
+	for forumKey, subscribers := range a.Storage.forumSubscriptions {
+		if _, ok = subscribers[key(user.Nickname)]; ok {
+			subscriptions = append(subscriptions, models.Subscription{Nickname: user.Nickname, Forum: a.Storage.forums[forumKey].Slug})
+		}
+	}
+	for threadId, subscribers := range a.Storage.threadSubscriptions {
+		if _, ok = subscribers[key(user.Nickname)]; ok {
+			subscriptions = append(subscriptions, models.Subscription{Nickname: user.Nickname, Thread: threadId})
+		}
+	}
+
+	sort.Slice(subscriptions, func(i, j int) bool {
+		if subscriptions[i].Thread != subscriptions[j].Thread {
+			return subscriptions[i].Thread < subscriptions[j].Thread
+		}
+		return subscriptions[i].Forum < subscriptions[j].Forum
+	})
+
+	return &subscriptions, nil
+}
+
+func (a *NotificationMemoryRepo) GetNotifications(ctx context.Context, nickname string, getSettings *models.GetNotifications) (*[]models.Notification, error) {
+	a.Storage.mu.RLock()
+	defer a.Storage.mu.RUnlock()
+
+	user, ok := a.Storage.getUser(nickname)
+	if !ok {
+		return nil, domain.ErrorUserDoesNotExist
+	}
+
+	stored := a.Storage.notifications[key(user.Nickname)]
+	notifications := make([]models.Notification, 0)
+	for ind := range stored {
+		notification := stored[ind]
+		if getSettings.Desc {
+			notification = stored[len(stored)-1-ind]
+		}
+
+		if getSettings.Since != 0 {
+			if getSettings.Desc && notification.id >= getSettings.Since {
+				continue
+			}
+			if !getSettings.Desc && notification.id <= getSettings.Since {
+				continue
+			}
+		}
+		if getSettings.Unread && notification.read {
+			continue
+		}
+		result, ok := a.Storage.getNotification(notification)
+		if !ok {
+			continue
+		}
+		if int32(len(notifications)) == getSettings.Limit {
+			break
+		}
+
+		notifications = append(notifications, result)
+	}
+
+	return &notifications, nil
+}
+
+func (a *NotificationMemoryRepo) MarkRead(ctx context.Context, nickname string, read *models.NotificationsRead) (*models.NotificationsCount, error) {
+	a.Storage.mu.Lock()
+	defer a.Storage.mu.Unlock()
+
+	user, ok := a.Storage.getUser(nickname)
+	if !ok {
+		return nil, domain.ErrorUserDoesNotExist
+	}
+
+	ids := make(map[int64]bool, len(read.Ids))
+	for _, id := range read.Ids {
+		ids[id] = true
+	}
+	for _, notification := range a.Storage.notifications[key(user.Nickname)] {
+		if read.All || ids[notification.id] {
+			notification.read = true
+		}
+	}
+
+	return a.Storage.countUnread(user.Nickname), nil
+}
+
+func (a *NotificationMemoryRepo) CountUnread(ctx context.Context, nickname string) (*models.NotificationsCount, error) {
+	a.Storage.mu.RLock()
+	defer a.Storage.mu.RUnlock()
+
+	user, ok := a.Storage.getUser(nickname)
+	if !ok {
+		return nil, domain.ErrorUserDoesNotExist
+	}
+
+	return a.Storage.countUnread(user.Nickname), nil
+}
+
+// getNotification дополняет уведомление данными поста или ветки. Уведомления удалённых постов и веток
+// не отдаются, как при ON DELETE CASCADE. Вызывать под блокировкой
+func (a *Storage) getNotification(notification *storedNotification) (models.Notification, bool) {
+	result := models.Notification{Id: notification.id, Kind: notification.kind, Post: notification.post, Read: notification.read}
+	if notification.post != 0 {
+		post, ok := a.posts[notification.post]
+		if !ok {
+			return result, false
+		}
+		result.Thread, result.Forum, result.Author, result.Created = post.post.Thread, post.post.Forum, post.post.Author, post.post.Created
+		return result, true
+	}
+
+	thread, ok := a.threads[notification.thread]
+	if !ok {
+		return result, false
+	}
+	result.Thread, result.Forum, result.Author, result.Created = thread.Id, thread.Forum, thread.Author, thread.Created
+
+	return result, true
+}
+
+// countUnread считает непрочитанные уведомления о существующих постах и ветках, вызывать под блокировкой
+func (a *Storage) countUnread(nickname string) *models.NotificationsCount {
+	count := &models.NotificationsCount{}
+	for _, notification := range a.notifications[key(nickname)] {
+		if _, ok := a.getNotification(notification); ok && !notification.read {
+			count.Unread++
+		}
+	}
+
+	return count
+}
+
+// notify - аналог CreateNotificationsCommand: уведомляет автора родителя, упомянутых mentioned, подписчиков ветки и форума
+// о новом посте, каждого пользователя один раз по первой причине. Вызывать под блокировкой после сохранения поста
+func (a *Storage) notify(post *models.Post, mentioned []string) {
+	notified := map[string]bool{key(post.Author): true}
+	add := func(nickname string, kind string) {
+		if notified[key(nickname)] {
+			return
+		}
+		notified[key(nickname)] = true
+
+		a.addNotification(nickname, &storedNotification{kind: kind, post: post.Id})
+	}
+
+	if parent, ok := a.posts[post.Parent]; ok && post.Parent != 0 {
+		add(parent.post.Author, models.NotificationReply)
+	}
+	for _, nickname := range mentioned {
+		add(nickname, models.NotificationMention)
+	}
+	for _, nickname := range sortedNicknames(a.threadSubscriptions[post.Thread]) {
+		add(nickname, models.NotificationThread)
+	}
+	for _, nickname := range sortedNicknames(a.forumSubscriptions[key(post.Forum)]) {
+		add(nickname, models.NotificationForum)
+	}
+}
+
+// addNotification выдаёт уведомлению id и добавляет его пользователю nickname, вызывать под блокировкой
+func (a *Storage) addNotification(nickname string, notification *storedNotification) {
+	a.lastNotificationId++
+	notification.id = a.lastNotificationId
+	a.notifications[key(nickname)] = append(a.notifications[key(nickname)], notification)
+}
+
+// sortedNicknames - ники по порядку, чтобы id уведомлений и упоминаний не зависели от обхода map
+func sortedNicknames(byKey map[string]string) []string {
+	nicknames := make([]string, 0, len(byKey))
+	for _, nickname := range byKey {
+		nicknames = append(nicknames, nickname)
+	}
+	sort.Strings(nicknames)
+
+	return nicknames
+}
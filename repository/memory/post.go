@@ -0,0 +1,248 @@
+package memory
+
+import (
+	"context"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+	"time"
+)
+
+type PostMemoryRepo struct {
+	Storage *Storage
+}
+
+func NewPostMemoryRepo(storage *Storage) domain.PostRepo {
+	return &PostMemoryRepo{Storage: storage}
+}
+
+func isIn(arr *[]string, find string) bool {
+	for _, str := range *arr {
+		if str == find {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (a *PostMemoryRepo) Get(ctx context.Context, id int64, getSettings *models.PostGetRequest) (*models.PostGetResult, error) {
+	a.Storage.mu.RLock()
+	defer a.Storage.mu.RUnlock()
+
+	stored, ok := a.Storage.posts[id]
+	if !ok {
+		return nil, domain.ErrorPostDoesNotExist
+	}
+
+	post := stored.post
+	var postResult models.PostGetResult
+	postResult.Post = &post
+
+	if isIn(&getSettings.Related, models.RelatedUser) {
+		author := &models.User{}
+		if user, ok := a.Storage.getUser(post.Author); ok {
+			*author = *user
+		}
+		postResult.Author = author
+	}
+	if isIn(&getSettings.Related, models.RelatedThread) {
+		thread := &models.Thread{}
+		if stored, ok := a.Storage.threads[post.Thread]; ok {
+			*thread = *stored
+		}
+		postResult.Thread = thread
+	}
+	if isIn(&getSettings.Related, models.RelatedForum) {
+		forum := &models.Forum{}
+		if stored, ok := a.Storage.forums[key(post.Forum)]; ok {
+			*forum = *stored
+		}
+		postResult.Forum = forum
+	}
+
+	return &postResult, nil
+}
+
+func (a *PostMemoryRepo) Update(ctx context.Context, id int64, updateDate *models.PostUpdate) (*models.Post, error) {
+	a.Storage.mu.Lock()
+	defer a.Storage.mu.Unlock()
+
+	stored, ok := a.Storage.posts[id]
+	if !ok {
+		return nil, domain.ErrorPostDoesNotExist
+	}
+	if stored.post.IsDeleted {
+		return nil, domain.ErrorPostIsDeleted
+	}
+
+	if updateDate.Message != "" && updateDate.Message != stored.post.Message {
+		editor := updateDate.Editor
+		if editor == "" {
+			editor = stored.post.Author
+		}
+		user, ok := a.Storage.getUser(editor)
+		if !ok {
+			return nil, domain.ErrorUserDoesNotExist
+		}
+
+		stored.revisions = append(stored.revisions, models.PostRevision{
+			Revision: int32(len(stored.revisions) + 1),
+			Editor:   user.Nickname,
+			Message:  stored.post.Message,
+			Created:  time.Unix(0, time.Now().UnixNano()/1e6*1e6),
+		})
+		stored.post.Message = updateDate.Message
+		stored.post.IsEdited = true
+		a.Storage.notifyMentioned(id, 0, a.Storage.setMentions(id, 0, stored.post.Author, stored.post.Message))
+	}
+
+	post := stored.post
+
+	return &post, nil
+}
+
+func (a *PostMemoryRepo) Create(ctx context.Context, threadSlugOrId string, posts *[]models.PostCreate) (*[]models.Post, error) {
+	a.Storage.mu.Lock()
+	defer a.Storage.mu.Unlock()
+
+	thread, err := a.Storage.getThread(threadSlugOrId)
+	if err != nil {
+		return nil, err
+	}
+	if err = domain.CheckWritable(thread.State, false); err != nil {
+		return nil, err
+	}
+
+	if len(*posts) == 0 {
+		postsToRet := make([]models.Post, 0)
+		return &postsToRet, nil
+	}
+
+	// вся пачка проверяется до вставки, чтобы при ошибке ничего не записать
+	for _, post := range *posts {
+		if post.Parent != 0 {
+			parent, ok := a.Storage.posts[post.Parent]
+			if !ok || parent.post.Thread != thread.Id {
+				return nil, domain.ErrorParentPostDoesNotExist
+			}
+		}
+	}
+	for _, post := range *posts {
+		if _, ok := a.Storage.getUser(post.Author); !ok {
+			return nil, domain.ErrorAuthorDoesNotExist
+		}
+	}
+	for _, post := range *posts {
+		if a.Storage.isBanned(thread.Forum, post.Author) {
+			return nil, domain.ErrorUserBanned
+		}
+	}
+
+	forum := a.Storage.forums[key(thread.Forum)]
+	postsToReturn := make([]models.Post, 0, len(*posts))
+	createdTime := time.Unix(0, time.Now().UnixNano()/1e6*1e6)
+	for _, post := range *posts {
+		a.Storage.lastPostId++
+		stored := &storedPost{
+			post: models.Post{
+				Id:      a.Storage.lastPostId,
+				Parent:  post.Parent,
+				Author:  post.Author,
+				Message: post.Message,
+				Forum:   thread.Forum,
+				Thread:  thread.Id,
+				Created: createdTime,
+			},
+		}
+
+		if post.Parent != 0 {
+			parentPath := a.Storage.posts[post.Parent].parentPath
+			stored.parentPath = make([]int64, 0, len(parentPath)+1)
+			stored.parentPath = append(stored.parentPath, parentPath...)
+		}
+		stored.parentPath = append(stored.parentPath, stored.post.Id)
+
+		a.Storage.posts[stored.post.Id] = stored
+		a.Storage.threadPosts[thread.Id] = append(a.Storage.threadPosts[thread.Id], stored.post.Id)
+		forum.Posts++
+		a.Storage.addForumUser(thread.Forum, post.Author)
+		a.Storage.notify(&stored.post, a.Storage.setMentions(stored.post.Id, 0, stored.post.Author, stored.post.Message))
+
+		postsToReturn = append(postsToReturn, stored.post)
+	}
+
+	return &postsToReturn, nil
+}
+
+func (a *PostMemoryRepo) Delete(ctx context.Context, id int64, deleteSettings *models.PostDeleteRequest) (*models.PostDeleteResult, error) {
+	a.Storage.mu.Lock()
+	defer a.Storage.mu.Unlock()
+
+	stored, ok := a.Storage.posts[id]
+	if !ok {
+		return nil, domain.ErrorPostDoesNotExist
+	}
+
+	result := &models.PostDeleteResult{}
+	if deleteSettings.Hard {
+		threadId := stored.post.Thread
+		remaining := make([]int64, 0, len(a.Storage.threadPosts[threadId]))
+		for _, postId := range a.Storage.threadPosts[threadId] {
+			post := a.Storage.posts[postId]
+			if !hasPrefix(post.parentPath, stored.parentPath) {
+				remaining = append(remaining, postId)
+				continue
+			}
+			if !post.post.IsDeleted {
+				result.Deleted++
+			}
+			delete(a.Storage.posts, postId)
+		}
+		a.Storage.threadPosts[threadId] = remaining
+	} else {
+		if !stored.post.IsDeleted {
+			stored.post.Message = ""
+			stored.post.IsDeleted = true
+			result.Deleted = 1
+			a.Storage.setMentions(id, 0, stored.post.Author, "")
+		}
+		post := stored.post
+		result.Post = &post
+	}
+
+	if forum, ok := a.Storage.forums[key(stored.post.Forum)]; ok {
+		forum.Posts -= result.Deleted
+	}
+
+	return result, nil
+}
+
+func (a *PostMemoryRepo) GetHistory(ctx context.Context, id int64) (*models.PostHistory, error) {
+	a.Storage.mu.RLock()
+	defer a.Storage.mu.RUnlock()
+
+	stored, ok := a.Storage.posts[id]
+	if !ok {
+		return nil, domain.ErrorPostDoesNotExist
+	}
+
+	post := stored.post
+	revisions := make([]models.PostRevision, len(stored.revisions))
+	copy(revisions, stored.revisions)
+
+	return &models.PostHistory{Post: &post, Revisions: revisions}, nil
+}
+
+// hasPrefix проверяет, что путь path лежит в поддереве с корнем по пути prefix
+func hasPrefix(path, prefix []int64) bool {
+	if len(path) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if path[i] != prefix[i] {
+			return false
+		}
+	}
+
+	return true
+}
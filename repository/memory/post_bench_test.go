@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"technopark-db-semester-project/domain/models"
+	"testing"
+)
+
+// benchPostBatchSizes совпадает с размерами из бенчмарка PostPostgresRepo.Create, чтобы результаты можно было сравнить
+var benchPostBatchSizes = []int{100, 999, 1000, 5000}
+
+func BenchmarkPostMemoryRepoCreate(b *testing.B) {
+	ctx := context.Background()
+	storage := NewStorage()
+	threads := NewThreadMemoryRepo(storage)
+	repo := NewPostMemoryRepo(storage)
+
+	if _, err := NewUserMemoryRepo(storage).Create(ctx, &models.User{Nickname: "bench", Fullname: "Bench", Email: "bench@example.com"}); err != nil {
+		b.Fatal("create user:", err)
+	}
+	if _, err := NewForumMemoryRepo(storage).Create(ctx, &models.ForumCreate{Title: "Bench", User: "bench", Slug: "bench"}); err != nil {
+		b.Fatal("create forum:", err)
+	}
+
+	for _, size := range benchPostBatchSizes {
+		// b.Run вызывает функцию несколько раз, подбирая b.N, поэтому ветка создаётся один раз на размер
+		thread, err := threads.Create(ctx, "bench", &models.ThreadCreate{Title: "Bench", Author: "bench", Message: "bench", Slug: fmt.Sprintf("bench-%d", size)})
+		if err != nil {
+			b.Fatal("create thread:", err)
+		}
+		slug := thread.Slug
+
+		posts := make([]models.PostCreate, size)
+		for ind := range posts {
+			posts[ind] = models.PostCreate{Author: "bench", Message: fmt.Sprintf("post %d", ind)}
+		}
+
+		b.Run(fmt.Sprintf("posts=%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := repo.Create(ctx, slug, &posts); err != nil {
+					b.Fatal("create posts:", err)
+				}
+			}
+			b.ReportMetric(float64(size), "posts/op")
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+)
+
+type RoleMemoryRepo struct {
+	Storage *Storage
+}
+
+func NewRoleMemoryRepo(storage *Storage) domain.RoleRepo {
+	return &RoleMemoryRepo{Storage: storage}
+}
+
+func (a *RoleMemoryRepo) Get(ctx context.Context, forumSlug string, nickname string) (string, error) {
+	a.Storage.mu.RLock()
+	defer a.Storage.mu.RUnlock()
+
+	forum, ok := a.Storage.forums[key(forumSlug)]
+	if !ok {
+		return "", domain.ErrorForumDoesNotExist
+	}
+	if key(forum.User) == key(nickname) {
+		return models.RoleOwner, nil
+	}
+	if role, ok := a.Storage.roles[key(forumSlug)][key(nickname)]; ok {
+		return role.Role, nil
+	}
+
+	return models.RoleMember, nil
+}
+
+func (a *RoleMemoryRepo) GetAll(ctx context.Context, forumSlug string) (*[]models.ForumRole, error) {
+	a.Storage.mu.RLock()
+	defer a.Storage.mu.RUnlock()
+
+	forum, ok := a.Storage.forums[key(forumSlug)]
+	if !ok {
+		return nil, domain.ErrorForumDoesNotExist
+	}
+
+	roles := []models.ForumRole{{Nickname: forum.User, Role: models.RoleOwner}}
+	for _, role := range a.Storage.roles[key(forumSlug)] {
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(i, j int) bool {
+		return roles[i].Nickname < roles[j].Nickname
+	})
+
+	return &roles, nil
+}
+
+func (a *RoleMemoryRepo) Set(ctx context.Context, forumSlug string, role *models.ForumRole) (*models.ForumRole, error) {
+	a.Storage.mu.Lock()
+	defer a.Storage.mu.Unlock()
+
+	if _, ok := a.Storage.forums[key(forumSlug)]; !ok {
+		return nil, domain.ErrorForumDoesNotExist
+	}
+	user, ok := a.Storage.getUser(role.Nickname)
+	if !ok {
+		return nil, domain.ErrorUserDoesNotExist
+	}
+
+	roles, ok := a.Storage.roles[key(forumSlug)]
+	if !ok {
+		roles = make(map[string]models.ForumRole)
+		a.Storage.roles[key(forumSlug)] = roles
+	}
+	stored := models.ForumRole{Nickname: user.Nickname, Role: role.Role}
+	roles[key(user.Nickname)] = stored
+
+	return &stored, nil
+}
@@ -0,0 +1,195 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+	"unicode"
+)
+
+// SearchMemoryRepo - упрощённый поиск: все слова запроса должны встретиться в тексте,
+// релевантность - число вхождений (слова заголовка ветки считаются дважды)
+type SearchMemoryRepo struct {
+	Storage *Storage
+}
+
+func NewSearchMemoryRepo(storage *Storage) domain.SearchRepo {
+	return &SearchMemoryRepo{Storage: storage}
+}
+
+func splitWords(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func countWords(text string) map[string]int {
+	counts := make(map[string]int)
+	for _, word := range splitWords(strings.ToLower(text)) {
+		counts[word]++
+	}
+
+	return counts
+}
+
+// countTerms возвращает число вхождений слов запроса или 0, если хотя бы одно слово не встретилось
+func countTerms(text string, terms []string) int {
+	counts := countWords(text)
+
+	total := 0
+	for _, term := range terms {
+		if counts[term] == 0 {
+			return 0
+		}
+		total += counts[term]
+	}
+
+	return total
+}
+
+// countAnyTerms возвращает число вхождений слов запроса, не требуя, чтобы встретились все
+func countAnyTerms(text string, terms []string) int {
+	counts := countWords(text)
+
+	total := 0
+	for _, term := range terms {
+		total += counts[term]
+	}
+
+	return total
+}
+
+func highlight(text string, terms []string) string {
+	isTerm := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		isTerm[term] = true
+	}
+
+	result := strings.Builder{}
+	word := strings.Builder{}
+	flush := func() {
+		if word.Len() == 0 {
+			return
+		}
+		if isTerm[strings.ToLower(word.String())] {
+			result.WriteString("<b>" + word.String() + "</b>")
+		} else {
+			result.WriteString(word.String())
+		}
+		word.Reset()
+	}
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			word.WriteRune(r)
+			continue
+		}
+		flush()
+		result.WriteRune(r)
+	}
+	flush()
+
+	return result.String()
+}
+
+// searchLess сравнивает записи в порядке выдачи: все ключи по убыванию
+func searchLess(first *models.SearchHit, second *models.SearchHit, sortBy string) bool {
+	if sortBy != models.SearchSortDate && first.Rank != second.Rank {
+		return first.Rank > second.Rank
+	}
+	if !first.Created.Equal(second.Created) {
+		return first.Created.After(second.Created)
+	}
+	if first.Id != second.Id {
+		return first.Id > second.Id
+	}
+	return first.Type > second.Type
+}
+
+func (a *SearchMemoryRepo) Search(ctx context.Context, request *models.SearchRequest) (*models.SearchResult, error) {
+	terms := splitWords(strings.ToLower(request.Query))
+	if len(terms) == 0 {
+		return nil, domain.ErrorEmptySearchQuery
+	}
+
+	a.Storage.mu.RLock()
+	defer a.Storage.mu.RUnlock()
+
+	if request.Forum != "" {
+		if _, ok := a.Storage.forums[key(request.Forum)]; !ok {
+			return nil, domain.ErrorForumDoesNotExist
+		}
+	}
+	var threadId int32
+	if request.Thread != "" {
+		thread, err := a.Storage.getThread(request.Thread)
+		if err != nil {
+			return nil, err
+		}
+		threadId = thread.Id
+	}
+
+	matchesScope := func(forum string, thread int32, author string) bool {
+		return (request.Forum == "" || key(forum) == key(request.Forum)) &&
+			(threadId == 0 || thread == threadId) &&
+			(request.Author == "" || key(author) == key(request.Author))
+	}
+
+	hits := make([]models.SearchHit, 0)
+	if request.Type == "" || request.Type == models.SearchTypePost {
+		for _, stored := range a.Storage.posts {
+			post := &stored.post
+			if !matchesScope(post.Forum, post.Thread, post.Author) {
+				continue
+			}
+			if rank := countTerms(post.Message, terms); rank > 0 {
+				hits = append(hits, models.SearchHit{Type: models.SearchTypePost, Id: post.Id, Thread: post.Thread, Forum: post.Forum, Author: post.Author,
+					Snippet: highlight(post.Message, terms), Rank: float32(rank), Created: post.Created})
+			}
+		}
+	}
+	if request.Type == "" || request.Type == models.SearchTypeThread {
+		for _, thread := range a.Storage.threads {
+			if !matchesScope(thread.Forum, thread.Id, thread.Author) {
+				continue
+			}
+			if rank := countTerms(thread.Title+" "+thread.Message, terms); rank > 0 {
+				rank += countAnyTerms(thread.Title, terms)
+				hits = append(hits, models.SearchHit{Type: models.SearchTypeThread, Id: int64(thread.Id), Thread: thread.Id, Forum: thread.Forum, Author: thread.Author,
+					Title: thread.Title, Snippet: highlight(thread.Message, terms), Rank: float32(rank), Created: thread.Created})
+			}
+		}
+	}
+
+	filtered := hits[:0]
+	for ind := range hits {
+		hit := &hits[ind]
+		if request.Since != nil && hit.Created.Before(*request.Since) {
+			continue
+		}
+		if request.Until != nil && hit.Created.After(*request.Until) {
+			continue
+		}
+		if request.Cursor != nil {
+			cursor := &models.SearchHit{Rank: request.Cursor.Rank, Created: request.Cursor.Created, Id: request.Cursor.Id, Type: request.Cursor.Type}
+			if !searchLess(cursor, hit, request.Sort) {
+				continue
+			}
+		}
+		filtered = append(filtered, *hit)
+	}
+
+	sort.Slice(filtered, func(i, j int) bool {
+		return searchLess(&filtered[i], &filtered[j], request.Sort)
+	})
+
+	result := &models.SearchResult{Hits: filtered}
+	if len(filtered) > int(request.Limit) {
+		result.Hits = filtered[:request.Limit]
+		last := result.Hits[len(result.Hits)-1]
+		result.Next = &models.SearchCursor{Rank: last.Rank, Created: last.Created, Id: last.Id, Type: last.Type}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"context"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+)
+
+type ServiceMemoryRepo struct {
+	Storage *Storage
+}
+
+func NewServiceMemoryRepo(storage *Storage) domain.ServiceRepo {
+	return &ServiceMemoryRepo{Storage: storage}
+}
+
+func (a *ServiceMemoryRepo) GetInfo(ctx context.Context) (*models.Service, error) {
+	a.Storage.mu.RLock()
+	defer a.Storage.mu.RUnlock()
+
+	var posts int64
+	for _, stored := range a.Storage.posts {
+		if !stored.post.IsDeleted {
+			posts++
+		}
+	}
+
+	return &models.Service{
+		User:   int32(len(a.Storage.users)),
+		Forum:  int32(len(a.Storage.forums)),
+		Thread: int32(len(a.Storage.threads)),
+		Post:   posts,
+	}, nil
+}
+
+func (a *ServiceMemoryRepo) Clear(ctx context.Context) error {
+	a.Storage.mu.Lock()
+	defer a.Storage.mu.Unlock()
+
+	a.Storage.reset()
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package memory
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+	"time"
+)
+
+// Storage - общее хранилище всех in-memory репозиториев. Повторяет схему db/db.sql:
+// ники, email и slug'и сравниваются без учёта регистра (citext), счётчики форумов и ForumUsers обновляются как триггерами
+type Storage struct {
+	mu sync.RWMutex
+
+	users  map[string]*models.User // ключ - ник в нижнем регистре
+	emails map[string]string       // email в нижнем регистре -> ключ пользователя
+
+	forums     map[string]*models.Forum          // ключ - slug в нижнем регистре
+	forumUsers map[string]map[string]models.User // slug форума -> ключ пользователя -> копия пользователя на момент добавления
+
+	threads      map[int32]*models.Thread
+	threadSlugs  map[string]int32 // slug в нижнем регистре -> id ветки
+	lastThreadId int32
+
+	posts       map[int64]*storedPost
+	threadPosts map[int32][]int64 // id ветки -> id постов в порядке создания
+	lastPostId  int64
+
+	votes map[voteKey]int32
+
+	roles map[string]map[string]models.ForumRole // slug форума -> ключ пользователя -> назначенная роль
+
+	sessions       map[string]models.Session       // sha256 токена -> сессия
+	passwordResets map[string]models.PasswordReset // sha256 токена -> сброс пароля
+
+	threadSubscriptions map[int32]map[string]string      // id ветки -> ключ пользователя -> ник
+	forumSubscriptions  map[string]map[string]string     // slug форума в нижнем регистре -> ключ пользователя -> ник
+	notifications       map[string][]*storedNotification // ключ пользователя -> уведомления в порядке id
+	lastNotificationId  int64
+
+	mentions      []*storedMention // в порядке id
+	lastMentionId int64
+}
+
+type storedPost struct {
+	post       models.Post
+	parentPath []int64
+	revisions  []models.PostRevision
+	votes      map[string]int32 // ключ пользователя -> голос за пост
+}
+
+// storedNotification и storedMention ссылаются на пост или, если post = 0, на текст ветки thread
+type storedNotification struct {
+	id     int64
+	kind   string
+	post   int64
+	thread int32
+	read   bool
+}
+
+type storedMention struct {
+	id       int64
+	nickname string
+	post     int64
+	thread   int32
+	created  time.Time
+}
+
+type voteKey struct {
+	nickname string
+	thread   int32
+}
+
+func NewStorage() *Storage {
+	storage := &Storage{}
+	storage.reset()
+
+	return storage
+}
+
+func (a *Storage) reset() {
+	a.users = make(map[string]*models.User)
+	a.emails = make(map[string]string)
+	a.forums = make(map[string]*models.Forum)
+	a.forumUsers = make(map[string]map[string]models.User)
+	a.threads = make(map[int32]*models.Thread)
+	a.threadSlugs = make(map[string]int32)
+	a.lastThreadId = 0
+	a.posts = make(map[int64]*storedPost)
+	a.threadPosts = make(map[int32][]int64)
+	a.lastPostId = 0
+	a.votes = make(map[voteKey]int32)
+	a.roles = make(map[string]map[string]models.ForumRole)
+	a.sessions = make(map[string]models.Session)
+	a.passwordResets = make(map[string]models.PasswordReset)
+	a.threadSubscriptions = make(map[int32]map[string]string)
+	a.forumSubscriptions = make(map[string]map[string]string)
+	a.notifications = make(map[string][]*storedNotification)
+	a.lastNotificationId = 0
+	a.mentions = make([]*storedMention, 0)
+	a.lastMentionId = 0
+}
+
+func key(value string) string {
+	return strings.ToLower(value)
+}
+
+// getUser ищет пользователя по нику, вызывать под блокировкой
+func (a *Storage) getUser(nickname string) (*models.User, bool) {
+	user, ok := a.users[key(nickname)]
+	return user, ok
+}
+
+// getThread ищет ветку по slug или id, вызывать под блокировкой
+func (a *Storage) getThread(slugOrId string) (*models.Thread, error) {
+	id, err := strconv.Atoi(slugOrId)
+	if err != nil {
+		threadId, ok := a.threadSlugs[key(slugOrId)]
+		if !ok {
+			return nil, domain.ErrorThreadDoesNotExist
+		}
+		id = int(threadId)
+	}
+
+	thread, ok := a.threads[int32(id)]
+	if !ok {
+		return nil, domain.ErrorThreadDoesNotExist
+	}
+
+	return thread, nil
+}
+
+// addForumUser - аналог триггера forum_users_update, вызывать под блокировкой
+func (a *Storage) addForumUser(forumSlug string, nickname string) {
+	user, ok := a.getUser(nickname)
+	if !ok {
+		return
+	}
+
+	users, ok := a.forumUsers[key(forumSlug)]
+	if !ok {
+		users = make(map[string]models.User)
+		a.forumUsers[key(forumSlug)] = users
+	}
+
+	if _, ok = users[key(nickname)]; !ok {
+		users[key(nickname)] = *user
+	}
+}
+
+// removeForumUsers убирает из ForumUsers форума тех из nicknames, у кого там не осталось ни веток, ни постов,
+// вызывать под блокировкой
+func (a *Storage) removeForumUsers(forumSlug string, nicknames []string) {
+	users, ok := a.forumUsers[key(forumSlug)]
+	if !ok {
+		return
+	}
+
+	stale := make(map[string]bool, len(nicknames))
+	for _, nickname := range nicknames {
+		stale[key(nickname)] = true
+	}
+	for _, thread := range a.threads {
+		if key(thread.Forum) == key(forumSlug) {
+			delete(stale, key(thread.Author))
+		}
+	}
+	for _, stored := range a.posts {
+		if key(stored.post.Forum) == key(forumSlug) {
+			delete(stale, key(stored.post.Author))
+		}
+	}
+
+	for nickname := range stale {
+		delete(users, nickname)
+	}
+}
+
+// isBanned проверяет, забанен ли пользователь в форуме, вызывать под блокировкой
+func (a *Storage) isBanned(forumSlug string, nickname string) bool {
+	role, ok := a.roles[key(forumSlug)][key(nickname)]
+	return ok && role.Role == models.RoleBanned
+}
+
+// compareCreated сравнивает ключи (created, id), как сравнение строк в postgres
+func compareCreated(firstCreated time.Time, firstId int64, secondCreated time.Time, secondId int64) int {
+	switch {
+	case firstCreated.Before(secondCreated):
+		return -1
+	case firstCreated.After(secondCreated):
+		return 1
+	case firstId < secondId:
+		return -1
+	case firstId > secondId:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// comparePaths сравнивает parent_path так же, как postgres сравнивает массивы
+func comparePaths(first []int64, second []int64) int {
+	for ind := 0; ind < len(first) && ind < len(second); ind++ {
+		if first[ind] < second[ind] {
+			return -1
+		}
+		if first[ind] > second[ind] {
+			return 1
+		}
+	}
+
+	switch {
+	case len(first) < len(second):
+		return -1
+	case len(first) > len(second):
+		return 1
+	default:
+		return 0
+	}
+}
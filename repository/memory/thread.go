@@ -0,0 +1,557 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+)
+
+type ThreadMemoryRepo struct {
+	Storage *Storage
+}
+
+func NewThreadMemoryRepo(storage *Storage) domain.ThreadRepo {
+	return &ThreadMemoryRepo{Storage: storage}
+}
+
+func (a *ThreadMemoryRepo) Create(ctx context.Context, forumSlug string, thread *models.ThreadCreate) (*models.Thread, error) {
+	a.Storage.mu.Lock()
+	defer a.Storage.mu.Unlock()
+
+	forum, ok := a.Storage.forums[key(forumSlug)]
+	if !ok {
+		return nil, domain.ErrorNoAuthorOrForum
+	}
+	if _, ok = a.Storage.getUser(thread.Author); !ok {
+		return nil, domain.ErrorNoAuthorOrForum
+	}
+	thread.Forum = forum.Slug
+
+	if a.Storage.isBanned(forum.Slug, thread.Author) {
+		return nil, domain.ErrorUserBanned
+	}
+
+	if thread.Slug != "" {
+		if id, ok := a.Storage.threadSlugs[key(thread.Slug)]; ok {
+			threadAlreadyExist := *a.Storage.threads[id]
+			return &threadAlreadyExist, domain.ErrorThreadAlreadyExist
+		}
+	}
+
+	a.Storage.lastThreadId++
+	created := &models.Thread{
+		Id:      a.Storage.lastThreadId,
+		Title:   thread.Title,
+		Author:  thread.Author,
+		Forum:   thread.Forum,
+		Message: thread.Message,
+		Votes:   0,
+		Slug:    thread.Slug,
+		Created: thread.Created,
+		State:   models.ThreadOpen,
+	}
+	a.Storage.threads[created.Id] = created
+	if thread.Slug != "" {
+		a.Storage.threadSlugs[key(thread.Slug)] = created.Id
+	}
+
+	forum.Threads++
+	a.Storage.addForumUser(forum.Slug, thread.Author)
+	a.Storage.notifyMentioned(0, created.Id, a.Storage.setMentions(0, created.Id, created.Author, created.Message))
+
+	threadToReturn := *created
+
+	return &threadToReturn, nil
+}
+
+func (a *ThreadMemoryRepo) Get(ctx context.Context, threadSlugOrId string) (*models.Thread, error) {
+	a.Storage.mu.RLock()
+	defer a.Storage.mu.RUnlock()
+
+	thread, err := a.Storage.getThread(threadSlugOrId)
+	if err != nil {
+		return nil, err
+	}
+
+	threadToReturn := *thread
+
+	return &threadToReturn, nil
+}
+
+func (a *ThreadMemoryRepo) Update(ctx context.Context, threadSlugOrId string, updateData *models.ThreadUpdate) (*models.Thread, error) {
+	a.Storage.mu.Lock()
+	defer a.Storage.mu.Unlock()
+
+	thread, err := a.Storage.getThread(threadSlugOrId)
+	if err != nil {
+		return nil, err
+	}
+
+	if updateData.Message != "" && updateData.Message != thread.Message {
+		thread.Message = updateData.Message
+		a.Storage.notifyMentioned(0, thread.Id, a.Storage.setMentions(0, thread.Id, thread.Author, thread.Message))
+	}
+	if updateData.Title != "" {
+		thread.Title = updateData.Title
+	}
+
+	threadToReturn := *thread
+
+	return &threadToReturn, nil
+}
+
+func (a *ThreadMemoryRepo) SetState(ctx context.Context, threadSlugOrId string, stateUpdate *models.ThreadStateUpdate) (*models.Thread, error) {
+	a.Storage.mu.Lock()
+	defer a.Storage.mu.Unlock()
+
+	thread, err := a.Storage.getThread(threadSlugOrId)
+	if err != nil {
+		return nil, err
+	}
+	thread.State = stateUpdate.State
+
+	threadToReturn := *thread
+
+	return &threadToReturn, nil
+}
+
+func (a *ThreadMemoryRepo) SetPin(ctx context.Context, threadSlugOrId string, pin *models.ThreadPin) (*models.Thread, error) {
+	a.Storage.mu.Lock()
+	defer a.Storage.mu.Unlock()
+
+	thread, err := a.Storage.getThread(threadSlugOrId)
+	if err != nil {
+		return nil, err
+	}
+	thread.Pinned = pin.Pinned
+	thread.Announcement = pin.Announcement
+
+	threadToReturn := *thread
+
+	return &threadToReturn, nil
+}
+
+func (a *ThreadMemoryRepo) GetPosts(ctx context.Context, slugOrId string, getSettings *models.ThreadPostRequest) (*[]models.Post, error) {
+	a.Storage.mu.RLock()
+	defer a.Storage.mu.RUnlock()
+
+	thread, err := a.Storage.getThread(slugOrId)
+	if err != nil {
+		return nil, err
+	}
+
+	// в курсоре parent_tree лежит id корневого поста, а в курсоре score - id поста, для них он работает как since
+	settings := *getSettings
+	if settings.After != nil && (settings.Sort == models.ParentTree || settings.Sort == models.Score) {
+		settings.Since, settings.After = settings.After.Id, nil
+	}
+	getSettings = &settings
+
+	var selected []*storedPost
+	switch getSettings.Sort {
+	case models.Flat:
+		selected = a.flat(thread.Id, getSettings)
+	case models.Tree:
+		selected = a.tree(thread.Id, getSettings)
+	case models.ParentTree:
+		selected = a.parentTree(thread.Id, getSettings)
+	case models.Score:
+		selected = a.score(thread.Id, getSettings)
+	}
+
+	posts := make([]models.Post, 0, len(selected))
+	for _, post := range selected {
+		item := post.post
+		item.Path = append([]int64(nil), post.parentPath...)
+		posts = append(posts, item)
+	}
+
+	return &posts, nil
+}
+
+func (a *ThreadMemoryRepo) threadPosts(threadId int32) []*storedPost {
+	ids := a.Storage.threadPosts[threadId]
+	posts := make([]*storedPost, 0, len(ids))
+	for _, id := range ids {
+		posts = append(posts, a.Storage.posts[id])
+	}
+
+	return posts
+}
+
+func limitPosts(posts []*storedPost, limit int32) []*storedPost {
+	if int(limit) < len(posts) {
+		return posts[:limit]
+	}
+
+	return posts
+}
+
+// flat - сортировка по (created, id), since сравнивается с id, after - с (created, id)
+func (a *ThreadMemoryRepo) flat(threadId int32, getSettings *models.ThreadPostRequest) []*storedPost {
+	posts := make([]*storedPost, 0)
+	for _, post := range a.threadPosts(threadId) {
+		if after := getSettings.After; after != nil {
+			cmp := compareCreated(post.post.Created, post.post.Id, after.Created, after.Id)
+			if getSettings.Desc && cmp >= 0 {
+				continue
+			}
+			if !getSettings.Desc && cmp <= 0 {
+				continue
+			}
+		} else if getSettings.Since != -1 {
+			if getSettings.Desc && post.post.Id >= getSettings.Since {
+				continue
+			}
+			if !getSettings.Desc && post.post.Id <= getSettings.Since {
+				continue
+			}
+		}
+		posts = append(posts, post)
+	}
+
+	sort.SliceStable(posts, func(i, j int) bool {
+		first, second := posts[i].post, posts[j].post
+		if !first.Created.Equal(second.Created) {
+			return first.Created.Before(second.Created) != getSettings.Desc
+		}
+		return (first.Id < second.Id) != getSettings.Desc
+	})
+
+	return limitPosts(posts, getSettings.Limit)
+}
+
+// tree - сортировка по parent_path, since сравнивается с parent_path указанного поста, after - с переданным путём
+func (a *ThreadMemoryRepo) tree(threadId int32, getSettings *models.ThreadPostRequest) []*storedPost {
+	var sincePath []int64
+	if getSettings.After != nil {
+		sincePath = getSettings.After.Path
+	} else if getSettings.Since != -1 {
+		sincePost, ok := a.Storage.posts[getSettings.Since]
+		if !ok {
+			return nil
+		}
+		sincePath = sincePost.parentPath
+	}
+
+	posts := make([]*storedPost, 0)
+	for _, post := range a.threadPosts(threadId) {
+		if sincePath != nil {
+			cmp := comparePaths(post.parentPath, sincePath)
+			if getSettings.Desc && cmp >= 0 {
+				continue
+			}
+			if !getSettings.Desc && cmp <= 0 {
+				continue
+			}
+		}
+		posts = append(posts, post)
+	}
+
+	sort.Slice(posts, func(i, j int) bool {
+		cmp := comparePaths(posts[i].parentPath, posts[j].parentPath)
+		if getSettings.Desc {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+
+	return limitPosts(posts, getSettings.Limit)
+}
+
+// parentTree - limit и since относятся к корневым постам, внутри каждого дерева посты идут по parent_path
+func (a *ThreadMemoryRepo) parentTree(threadId int32, getSettings *models.ThreadPostRequest) []*storedPost {
+	withSince := getSettings.Since != -1
+	if getSettings.Desc {
+		withSince = getSettings.Since > 0
+	}
+
+	var sinceRoot int64
+	if withSince {
+		sincePost, ok := a.Storage.posts[getSettings.Since]
+		if !ok {
+			return nil
+		}
+		sinceRoot = sincePost.parentPath[0]
+	}
+
+	posts := a.threadPosts(threadId)
+	roots := make([]int64, 0)
+	for _, post := range posts {
+		if post.post.Parent != 0 {
+			continue
+		}
+		if withSince {
+			if getSettings.Desc && post.post.Id >= sinceRoot {
+				continue
+			}
+			if !getSettings.Desc && post.post.Id <= sinceRoot {
+				continue
+			}
+		}
+		roots = append(roots, post.post.Id)
+	}
+
+	sort.Slice(roots, func(i, j int) bool {
+		return (roots[i] < roots[j]) != getSettings.Desc
+	})
+	if int(getSettings.Limit) < len(roots) {
+		roots = roots[:getSettings.Limit]
+	}
+
+	rootOrder := make(map[int64]int, len(roots))
+	for ind, root := range roots {
+		rootOrder[root] = ind
+	}
+
+	selected := make([]*storedPost, 0)
+	for _, post := range posts {
+		if _, ok := rootOrder[post.parentPath[0]]; ok {
+			selected = append(selected, post)
+		}
+	}
+
+	sort.Slice(selected, func(i, j int) bool {
+		firstRoot, secondRoot := rootOrder[selected[i].parentPath[0]], rootOrder[selected[j].parentPath[0]]
+		if firstRoot != secondRoot {
+			return firstRoot < secondRoot
+		}
+		return comparePaths(selected[i].parentPath, selected[j].parentPath) < 0
+	})
+
+	return selected
+}
+
+// scorePath - путь из пар (±votes, id) от корня до поста, как score_path в GetPostsOnThreadScoreCommand
+func (a *ThreadMemoryRepo) scorePath(post *storedPost, direction int64) []int64 {
+	path := make([]int64, 0, 2*len(post.parentPath))
+	for _, id := range post.parentPath {
+		path = append(path, direction*int64(a.Storage.posts[id].post.Votes), id)
+	}
+
+	return path
+}
+
+// score - дерево, в котором ответы одного уровня идут по возрастанию рейтинга (по убыванию при desc), since - id поста
+func (a *ThreadMemoryRepo) score(threadId int32, getSettings *models.ThreadPostRequest) []*storedPost {
+	direction := int64(1)
+	if getSettings.Desc {
+		direction = -1
+	}
+
+	posts := a.threadPosts(threadId)
+	paths := make(map[int64][]int64, len(posts))
+	for _, post := range posts {
+		paths[post.post.Id] = a.scorePath(post, direction)
+	}
+
+	sincePath := paths[getSettings.Since]
+	selected := make([]*storedPost, 0, len(posts))
+	for _, post := range posts {
+		if comparePaths(paths[post.post.Id], sincePath) > 0 {
+			selected = append(selected, post)
+		}
+	}
+
+	sort.Slice(selected, func(i, j int) bool {
+		return comparePaths(paths[selected[i].post.Id], paths[selected[j].post.Id]) < 0
+	})
+
+	return limitPosts(selected, getSettings.Limit)
+}
+
+func (a *ThreadMemoryRepo) Move(ctx context.Context, threadSlugOrId string, move *models.ThreadMove) (*models.Thread, error) {
+	a.Storage.mu.Lock()
+	defer a.Storage.mu.Unlock()
+
+	thread, err := a.Storage.getThread(threadSlugOrId)
+	if err != nil {
+		return nil, err
+	}
+	forum, ok := a.Storage.forums[key(move.Forum)]
+	if !ok {
+		return nil, domain.ErrorForumDoesNotExist
+	}
+
+	oldForum := a.Storage.forums[key(thread.Forum)]
+	if forum == oldForum {
+		threadToReturn := *thread
+		return &threadToReturn, nil
+	}
+
+	authors := []string{thread.Author}
+	var posts int64
+	for _, postId := range a.Storage.threadPosts[thread.Id] {
+		stored := a.Storage.posts[postId]
+		stored.post.Forum = forum.Slug
+		if !stored.post.IsDeleted {
+			posts++
+		}
+		authors = append(authors, stored.post.Author)
+	}
+	thread.Forum = forum.Slug
+
+	oldForum.Threads--
+	oldForum.Posts -= posts
+	forum.Threads++
+	forum.Posts += posts
+	for _, author := range authors {
+		a.Storage.addForumUser(forum.Slug, author)
+	}
+	a.Storage.removeForumUsers(oldForum.Slug, authors)
+
+	threadToReturn := *thread
+
+	return &threadToReturn, nil
+}
+
+func (a *ThreadMemoryRepo) Merge(ctx context.Context, threadSlugOrId string, merge *models.ThreadMerge) (*models.Thread, error) {
+	a.Storage.mu.Lock()
+	defer a.Storage.mu.Unlock()
+
+	source, err := a.Storage.getThread(threadSlugOrId)
+	if err != nil {
+		return nil, err
+	}
+	target, err := a.Storage.getThread(merge.Into)
+	if err != nil {
+		return nil, err
+	}
+	if source.Id == target.Id {
+		return nil, domain.ErrorMergeSameThread
+	}
+
+	sourceForum := a.Storage.forums[key(source.Forum)]
+	targetForum := a.Storage.forums[key(target.Forum)]
+
+	// текст ветки становится корневым постом, корневые посты ветки - ответами на него
+	a.Storage.lastPostId++
+	root := &storedPost{
+		post: models.Post{
+			Id:      a.Storage.lastPostId,
+			Author:  source.Author,
+			Message: source.Message,
+			Forum:   target.Forum,
+			Thread:  target.Id,
+			Created: source.Created,
+		},
+		parentPath: []int64{a.Storage.lastPostId},
+	}
+	a.Storage.posts[root.post.Id] = root
+	a.Storage.moveMentions(0, source.Id, root.post.Id, 0)
+	a.Storage.threadPosts[target.Id] = append(a.Storage.threadPosts[target.Id], root.post.Id)
+	targetForum.Posts++
+	a.Storage.addForumUser(target.Forum, source.Author)
+
+	authors := []string{source.Author}
+	for _, postId := range a.Storage.threadPosts[source.Id] {
+		stored := a.Storage.posts[postId]
+		if stored.post.Parent == 0 {
+			stored.post.Parent = root.post.Id
+		}
+		stored.post.Thread = target.Id
+		stored.post.Forum = target.Forum
+		stored.parentPath = append([]int64{root.post.Id}, stored.parentPath...)
+		if !stored.post.IsDeleted {
+			sourceForum.Posts--
+			targetForum.Posts++
+		}
+		a.Storage.addForumUser(target.Forum, stored.post.Author)
+		authors = append(authors, stored.post.Author)
+	}
+	a.Storage.threadPosts[target.Id] = append(a.Storage.threadPosts[target.Id], a.Storage.threadPosts[source.Id]...)
+
+	for vote := range a.Storage.votes {
+		if vote.thread == source.Id {
+			delete(a.Storage.votes, vote)
+		}
+	}
+	// подписчики ветки становятся подписчиками target
+	for subscriberKey, nickname := range a.Storage.threadSubscriptions[source.Id] {
+		subscribers, ok := a.Storage.threadSubscriptions[target.Id]
+		if !ok {
+			subscribers = make(map[string]string)
+			a.Storage.threadSubscriptions[target.Id] = subscribers
+		}
+		subscribers[subscriberKey] = nickname
+	}
+	delete(a.Storage.threadSubscriptions, source.Id)
+	delete(a.Storage.threadPosts, source.Id)
+	delete(a.Storage.threads, source.Id)
+	if source.Slug != "" {
+		delete(a.Storage.threadSlugs, key(source.Slug))
+	}
+	sourceForum.Threads--
+	a.Storage.removeForumUsers(sourceForum.Slug, authors)
+
+	threadToReturn := *target
+
+	return &threadToReturn, nil
+}
+
+func (a *ThreadMemoryRepo) Split(ctx context.Context, postId int64, split *models.ThreadSplit) (*models.Thread, error) {
+	a.Storage.mu.Lock()
+	defer a.Storage.mu.Unlock()
+
+	stored, ok := a.Storage.posts[postId]
+	if !ok {
+		return nil, domain.ErrorPostDoesNotExist
+	}
+
+	if split.Slug != "" {
+		if id, ok := a.Storage.threadSlugs[key(split.Slug)]; ok {
+			threadAlreadyExist := *a.Storage.threads[id]
+			return &threadAlreadyExist, domain.ErrorThreadAlreadyExist
+		}
+	}
+
+	forum := a.Storage.forums[key(stored.post.Forum)]
+	a.Storage.lastThreadId++
+	thread := &models.Thread{
+		Id:      a.Storage.lastThreadId,
+		Title:   split.Title,
+		Author:  stored.post.Author,
+		Forum:   stored.post.Forum,
+		Message: stored.post.Message,
+		Slug:    split.Slug,
+		Created: stored.post.Created,
+		State:   models.ThreadOpen,
+	}
+	a.Storage.threads[thread.Id] = thread
+	if split.Slug != "" {
+		a.Storage.threadSlugs[key(split.Slug)] = thread.Id
+	}
+	forum.Threads++
+
+	// ответы на пост становятся корневыми постами новой ветки, а parent_path поддерева теряет общий префикс
+	prefix := stored.parentPath
+	oldThread := stored.post.Thread
+	remaining := make([]int64, 0, len(a.Storage.threadPosts[oldThread]))
+	for _, id := range a.Storage.threadPosts[oldThread] {
+		post := a.Storage.posts[id]
+		if id == postId || !hasPrefix(post.parentPath, prefix) {
+			if id != postId {
+				remaining = append(remaining, id)
+			}
+			continue
+		}
+		if post.post.Parent == postId {
+			post.post.Parent = 0
+		}
+		post.post.Thread = thread.Id
+		post.parentPath = post.parentPath[len(prefix):]
+		a.Storage.threadPosts[thread.Id] = append(a.Storage.threadPosts[thread.Id], id)
+	}
+	a.Storage.threadPosts[oldThread] = remaining
+
+	a.Storage.moveMentions(postId, 0, 0, thread.Id)
+	delete(a.Storage.posts, postId)
+	if !stored.post.IsDeleted {
+		forum.Posts--
+	}
+
+	threadToReturn := *thread
+
+	return &threadToReturn, nil
+}
@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"context"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+)
+
+type UserMemoryRepo struct {
+	Storage *Storage
+}
+
+func NewUserMemoryRepo(storage *Storage) domain.UserRepo {
+	return &UserMemoryRepo{Storage: storage}
+}
+
+func (a *UserMemoryRepo) Create(ctx context.Context, user *models.User) (*[]models.User, error) {
+	a.Storage.mu.Lock()
+	defer a.Storage.mu.Unlock()
+
+	conflicts := make([]models.User, 0, 2)
+	if existing, ok := a.Storage.getUser(user.Nickname); ok {
+		conflicts = append(conflicts, *existing)
+	}
+	if nicknameKey, ok := a.Storage.emails[key(user.Email)]; ok && nicknameKey != key(user.Nickname) {
+		conflicts = append(conflicts, *a.Storage.users[nicknameKey])
+	}
+	if len(conflicts) > 0 {
+		return &conflicts, domain.ErrorUserAlreadyExist
+	}
+
+	created := *user
+	a.Storage.users[key(user.Nickname)] = &created
+	a.Storage.emails[key(user.Email)] = key(user.Nickname)
+
+	userToReturn := make([]models.User, 0, 1)
+	userToReturn = append(userToReturn, *user)
+
+	return &userToReturn, nil
+}
+
+func (a *UserMemoryRepo) Update(ctx context.Context, nickname string, updateData *models.UserUpdate) (*models.User, error) {
+	a.Storage.mu.Lock()
+	defer a.Storage.mu.Unlock()
+
+	user, ok := a.Storage.getUser(nickname)
+	if !ok {
+		return nil, domain.ErrorUserDoesNotExist
+	}
+
+	if updateData.Email != "" && key(updateData.Email) != key(user.Email) {
+		if _, ok = a.Storage.emails[key(updateData.Email)]; ok {
+			return nil, domain.ErrorConflictUpdateUser
+		}
+		delete(a.Storage.emails, key(user.Email))
+		a.Storage.emails[key(updateData.Email)] = key(user.Nickname)
+		user.Email = updateData.Email
+	}
+	if updateData.Fullname != "" {
+		user.Fullname = updateData.Fullname
+	}
+	if updateData.About != "" {
+		user.About = updateData.About
+	}
+
+	userToReturn := *user
+
+	return &userToReturn, nil
+}
+
+func (a *UserMemoryRepo) Get(ctx context.Context, nicknameOrEmail string) (*models.User, error) {
+	a.Storage.mu.RLock()
+	defer a.Storage.mu.RUnlock()
+
+	user, ok := a.Storage.getUser(nicknameOrEmail)
+	if !ok {
+		nicknameKey, found := a.Storage.emails[key(nicknameOrEmail)]
+		if !found {
+			return nil, domain.ErrorUserDoesNotExist
+		}
+		user = a.Storage.users[nicknameKey]
+	}
+
+	userToReturn := *user
+
+	return &userToReturn, nil
+}
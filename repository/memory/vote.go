@@ -0,0 +1,176 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+)
+
+type VoteMemoryRepo struct {
+	Storage *Storage
+}
+
+func NewVoteMemoryRepo(storage *Storage) domain.VoteRepo {
+	return &VoteMemoryRepo{Storage: storage}
+}
+
+func (a *VoteMemoryRepo) Create(ctx context.Context, threadSlugOrId string, vote *models.VoteCreate) (*models.Thread, error) {
+	a.Storage.mu.Lock()
+	defer a.Storage.mu.Unlock()
+
+	thread, err := a.Storage.getThread(threadSlugOrId)
+	if err != nil {
+		return nil, err
+	}
+	if err = domain.CheckWritable(thread.State, true); err != nil {
+		return nil, err
+	}
+	if a.Storage.isBanned(thread.Forum, vote.Nickname) {
+		return nil, domain.ErrorUserBanned
+	}
+
+	voteId := voteKey{nickname: key(vote.Nickname), thread: thread.Id}
+	if *vote.Voice == 0 {
+		// отзыв голоса
+		thread.Votes -= a.Storage.votes[voteId]
+		delete(a.Storage.votes, voteId)
+
+		threadToReturn := *thread
+		return &threadToReturn, nil
+	}
+	if oldVoice, ok := a.Storage.votes[voteId]; ok {
+		// пользователь учитывается один раз: повторный голос заменяет предыдущий
+		thread.Votes += *vote.Voice - oldVoice
+	} else {
+		if _, ok = a.Storage.getUser(vote.Nickname); !ok {
+			return nil, domain.ErrorUserDoesNotExist
+		}
+		thread.Votes += *vote.Voice
+	}
+	a.Storage.votes[voteId] = *vote.Voice
+
+	threadToReturn := *thread
+
+	return &threadToReturn, nil
+}
+
+func (a *VoteMemoryRepo) CreateForPost(ctx context.Context, postId int64, vote *models.VoteCreate) (*models.Post, error) {
+	a.Storage.mu.Lock()
+	defer a.Storage.mu.Unlock()
+
+	stored, ok := a.Storage.posts[postId]
+	if !ok {
+		return nil, domain.ErrorPostDoesNotExist
+	}
+	if stored.post.IsDeleted {
+		return nil, domain.ErrorPostIsDeleted
+	}
+	if err := domain.CheckWritable(a.Storage.threads[stored.post.Thread].State, true); err != nil {
+		return nil, err
+	}
+	if a.Storage.isBanned(stored.post.Forum, vote.Nickname) {
+		return nil, domain.ErrorUserBanned
+	}
+	if _, ok = a.Storage.getUser(vote.Nickname); !ok {
+		return nil, domain.ErrorUserDoesNotExist
+	}
+
+	if *vote.Voice == 0 {
+		stored.post.Votes -= stored.votes[key(vote.Nickname)]
+		delete(stored.votes, key(vote.Nickname))
+
+		postToReturn := stored.post
+		return &postToReturn, nil
+	}
+	if stored.votes == nil {
+		stored.votes = make(map[string]int32)
+	}
+	voter := key(vote.Nickname)
+	stored.post.Votes += *vote.Voice - stored.votes[voter]
+	stored.votes[voter] = *vote.Voice
+
+	postToReturn := stored.post
+
+	return &postToReturn, nil
+}
+
+// votedUser возвращает ник проголосовавшего в исходном регистре
+func (a *VoteMemoryRepo) votedUser(nicknameKey string) string {
+	if user, ok := a.Storage.getUser(nicknameKey); ok {
+		return user.Nickname
+	}
+
+	return nicknameKey
+}
+
+func (a *VoteMemoryRepo) GetByThread(ctx context.Context, threadSlugOrId string, getSettings *models.GetThreadVotes) (*[]models.Vote, error) {
+	a.Storage.mu.RLock()
+	defer a.Storage.mu.RUnlock()
+
+	thread, err := a.Storage.getThread(threadSlugOrId)
+	if err != nil {
+		return nil, err
+	}
+
+	since := key(getSettings.Since)
+	votes := make([]models.Vote, 0)
+	for voteId, voice := range a.Storage.votes {
+		if voteId.thread != thread.Id || (getSettings.Voice != 0 && voice != getSettings.Voice) {
+			continue
+		}
+		if getSettings.Since != "" {
+			if getSettings.Desc && voteId.nickname >= since {
+				continue
+			}
+			if !getSettings.Desc && voteId.nickname <= since {
+				continue
+			}
+		}
+		votes = append(votes, models.Vote{Nickname: a.votedUser(voteId.nickname), Thread: int64(thread.Id), Voice: voice})
+	}
+
+	sort.Slice(votes, func(i, j int) bool {
+		return (key(votes[i].Nickname) < key(votes[j].Nickname)) != getSettings.Desc
+	})
+	if int(getSettings.Limit) < len(votes) {
+		votes = votes[:getSettings.Limit]
+	}
+
+	return &votes, nil
+}
+
+func (a *VoteMemoryRepo) GetByUser(ctx context.Context, nickname string, getSettings *models.GetUserVotes) (*[]models.Vote, error) {
+	a.Storage.mu.RLock()
+	defer a.Storage.mu.RUnlock()
+
+	user, ok := a.Storage.getUser(nickname)
+	if !ok {
+		return nil, domain.ErrorUserDoesNotExist
+	}
+
+	votes := make([]models.Vote, 0)
+	for voteId, voice := range a.Storage.votes {
+		if voteId.nickname != key(user.Nickname) {
+			continue
+		}
+		if getSettings.Since != 0 {
+			if getSettings.Desc && voteId.thread >= getSettings.Since {
+				continue
+			}
+			if !getSettings.Desc && voteId.thread <= getSettings.Since {
+				continue
+			}
+		}
+		votes = append(votes, models.Vote{Nickname: user.Nickname, Thread: int64(voteId.thread), Voice: voice})
+	}
+
+	sort.Slice(votes, func(i, j int) bool {
+		return (votes[i].Thread < votes[j].Thread) != getSettings.Desc
+	})
+	if int(getSettings.Limit) < len(votes) {
+		votes = votes[:getSettings.Limit]
+	}
+
+	return &votes, nil
+}
@@ -0,0 +1,139 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+	"time"
+)
+
+const (
+	GetPasswordHashCommand       = "SELECT nickname, password_hash FROM Users WHERE nickname = $1;"
+	DeleteExpiredSessionsCommand = "DELETE FROM Sessions WHERE nickname = $1 AND expires <= now();"
+	CreateSessionCommand         = "INSERT INTO Sessions (token_hash, nickname, expires) VALUES ($1, $2, $3);"
+	GetSessionCommand            = "SELECT nickname, expires FROM Sessions WHERE token_hash = $1 AND expires > now();"
+	DeleteSessionCommand         = "DELETE FROM Sessions WHERE token_hash = $1;"
+
+	SetPasswordHashCommand      = "UPDATE Users SET password_hash = $1 WHERE nickname = $2;"
+	DeleteUserSessionsCommand   = "DELETE FROM Sessions WHERE nickname = $1;"
+	DeletePasswordResetsCommand = "DELETE FROM PasswordResets WHERE nickname = $1;"
+	CreatePasswordResetCommand  = "INSERT INTO PasswordResets (token_hash, nickname, expires) SELECT $1, nickname, $3 FROM Users WHERE nickname = $2 ON CONFLICT (nickname) DO UPDATE SET (token_hash, expires) = (excluded.token_hash, excluded.expires) RETURNING nickname;"
+	UsePasswordResetCommand     = "DELETE FROM PasswordResets WHERE token_hash = $1 AND nickname = $2 RETURNING nickname, expires;"
+)
+
+type AuthPostgresRepo struct {
+	Db *pgxpool.Pool
+}
+
+func NewAuthPostgresRepo(db *pgxpool.Pool) domain.AuthRepo {
+	return &AuthPostgresRepo{Db: db}
+}
+
+func (a *AuthPostgresRepo) GetPasswordHash(ctx context.Context, nickname string) (string, []byte, error) {
+	var hash []byte
+	err := a.Db.QueryRow(ctx, GetPasswordHashCommand, nickname).Scan(&nickname, &hash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil, domain.ErrorUserDoesNotExist
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("get password hash: %w", err)
+	}
+
+	return nickname, hash, nil
+}
+
+// CreateSession заодно удаляет истёкшие сессии пользователя, чтобы таблица не росла
+func (a *AuthPostgresRepo) CreateSession(ctx context.Context, session *models.Session) error {
+	if _, err := a.Db.Exec(ctx, DeleteExpiredSessionsCommand, session.Nickname); err != nil {
+		return fmt.Errorf("delete expired sessions: %w", err)
+	}
+	if _, err := a.Db.Exec(ctx, CreateSessionCommand, session.TokenHash, session.Nickname, session.Expires); err != nil {
+		return fmt.Errorf("create session: %w", err)
+	}
+
+	return nil
+}
+
+func (a *AuthPostgresRepo) GetSession(ctx context.Context, tokenHash []byte) (*models.Session, error) {
+	session := &models.Session{TokenHash: tokenHash}
+	err := a.Db.QueryRow(ctx, GetSessionCommand, tokenHash).Scan(&session.Nickname, &session.Expires)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrorSessionDoesNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get session: %w", err)
+	}
+
+	return session, nil
+}
+
+func (a *AuthPostgresRepo) DeleteSession(ctx context.Context, tokenHash []byte) error {
+	if _, err := a.Db.Exec(ctx, DeleteSessionCommand, tokenHash); err != nil {
+		return fmt.Errorf("delete session: %w", err)
+	}
+
+	return nil
+}
+
+// SetPasswordHash в одной транзакции с паролем удаляет сессии и сбросы: старые токены после смены пароля не действуют
+func (a *AuthPostgresRepo) SetPasswordHash(ctx context.Context, nickname string, hash []byte) error {
+	tx, err := a.Db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin set password: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	tag, err := tx.Exec(ctx, SetPasswordHashCommand, hash, nickname)
+	if err != nil {
+		return fmt.Errorf("set password hash: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return domain.ErrorUserDoesNotExist
+	}
+	if _, err = tx.Exec(ctx, DeleteUserSessionsCommand, nickname); err != nil {
+		return fmt.Errorf("delete sessions: %w", err)
+	}
+	if _, err = tx.Exec(ctx, DeletePasswordResetsCommand, nickname); err != nil {
+		return fmt.Errorf("delete password resets: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit set password: %w", err)
+	}
+
+	return nil
+}
+
+func (a *AuthPostgresRepo) CreatePasswordReset(ctx context.Context, reset *models.PasswordReset) error {
+	err := a.Db.QueryRow(ctx, CreatePasswordResetCommand, reset.TokenHash, reset.Nickname, reset.Expires).Scan(&reset.Nickname)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.ErrorUserDoesNotExist
+	}
+	if err != nil {
+		return fmt.Errorf("create password reset: %w", err)
+	}
+
+	return nil
+}
+
+// UsePasswordReset удаляет и истёкший сброс, второй раз воспользоваться токеном нельзя в любом случае.
+// Ник проверяется в том же DELETE, поэтому запрос с чужим ником токен не тратит
+func (a *AuthPostgresRepo) UsePasswordReset(ctx context.Context, tokenHash []byte, nickname string) (*models.PasswordReset, error) {
+	reset := &models.PasswordReset{TokenHash: tokenHash}
+	err := a.Db.QueryRow(ctx, UsePasswordResetCommand, tokenHash, nickname).Scan(&reset.Nickname, &reset.Expires)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrorPasswordResetDoesNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("use password reset: %w", err)
+	}
+	if !reset.Expires.After(time.Now()) {
+		return nil, domain.ErrorPasswordResetDoesNotExist
+	}
+
+	return reset, nil
+}
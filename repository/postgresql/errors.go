@@ -0,0 +1,26 @@
+package postgresql
+
+import (
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"technopark-db-semester-project/domain"
+)
+
+const UniqueViolationCode = "23505"
+
+// noRows превращает pgx.ErrNoRows в ошибку notFound, сохраняя исходную ошибку внутри.
+// Остальные ошибки запроса (обрыв соединения, отмена контекста) возвращаются как есть и отдаются клиенту как 500
+func noRows(err error, notFound *domain.Error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return notFound.Wrap(err)
+	}
+
+	return err
+}
+
+// isUniqueViolation - нарушение уникального индекса, т.е. запись с такими данными уже есть
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == UniqueViolationCode
+}
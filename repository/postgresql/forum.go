@@ -2,7 +2,7 @@ package postgresql
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"technopark-db-semester-project/domain"
@@ -18,15 +18,14 @@ const (
 	GetUsersOnForumWithoutSinceCommand     = "SELECT nickname, fullname, about, email FROM ForumUsers WHERE forum = $1 ORDER BY nickname LIMIT $2;"
 	GetUsersOnForumWithoutSinceDescCommand = "SELECT nickname, fullname, about, email FROM ForumUsers WHERE forum = $1 ORDER BY nickname DESC LIMIT $2;"
 
-	GetThreadsOnForumCommand                 = "SELECT id, title, author, forum, message, votes, slug, created FROM Threads WHERE forum = $1 AND created >= $2 ORDER BY created LIMIT $3;"
-	GetThreadsOnForumDescCommand             = "SELECT id, title, author, forum, message, votes, slug, created FROM Threads WHERE forum = $1 AND created <= $2 ORDER BY created DESC LIMIT $3;"
-	GetThreadsOnForumWithoutSinceCommand     = "SELECT id, title, author, forum, message, votes, slug, created FROM Threads WHERE forum = $1 ORDER BY created LIMIT $2;"
-	GetThreadsOnForumWithoutSinceDescCommand = "SELECT id, title, author, forum, message, votes, slug, created FROM Threads WHERE forum = $1 ORDER BY created DESC LIMIT $2;"
-)
-
-var (
-	ErrorForumAlreadyExist = errors.New("forum already exist")
-	ErrorForumDoesNotExist = errors.New("forum does not exist")
+	GetThreadsOnForumCommand                 = "SELECT id, title, author, forum, message, votes, slug, created, state, pinned, announcement FROM Threads WHERE forum = $1 AND pinned = 0 AND NOT announcement AND created >= $2 ORDER BY created, id LIMIT $3;"
+	GetThreadsOnForumDescCommand             = "SELECT id, title, author, forum, message, votes, slug, created, state, pinned, announcement FROM Threads WHERE forum = $1 AND pinned = 0 AND NOT announcement AND created <= $2 ORDER BY created DESC, id DESC LIMIT $3;"
+	GetThreadsOnForumWithoutSinceCommand     = "SELECT id, title, author, forum, message, votes, slug, created, state, pinned, announcement FROM Threads WHERE forum = $1 AND pinned = 0 AND NOT announcement ORDER BY created, id LIMIT $2;"
+	GetThreadsOnForumWithoutSinceDescCommand = "SELECT id, title, author, forum, message, votes, slug, created, state, pinned, announcement FROM Threads WHERE forum = $1 AND pinned = 0 AND NOT announcement ORDER BY created DESC, id DESC LIMIT $2;"
+	GetThreadsOnForumAfterCommand            = "SELECT id, title, author, forum, message, votes, slug, created, state, pinned, announcement FROM Threads WHERE forum = $1 AND pinned = 0 AND NOT announcement AND (created, id) > ($2, $3) ORDER BY created, id LIMIT $4;"
+	GetThreadsOnForumAfterDescCommand        = "SELECT id, title, author, forum, message, votes, slug, created, state, pinned, announcement FROM Threads WHERE forum = $1 AND pinned = 0 AND NOT announcement AND (created, id) < ($2, $3) ORDER BY created DESC, id DESC LIMIT $4;"
+	GetPinnedThreadsOnForumCommand           = "SELECT id, title, author, forum, message, votes, slug, created, state, pinned, announcement FROM Threads WHERE announcement OR (forum = $1 AND pinned > 0) ORDER BY announcement DESC, pinned DESC, created DESC, id DESC;"
+	CountThreadsOnForumCommand               = "SELECT f.threads + (SELECT count(*) FROM Threads WHERE announcement AND forum != f.slug) FROM Forums f WHERE f.slug = $1;"
 )
 
 type ForumPostgresRepo struct {
@@ -41,13 +40,19 @@ func (a *ForumPostgresRepo) Create(ctx context.Context, forum *models.ForumCreat
 	var user models.User
 	err := a.Db.QueryRow(ctx, GetUserByNicknameCommand, forum.User).Scan(&user.Nickname, &user.Fullname, &user.About, &user.Email)
 	if err != nil {
-		return nil, ErrorUserDoesNotExist
+		return nil, noRows(err, domain.ErrorUserDoesNotExist)
 	}
 
 	_, err = a.Db.Exec(ctx, CreateForumCommand, forum.Title, user.Nickname, forum.Slug)
+	if isUniqueViolation(err) {
+		forumAlreadyExist, err := a.Get(ctx, forum.Slug)
+		if err != nil {
+			return nil, err
+		}
+		return forumAlreadyExist, domain.ErrorForumAlreadyExist
+	}
 	if err != nil {
-		forumAlreadyExist, _ := a.Get(ctx, forum.Slug)
-		return forumAlreadyExist, ErrorForumAlreadyExist
+		return nil, fmt.Errorf("create forum: %w", err)
 	}
 
 	forumToReturn := &models.Forum{
@@ -66,7 +71,7 @@ func (a *ForumPostgresRepo) Get(ctx context.Context, slug string) (*models.Forum
 
 	err := a.Db.QueryRow(ctx, GetForumCommand, slug).Scan(&forum.Title, &forum.User, &forum.Slug, &forum.Posts, &forum.Threads)
 	if err != nil {
-		return nil, ErrorForumDoesNotExist
+		return nil, noRows(err, domain.ErrorForumDoesNotExist)
 	}
 
 	return &forum, nil
@@ -78,7 +83,7 @@ func (a *ForumPostgresRepo) GetUsers(ctx context.Context, getSettings *models.Ge
 
 	_, err = a.Get(ctx, getSettings.Slug)
 	if err != nil {
-		return nil, ErrorForumDoesNotExist
+		return nil, err
 	}
 
 	if getSettings.Desc {
@@ -96,7 +101,7 @@ func (a *ForumPostgresRepo) GetUsers(ctx context.Context, getSettings *models.Ge
 	}
 
 	if err != nil {
-		return nil, ErrorForumDoesNotExist
+		return nil, fmt.Errorf("get forum users: %w", err)
 	}
 	defer rows.Close()
 
@@ -105,7 +110,7 @@ func (a *ForumPostgresRepo) GetUsers(ctx context.Context, getSettings *models.Ge
 		user := models.User{}
 		err = rows.Scan(&user.Nickname, &user.Fullname, &user.About, &user.Email)
 		if err != nil {
-			return nil, ErrorForumDoesNotExist
+			return nil, fmt.Errorf("get forum users: %w", err)
 		}
 		users = append(users, user)
 	}
@@ -113,15 +118,36 @@ func (a *ForumPostgresRepo) GetUsers(ctx context.Context, getSettings *models.Ge
 	return &users, nil
 }
 
+// GetThreads на первой странице (без since и after) сначала отдаёт объявления всех форумов и закреплённые ветки форума,
+// а за ними limit остальных веток. Сколько остальных войдёт в страницу, решает delivery: закреплённые занимают места в limit.
+// After с нулевым id - начало списка после первой страницы, в которую поместились только закреплённые ветки
 func (a *ForumPostgresRepo) GetThreads(ctx context.Context, slug string, getSettings *models.GetForumThreads) (*[]models.Thread, error) {
 	var rows pgx.Rows
 
 	_, err := a.Get(ctx, slug)
 	if err != nil {
-		return nil, ErrorForumDoesNotExist
+		return nil, err
 	}
 
-	if getSettings.Desc {
+	threads := make([]models.Thread, 0)
+	if getSettings.Since == "" && getSettings.After == nil {
+		rows, err = a.Db.Query(ctx, GetPinnedThreadsOnForumCommand, slug)
+		if err != nil {
+			return nil, fmt.Errorf("get pinned threads: %w", err)
+		}
+		threads, err = scanThreads(rows, threads)
+		if err != nil {
+			return nil, fmt.Errorf("get pinned threads: %w", err)
+		}
+	}
+
+	if after := getSettings.After; after != nil && after.Id != 0 {
+		if getSettings.Desc {
+			rows, err = a.Db.Query(ctx, GetThreadsOnForumAfterDescCommand, slug, after.Created, after.Id, getSettings.Limit)
+		} else {
+			rows, err = a.Db.Query(ctx, GetThreadsOnForumAfterCommand, slug, after.Created, after.Id, getSettings.Limit)
+		}
+	} else if getSettings.Desc {
 		if getSettings.Since == "" {
 			rows, err = a.Db.Query(ctx, GetThreadsOnForumWithoutSinceDescCommand, slug, getSettings.Limit)
 		} else {
@@ -136,20 +162,39 @@ func (a *ForumPostgresRepo) GetThreads(ctx context.Context, slug string, getSett
 	}
 
 	if err != nil {
-		return nil, ErrorForumDoesNotExist
+		return nil, fmt.Errorf("get forum threads: %w", err)
+	}
+	threads, err = scanThreads(rows, threads)
+	if err != nil {
+		return nil, fmt.Errorf("get forum threads: %w", err)
 	}
+
+	return &threads, nil
+}
+
+// scanThreads дописывает ветки из rows в threads и закрывает rows
+func scanThreads(rows pgx.Rows, threads []models.Thread) ([]models.Thread, error) {
 	defer rows.Close()
 
-	threads := make([]models.Thread, 0, rows.CommandTag().RowsAffected())
 	for rows.Next() {
 		thread := models.Thread{}
-		err = rows.Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &thread.Slug, &thread.Created)
+		err := rows.Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &thread.Slug, &thread.Created, &thread.State, &thread.Pinned, &thread.Announcement)
 		if err != nil {
-			return nil, ErrorForumDoesNotExist
+			return nil, err
 		}
 
 		threads = append(threads, thread)
 	}
 
-	return &threads, nil
+	return threads, rows.Err()
+}
+
+func (a *ForumPostgresRepo) CountThreads(ctx context.Context, slug string) (int64, error) {
+	var count int64
+	err := a.Db.QueryRow(ctx, CountThreadsOnForumCommand, slug).Scan(&count)
+	if err != nil {
+		return 0, noRows(err, domain.ErrorForumDoesNotExist)
+	}
+
+	return count, nil
 }
@@ -0,0 +1,130 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+)
+
+const (
+	GetMentionsCommand     = "SELECT m.id, m.nickname, coalesce(m.post, 0), coalesce(p.thread, t.id), coalesce(p.forum, t.forum), coalesce(p.author, t.author), m.created FROM Mentions m LEFT JOIN Posts p ON p.id = m.post LEFT JOIN Threads t ON t.id = m.thread WHERE m.nickname = $1 AND m.id > $2 ORDER BY m.id LIMIT $3;"
+	GetMentionsDescCommand = "SELECT m.id, m.nickname, coalesce(m.post, 0), coalesce(p.thread, t.id), coalesce(p.forum, t.forum), coalesce(p.author, t.author), m.created FROM Mentions m LEFT JOIN Posts p ON p.id = m.post LEFT JOIN Threads t ON t.id = m.thread WHERE m.nickname = $1 AND ($2::bigint = 0 OR m.id < $2) ORDER BY m.id DESC LIMIT $3;"
+
+	// упоминания новых постов пачкой: $1 - id постов, $2 - ники, попарно. Автор поста сам себя не упоминает
+	CreatePostsMentionsCommand = "INSERT INTO Mentions (nickname, post) SELECT u.nickname, m.post FROM unnest($1::bigint[], $2::text[]) AS m (post, nickname) JOIN Posts p ON p.id = m.post JOIN Users u ON u.nickname = m.nickname::citext WHERE u.nickname != p.author ON CONFLICT DO NOTHING;"
+
+	// при правке текста пропавшие упоминания удаляются, а об оставшихся повторно не уведомляют
+	DeletePostMentionsCommand   = "DELETE FROM Mentions WHERE post = $1 AND nickname != ALL($2::text[]::citext[]);"
+	AddPostMentionsCommand      = "WITH added AS (INSERT INTO Mentions (nickname, post) SELECT nickname, $1::bigint FROM Users WHERE nickname = ANY($2::text[]::citext[]) AND nickname != $3 ON CONFLICT DO NOTHING RETURNING nickname) INSERT INTO Notifications (nickname, kind, post) SELECT nickname, 'mention', $1 FROM added;"
+	DeleteThreadMentionsCommand = "DELETE FROM Mentions WHERE thread = $1 AND nickname != ALL($2::text[]::citext[]);"
+	AddThreadMentionsCommand    = "WITH added AS (INSERT INTO Mentions (nickname, thread) SELECT nickname, $1::bigint FROM Users WHERE nickname = ANY($2::text[]::citext[]) AND nickname != $3 ON CONFLICT DO NOTHING RETURNING nickname) INSERT INTO Notifications (nickname, kind, thread) SELECT nickname, 'mention', $1 FROM added;"
+
+	// текст ветки при слиянии становится постом $1, пост при выделении - веткой $1
+	ThreadMentionsToPostCommand = "WITH mentions AS (UPDATE Mentions SET (post, thread) = ($1::bigint, NULL) WHERE thread = $2) UPDATE Notifications SET (post, thread) = ($1::bigint, NULL) WHERE thread = $2;"
+	PostMentionsToThreadCommand = "WITH mentions AS (UPDATE Mentions SET (post, thread) = (NULL, $1::bigint) WHERE post = $2) UPDATE Notifications SET (post, thread) = (NULL, $1::bigint) WHERE post = $2;"
+)
+
+type MentionPostgresRepo struct {
+	Db *pgxpool.Pool
+}
+
+func NewMentionPostgresRepo(db *pgxpool.Pool) domain.MentionRepo {
+	return &MentionPostgresRepo{Db: db}
+}
+
+func (a *MentionPostgresRepo) GetByUser(ctx context.Context, nickname string, getSettings *models.GetMentions) (*[]models.Mention, error) {
+	var user models.User
+	err := a.Db.QueryRow(ctx, GetUserByNicknameCommand, nickname).Scan(&user.Nickname, &user.Fullname, &user.About, &user.Email)
+	if err != nil {
+		return nil, noRows(err, domain.ErrorUserDoesNotExist)
+	}
+
+	var rows pgx.Rows
+	if getSettings.Desc {
+		rows, err = a.Db.Query(ctx, GetMentionsDescCommand, user.Nickname, getSettings.Since, getSettings.Limit)
+	} else {
+		rows, err = a.Db.Query(ctx, GetMentionsCommand, user.Nickname, getSettings.Since, getSettings.Limit)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get mentions: %w", err)
+	}
+	defer rows.Close()
+
+	mentions := make([]models.Mention, 0)
+	for rows.Next() {
+		var mention models.Mention
+		err = rows.Scan(&mention.Id, &mention.Nickname, &mention.Post, &mention.Thread, &mention.Forum, &mention.Author, &mention.Created)
+		if err != nil {
+			return nil, fmt.Errorf("scan mention: %w", err)
+		}
+		mentions = append(mentions, mention)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("get mentions: %w", err)
+	}
+
+	return &mentions, nil
+}
+
+// createPostsMentions сохраняет упоминания только что вставленных постов. Уведомления о них
+// создаёт createNotifications вместе с остальными
+func createPostsMentions(ctx context.Context, tx pgx.Tx, posts []models.Post) error {
+	ids := make([]int64, 0)
+	nicknames := make([]string, 0)
+	for _, post := range posts {
+		for _, nickname := range domain.ParseMentions(post.Message) {
+			ids = append(ids, post.Id)
+			nicknames = append(nicknames, nickname)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, CreatePostsMentionsCommand, ids, nicknames); err != nil {
+		return fmt.Errorf("create mentions: %w", err)
+	}
+
+	return nil
+}
+
+// setPostMentions приводит упоминания поста к тексту message и уведомляет новых упомянутых
+func setPostMentions(ctx context.Context, tx pgx.Tx, post *models.Post, message string) error {
+	nicknames := domain.ParseMentions(message)
+	if _, err := tx.Exec(ctx, DeletePostMentionsCommand, post.Id, nicknames); err != nil {
+		return fmt.Errorf("delete mentions: %w", err)
+	}
+	if len(nicknames) == 0 {
+		return nil
+	}
+	if _, err := tx.Exec(ctx, AddPostMentionsCommand, post.Id, nicknames, post.Author); err != nil {
+		return fmt.Errorf("add mentions: %w", err)
+	}
+
+	return nil
+}
+
+// addThreadMentions сохраняет упоминания из текста новой ветки и уведомляет упомянутых
+func addThreadMentions(ctx context.Context, tx pgx.Tx, thread *models.Thread) error {
+	nicknames := domain.ParseMentions(thread.Message)
+	if len(nicknames) == 0 {
+		return nil
+	}
+	if _, err := tx.Exec(ctx, AddThreadMentionsCommand, thread.Id, nicknames, thread.Author); err != nil {
+		return fmt.Errorf("add mentions: %w", err)
+	}
+
+	return nil
+}
+
+// setThreadMentions - то же, что setPostMentions, для текста ветки
+func setThreadMentions(ctx context.Context, tx pgx.Tx, thread *models.Thread) error {
+	if _, err := tx.Exec(ctx, DeleteThreadMentionsCommand, thread.Id, domain.ParseMentions(thread.Message)); err != nil {
+		return fmt.Errorf("delete mentions: %w", err)
+	}
+
+	return addThreadMentions(ctx, tx, thread)
+}
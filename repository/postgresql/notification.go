@@ -0,0 +1,248 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"strconv"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+)
+
+const (
+	// DO UPDATE вместо DO NOTHING, чтобы RETURNING вернул строку и при повторной подписке
+	SubscribeThreadCommand   = "INSERT INTO ThreadSubscriptions (thread, nickname) SELECT $1::bigint, nickname FROM Users WHERE nickname = $2 ON CONFLICT (thread, nickname) DO UPDATE SET nickname = excluded.nickname RETURNING nickname;"
+	UnsubscribeThreadCommand = "DELETE FROM ThreadSubscriptions WHERE thread = $1 AND nickname = $2;"
+	SubscribeForumCommand    = "INSERT INTO ForumSubscriptions (forum, nickname) SELECT $1::citext, nickname FROM Users WHERE nickname = $2 ON CONFLICT (forum, nickname) DO UPDATE SET nickname = excluded.nickname RETURNING nickname;"
+	UnsubscribeForumCommand  = "DELETE FROM ForumSubscriptions WHERE forum = $1 AND nickname = $2;"
+	GetSubscriptionsCommand  = "SELECT nickname, 0, forum::text FROM ForumSubscriptions WHERE nickname = $1 UNION ALL SELECT nickname, thread, '' FROM ThreadSubscriptions WHERE nickname = $1 ORDER BY 2, 3;"
+	MoveSubscriptionsCommand = "INSERT INTO ThreadSubscriptions (thread, nickname) SELECT $1::bigint, nickname FROM ThreadSubscriptions WHERE thread = $2 ON CONFLICT DO NOTHING;"
+
+	GetNotificationsCommand     = "SELECT n.id, n.kind, coalesce(n.post, 0), coalesce(p.thread, t.id), coalesce(p.forum, t.forum), coalesce(p.author, t.author), coalesce(p.created, t.created), n.isRead FROM Notifications n LEFT JOIN Posts p ON p.id = n.post LEFT JOIN Threads t ON t.id = n.thread WHERE n.nickname = $1 AND n.id > $2 AND (NOT $3 OR NOT n.isRead) ORDER BY n.id LIMIT $4;"
+	GetNotificationsDescCommand = "SELECT n.id, n.kind, coalesce(n.post, 0), coalesce(p.thread, t.id), coalesce(p.forum, t.forum), coalesce(p.author, t.author), coalesce(p.created, t.created), n.isRead FROM Notifications n LEFT JOIN Posts p ON p.id = n.post LEFT JOIN Threads t ON t.id = n.thread WHERE n.nickname = $1 AND ($2::bigint = 0 OR n.id < $2) AND (NOT $3 OR NOT n.isRead) ORDER BY n.id DESC LIMIT $4;"
+	// в CTE обновление не видно остальной части запроса, поэтому отмеченные вычитаются из старого счётчика
+	MarkNotificationsReadCommand    = "WITH marked AS (UPDATE Notifications SET isRead = true WHERE nickname = $1 AND NOT isRead AND id = ANY($2::bigint[]) RETURNING id) SELECT (SELECT count(*) FROM Notifications WHERE nickname = $1 AND NOT isRead) - (SELECT count(*) FROM marked);"
+	MarkAllNotificationsReadCommand = "UPDATE Notifications SET isRead = true WHERE nickname = $1 AND NOT isRead;"
+	CountUnreadNotificationsCommand = "SELECT count(*) FROM Notifications WHERE nickname = $1 AND NOT isRead;"
+
+	// для каждого нового поста - автор родителя, упомянутые, подписчики ветки и подписчики форума, кроме автора самого поста.
+	// Из нескольких причин для одного пользователя остаётся первая: ответ, упоминание, ветка, форум
+	CreateNotificationsCommand = "INSERT INTO Notifications (nickname, kind, post) " +
+		"SELECT DISTINCT ON (p.id, r.nickname) r.nickname, r.kind, p.id FROM Posts p CROSS JOIN LATERAL (" +
+		"SELECT parent.author AS nickname, 'reply' AS kind, 1 AS rank FROM Posts parent WHERE parent.id = p.parent " +
+		"UNION ALL SELECT m.nickname, 'mention', 2 FROM Mentions m WHERE m.post = p.id " +
+		"UNION ALL SELECT s.nickname, 'thread', 3 FROM ThreadSubscriptions s WHERE s.thread = p.thread " +
+		"UNION ALL SELECT s.nickname, 'forum', 4 FROM ForumSubscriptions s WHERE s.forum = p.forum" +
+		") r WHERE p.id = ANY($1::bigint[]) AND r.nickname != p.author ORDER BY p.id, r.nickname, r.rank;"
+)
+
+type NotificationPostgresRepo struct {
+	Db *pgxpool.Pool
+}
+
+func NewNotificationPostgresRepo(db *pgxpool.Pool) domain.NotificationRepo {
+	return &NotificationPostgresRepo{Db: db}
+}
+
+func (a *NotificationPostgresRepo) getThreadId(ctx context.Context, threadSlugOrId string) (int32, error) {
+	var threadId int32
+	var err error
+	if id, convErr := strconv.Atoi(threadSlugOrId); convErr == nil {
+		err = a.Db.QueryRow(ctx, GetThreadIdByIdCommand, id).Scan(&threadId)
+	} else {
+		err = a.Db.QueryRow(ctx, GetThreadIdBySlugCommand, threadSlugOrId).Scan(&threadId)
+	}
+	if err != nil {
+		return 0, noRows(err, domain.ErrorThreadDoesNotExist)
+	}
+
+	return threadId, nil
+}
+
+func (a *NotificationPostgresRepo) getUserNickname(ctx context.Context, nickname string) (string, error) {
+	var user models.User
+	err := a.Db.QueryRow(ctx, GetUserByNicknameCommand, nickname).Scan(&user.Nickname, &user.Fullname, &user.About, &user.Email)
+	if err != nil {
+		return "", noRows(err, domain.ErrorUserDoesNotExist)
+	}
+
+	return user.Nickname, nil
+}
+
+func (a *NotificationPostgresRepo) SubscribeThread(ctx context.Context, nickname string, threadSlugOrId string) (*models.Subscription, error) {
+	threadId, err := a.getThreadId(ctx, threadSlugOrId)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription := &models.Subscription{Thread: threadId}
+	err = a.Db.QueryRow(ctx, SubscribeThreadCommand, threadId, nickname).Scan(&subscription.Nickname)
+	// ветку могли удалить слиянием между проверкой и вставкой
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == ForeignKeyViolationCode {
+		return nil, domain.ErrorThreadDoesNotExist
+	}
+	if err != nil {
+		return nil, noRows(err, domain.ErrorUserDoesNotExist)
+	}
+
+	return subscription, nil
+}
+
+func (a *NotificationPostgresRepo) UnsubscribeThread(ctx context.Context, nickname string, threadSlugOrId string) error {
+	threadId, err := a.getThreadId(ctx, threadSlugOrId)
+	if err != nil {
+		return err
+	}
+
+	if _, err = a.Db.Exec(ctx, UnsubscribeThreadCommand, threadId, nickname); err != nil {
+		return fmt.Errorf("unsubscribe thread: %w", err)
+	}
+
+	return nil
+}
+
+func (a *NotificationPostgresRepo) SubscribeForum(ctx context.Context, nickname string, forumSlug string) (*models.Subscription, error) {
+	subscription := &models.Subscription{}
+	err := a.Db.QueryRow(ctx, CheckForumCommand, forumSlug).Scan(&subscription.Forum)
+	if err != nil {
+		return nil, noRows(err, domain.ErrorForumDoesNotExist)
+	}
+
+	err = a.Db.QueryRow(ctx, SubscribeForumCommand, subscription.Forum, nickname).Scan(&subscription.Nickname)
+	if err != nil {
+		return nil, noRows(err, domain.ErrorUserDoesNotExist)
+	}
+
+	return subscription, nil
+}
+
+func (a *NotificationPostgresRepo) UnsubscribeForum(ctx context.Context, nickname string, forumSlug string) error {
+	var slug string
+	err := a.Db.QueryRow(ctx, CheckForumCommand, forumSlug).Scan(&slug)
+	if err != nil {
+		return noRows(err, domain.ErrorForumDoesNotExist)
+	}
+
+	if _, err = a.Db.Exec(ctx, UnsubscribeForumCommand, slug, nickname); err != nil {
+		return fmt.Errorf("unsubscribe forum: %w", err)
+	}
+
+	return nil
+}
+
+func (a *NotificationPostgresRepo) GetSubscriptions(ctx context.Context, nickname string) (*[]models.Subscription, error) {
+	userNickname, err := a.getUserNickname(ctx, nickname)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := a.Db.Query(ctx, GetSubscriptionsCommand, userNickname)
+	if err != nil {
+		return nil, fmt.Errorf("get subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subscriptions := make([]models.Subscription, 0)
+	for rows.Next() {
+		var subscription models.Subscription
+		if err = rows.Scan(&subscription.Nickname, &subscription.Thread, &subscription.Forum); err != nil {
+			return nil, fmt.Errorf("scan subscription: %w", err)
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("get subscriptions: %w", err)
+	}
+
+	return &subscriptions, nil
+}
+
+func (a *NotificationPostgresRepo) GetNotifications(ctx context.Context, nickname string, getSettings *models.GetNotifications) (*[]models.Notification, error) {
+	userNickname, err := a.getUserNickname(ctx, nickname)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows pgx.Rows
+	if getSettings.Desc {
+		rows, err = a.Db.Query(ctx, GetNotificationsDescCommand, userNickname, getSettings.Since, getSettings.Unread, getSettings.Limit)
+	} else {
+		rows, err = a.Db.Query(ctx, GetNotificationsCommand, userNickname, getSettings.Since, getSettings.Unread, getSettings.Limit)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := make([]models.Notification, 0)
+	for rows.Next() {
+		var notification models.Notification
+		err = rows.Scan(&notification.Id, &notification.Kind, &notification.Post, &notification.Thread, &notification.Forum, &notification.Author, &notification.Created, &notification.Read)
+		if err != nil {
+			return nil, fmt.Errorf("scan notification: %w", err)
+		}
+		notifications = append(notifications, notification)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("get notifications: %w", err)
+	}
+
+	return &notifications, nil
+}
+
+func (a *NotificationPostgresRepo) MarkRead(ctx context.Context, nickname string, read *models.NotificationsRead) (*models.NotificationsCount, error) {
+	userNickname, err := a.getUserNickname(ctx, nickname)
+	if err != nil {
+		return nil, err
+	}
+
+	count := &models.NotificationsCount{}
+	if read.All {
+		if _, err = a.Db.Exec(ctx, MarkAllNotificationsReadCommand, userNickname); err != nil {
+			return nil, fmt.Errorf("mark notifications read: %w", err)
+		}
+		return count, nil
+	}
+
+	ids := read.Ids
+	if ids == nil {
+		ids = make([]int64, 0)
+	}
+	if err = a.Db.QueryRow(ctx, MarkNotificationsReadCommand, userNickname, ids).Scan(&count.Unread); err != nil {
+		return nil, fmt.Errorf("mark notifications read: %w", err)
+	}
+
+	return count, nil
+}
+
+func (a *NotificationPostgresRepo) CountUnread(ctx context.Context, nickname string) (*models.NotificationsCount, error) {
+	userNickname, err := a.getUserNickname(ctx, nickname)
+	if err != nil {
+		return nil, err
+	}
+
+	count := &models.NotificationsCount{}
+	if err = a.Db.QueryRow(ctx, CountUnreadNotificationsCommand, userNickname).Scan(&count.Unread); err != nil {
+		return nil, fmt.Errorf("count unread notifications: %w", err)
+	}
+
+	return count, nil
+}
+
+// createNotifications создаёт уведомления о только что вставленных постах в той же транзакции
+func createNotifications(ctx context.Context, tx pgx.Tx, posts []models.Post) error {
+	ids := make([]int64, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.Id)
+	}
+
+	if _, err := tx.Exec(ctx, CreateNotificationsCommand, ids); err != nil {
+		return fmt.Errorf("create notifications: %w", err)
+	}
+
+	return nil
+}
@@ -4,7 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	_ "github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"strconv"
 	"strings"
@@ -14,25 +15,40 @@ import (
 )
 
 const (
-	GetPostCommand       = "SELECT id, parent, author, message, isEdited, forum, thread, created FROM Posts WHERE id = $1;"
-	GetPostAuthorCommand = "SELECT nickname, fullname, about, email FROM Users WHERE nickname = $1;"
-	GetPostForumCommand  = "SELECT title, \"user\", slug, posts, threads FROM Forums WHERE slug = $1;"
-	GetPostThreadCommand = "SELECT id, title, author, forum, message, votes, slug, created FROM Threads WHERE id = $1;"
-	UpdatePostCommand    = "UPDATE Posts SET (message, isEdited) = ($1, true) WHERE id = $2;"
+	GetPostCommand    = "SELECT id, parent, author, message, isEdited, forum, thread, created, isDeleted, votes FROM Posts WHERE id = $1;"
+	UpdatePostCommand = "UPDATE Posts SET (message, isEdited) = ($1, true) WHERE id = $2;"
+
+	LockThreadPostsCommand = "SELECT id FROM Posts WHERE thread = $1 AND id = ANY($2::bigint[]) FOR KEY SHARE;"
+	LockUsersCommand       = "SELECT nickname FROM Users WHERE nickname = ANY($1::text[]::citext[]) FOR KEY SHARE;"
+	NextPostIdsCommand     = "SELECT nextval(pg_get_serial_sequence('posts', 'id')) FROM generate_series(1, $1);"
+
+	LockPostCommand            = "SELECT id, parent, author, message, isEdited, forum, thread, created, isDeleted, votes, parent_path FROM Posts WHERE id = $1 FOR UPDATE;"
+	SoftDeletePostCommand      = "UPDATE Posts SET (message, isDeleted) = ('', true) WHERE id = $1;"
+	HardDeletePostsCommand     = "WITH deleted AS (DELETE FROM Posts WHERE thread = $1 AND parent_path[1:$2] = $3 RETURNING isDeleted) SELECT count(*) FILTER (WHERE NOT isDeleted) FROM deleted;"
+	DecrementForumPostsCommand = "UPDATE Forums SET posts = posts - $1 WHERE slug = $2;"
+
+	GetPostForUpdateCommand   = "SELECT id, parent, author, message, isEdited, forum, thread, created, isDeleted, votes FROM Posts WHERE id = $1 FOR UPDATE;"
+	InsertPostRevisionCommand = "INSERT INTO PostRevisions (post, revision, editor, message) SELECT $1, (SELECT coalesce(max(revision), 0) + 1 FROM PostRevisions WHERE post = $1), nickname, $3 FROM Users WHERE nickname = $2 RETURNING revision;"
+	GetPostRevisionsCommand   = "SELECT revision, editor, message, created FROM PostRevisions WHERE post = $1 ORDER BY revision;"
 )
 
-var (
-	ErrorPostDoesNotExist       = errors.New("post does not exist")
-	ErrorAuthorDoesNotExist     = errors.New("author does not exist")
-	ErrorParentPostDoesNotExist = errors.New("parent post does not exist")
+const (
+	// CopyPostsThreshold - начиная с такого размера пачки посты вставляются через COPY, а не одним INSERT
+	CopyPostsThreshold = 1000
+
+	ForeignKeyViolationCode = "23503"
+
+	PostsAuthorForeignKey = "posts_author_fkey"
+	PostsThreadForeignKey = "posts_thread_fkey"
 )
 
 type PostPostgresRepo struct {
-	Db *pgxpool.Pool
+	Db       *pgxpool.Pool
+	resolver *Resolver
 }
 
-func NewPostPostgresRepo(db *pgxpool.Pool) domain.PostRepo {
-	return &PostPostgresRepo{Db: db}
+func NewPostPostgresRepo(db *pgxpool.Pool, resolver *Resolver) domain.PostRepo {
+	return &PostPostgresRepo{Db: db, resolver: resolver}
 }
 
 func isIn(arr *[]string, find string) bool {
@@ -47,74 +63,281 @@ func isIn(arr *[]string, find string) bool {
 
 func (a *PostPostgresRepo) Get(ctx context.Context, id int64, getSettings *models.PostGetRequest) (*models.PostGetResult, error) {
 	var post models.Post
-	err := a.Db.QueryRow(ctx, GetPostCommand, id).Scan(&post.Id, &post.Parent, &post.Author, &post.Message, &post.IsEdited, &post.Forum, &post.Thread, &post.Created)
+	err := a.Db.QueryRow(ctx, GetPostCommand, id).Scan(&post.Id, &post.Parent, &post.Author, &post.Message, &post.IsEdited, &post.Forum, &post.Thread, &post.Created, &post.IsDeleted, &post.Votes)
 	if err != nil {
-		return nil, ErrorPostDoesNotExist
+		return nil, noRows(err, domain.ErrorPostDoesNotExist)
 	}
 
 	var postResult models.PostGetResult
 	postResult.Post = &post
 
+	// связанные записи берутся через Resolver, с кэшем - без запросов к базе
 	if isIn(&getSettings.Related, models.RelatedUser) {
-		author := &models.User{}
-		_ = a.Db.QueryRow(ctx, GetPostAuthorCommand, post.Author).Scan(&author.Nickname, &author.Fullname, &author.About, &author.Email)
-		postResult.Author = author
+		if postResult.Author, err = a.resolver.Users.Get(ctx, post.Author); err != nil {
+			return nil, err
+		}
 	}
 	if isIn(&getSettings.Related, models.RelatedThread) {
-		thread := &models.Thread{}
-		_ = a.Db.QueryRow(ctx, GetPostThreadCommand, post.Thread).Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &thread.Slug, &thread.Created)
-		postResult.Thread = thread
+		if postResult.Thread, err = a.resolver.Threads.Get(ctx, strconv.FormatInt(int64(post.Thread), 10)); err != nil {
+			return nil, err
+		}
 	}
 	if isIn(&getSettings.Related, models.RelatedForum) {
-		forum := &models.Forum{}
-		_ = a.Db.QueryRow(ctx, GetPostForumCommand, post.Forum).Scan(&forum.Title, &forum.User, &forum.Slug, &forum.Posts, &forum.Threads)
-		postResult.Forum = forum
+		if postResult.Forum, err = a.resolver.Forums.Get(ctx, post.Forum); err != nil {
+			return nil, err
+		}
 	}
 
 	return &postResult, nil
 }
 
+// Update меняет текст поста и в той же транзакции сохраняет прежний текст в PostRevisions
 func (a *PostPostgresRepo) Update(ctx context.Context, id int64, updateDate *models.PostUpdate) (*models.Post, error) {
+	tx, err := a.Db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin update post: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
 	var post models.Post
-	err := a.Db.QueryRow(ctx, GetPostCommand, id).Scan(&post.Id, &post.Parent, &post.Author, &post.Message, &post.IsEdited, &post.Forum, &post.Thread, &post.Created)
+	err = tx.QueryRow(ctx, GetPostForUpdateCommand, id).Scan(&post.Id, &post.Parent, &post.Author, &post.Message, &post.IsEdited, &post.Forum, &post.Thread, &post.Created, &post.IsDeleted, &post.Votes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrorPostDoesNotExist
+	}
 	if err != nil {
-		return nil, ErrorPostDoesNotExist
+		return nil, fmt.Errorf("get post: %w", err)
+	}
+	if post.IsDeleted {
+		return nil, domain.ErrorPostIsDeleted
 	}
 
 	if updateDate.Message == "" || updateDate.Message == post.Message {
 		return &post, nil
 	}
 
-	_ = a.Db.QueryRow(ctx, UpdatePostCommand, updateDate.Message, id)
+	editor := updateDate.Editor
+	if editor == "" {
+		editor = post.Author
+	}
+
+	var revision int32
+	err = tx.QueryRow(ctx, InsertPostRevisionCommand, id, editor, post.Message).Scan(&revision)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrorUserDoesNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("save post revision: %w", err)
+	}
+
+	if _, err = tx.Exec(ctx, UpdatePostCommand, updateDate.Message, id); err != nil {
+		return nil, fmt.Errorf("update post: %w", err)
+	}
+	if err = setPostMentions(ctx, tx, &post, updateDate.Message); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit update post: %w", err)
+	}
+
 	post.Message = updateDate.Message
 	post.IsEdited = true
 
 	return &post, nil
 }
 
-func (a *PostPostgresRepo) CheckParentAndAuthor(ctx context.Context, post *models.PostCreate) error {
-	if post.Parent != 0 {
-		_, err := a.Db.Exec(ctx, GetPostCommand, post.Parent)
+// GetHistory читает пост и его правки из одного снимка, чтобы текущий текст соответствовал последней правке
+func (a *PostPostgresRepo) GetHistory(ctx context.Context, id int64) (*models.PostHistory, error) {
+	tx, err := a.Db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return nil, fmt.Errorf("begin get post history: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var post models.Post
+	err = tx.QueryRow(ctx, GetPostCommand, id).Scan(&post.Id, &post.Parent, &post.Author, &post.Message, &post.IsEdited, &post.Forum, &post.Thread, &post.Created, &post.IsDeleted, &post.Votes)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrorPostDoesNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get post: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, GetPostRevisionsCommand, id)
+	if err != nil {
+		return nil, fmt.Errorf("get post revisions: %w", err)
+	}
+	defer rows.Close()
+
+	history := &models.PostHistory{Post: &post, Revisions: make([]models.PostRevision, 0)}
+	for rows.Next() {
+		var revision models.PostRevision
+		if err = rows.Scan(&revision.Revision, &revision.Editor, &revision.Message, &revision.Created); err != nil {
+			return nil, fmt.Errorf("scan post revision: %w", err)
+		}
+		history.Revisions = append(history.Revisions, revision)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("get post revisions: %w", err)
+	}
+
+	return history, nil
+}
+
+// classifyCreatePostsError переводит ошибку вставки постов в ошибку репозитория
+func classifyCreatePostsError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == ForeignKeyViolationCode {
+		switch pgErr.ConstraintName {
+		case PostsAuthorForeignKey:
+			return domain.ErrorAuthorDoesNotExist
+		case PostsThreadForeignKey:
+			return domain.ErrorThreadDoesNotExist
+		}
+	}
+
+	return fmt.Errorf("create posts: %w", err)
+}
+
+// checkParentsAndAuthors проверяет внутри транзакции, что все родители лежат в той же ветке, а все авторы существуют
+// и не забанены в форуме ветки. Проверка делается запросами на всю пачку, найденные строки блокируются FOR KEY SHARE до конца вставки
+func checkParentsAndAuthors(ctx context.Context, tx pgx.Tx, thread *models.Thread, posts []models.PostCreate) error {
+	parents := make([]int64, 0)
+	seenParents := make(map[int64]struct{})
+	authors := make([]string, 0)
+	seenAuthors := make(map[string]struct{})
+	for _, post := range posts {
+		if _, ok := seenParents[post.Parent]; post.Parent != 0 && !ok {
+			seenParents[post.Parent] = struct{}{}
+			parents = append(parents, post.Parent)
+		}
+		// ники сравниваются без учёта регистра, как citext
+		lowerAuthor := strings.ToLower(post.Author)
+		if _, ok := seenAuthors[lowerAuthor]; !ok {
+			seenAuthors[lowerAuthor] = struct{}{}
+			authors = append(authors, post.Author)
+		}
+	}
+
+	if len(parents) > 0 {
+		rows, err := tx.Query(ctx, LockThreadPostsCommand, thread.Id, parents)
 		if err != nil {
+			return fmt.Errorf("check parent posts: %w", err)
+		}
+		found := 0
+		for rows.Next() {
+			found++
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("check parent posts: %w", err)
+		}
+		if found != len(parents) {
+			return domain.ErrorParentPostDoesNotExist
+		}
+	}
+
+	rows, err := tx.Query(ctx, LockUsersCommand, authors)
+	if err != nil {
+		return fmt.Errorf("check authors: %w", err)
+	}
+	found := make(map[string]struct{}, len(authors))
+	for rows.Next() {
+		var nickname string
+		if err = rows.Scan(&nickname); err != nil {
+			rows.Close()
+			return fmt.Errorf("check authors: %w", err)
+		}
+		found[strings.ToLower(nickname)] = struct{}{}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("check authors: %w", err)
+	}
+	if len(found) != len(seenAuthors) {
+		return domain.ErrorAuthorDoesNotExist
+	}
+
+	return checkNotBanned(ctx, tx, thread.Forum, authors)
+}
+
+// insertPosts вставляет посты одним INSERT ... RETURNING id и проставляет им id
+func insertPosts(ctx context.Context, tx pgx.Tx, posts []models.Post) error {
+	command := strings.Builder{}
+	command.WriteString("INSERT INTO Posts (parent, author, message, forum, thread, created) VALUES ")
+
+	argsForCommand := make([]interface{}, 0, len(posts)*6)
+	for ind, post := range posts {
+		sixInd := ind * 6
+		fmt.Fprintf(&command, "($%d, $%d, $%d, $%d, $%d, $%d),", sixInd+1, sixInd+2, sixInd+3, sixInd+4, sixInd+5, sixInd+6)
+		argsForCommand = append(argsForCommand, post.Parent, post.Author, post.Message, post.Forum, post.Thread, post.Created)
+	}
+
+	qs := command.String()
+	qs = qs[:len(qs)-1] + " RETURNING id"
+
+	rows, err := tx.Query(ctx, qs, argsForCommand...)
+	if err != nil {
+		return err
+	}
+
+	for ind := 0; rows.Next(); ind++ {
+		if err = rows.Scan(&posts[ind].Id); err != nil {
+			rows.Close()
+			return err
+		}
+	}
+	rows.Close()
+
+	// ошибки триггеров (счётчики форума, ForumUsers, parent_path) приходят только после чтения всех строк
+	return rows.Err()
+}
+
+// copyPosts вставляет большую пачку через COPY. COPY не умеет RETURNING, поэтому id заранее берутся из sequence
+func copyPosts(ctx context.Context, tx pgx.Tx, posts []models.Post) error {
+	rows, err := tx.Query(ctx, NextPostIdsCommand, len(posts))
+	if err != nil {
+		return err
+	}
+	for ind := 0; rows.Next(); ind++ {
+		if err = rows.Scan(&posts[ind].Id); err != nil {
+			rows.Close()
 			return err
 		}
 	}
-	_, err := a.Db.Exec(ctx, GetUserByNicknameCommand, post.Author)
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = tx.CopyFrom(
+		ctx,
+		pgx.Identifier{"posts"},
+		[]string{"id", "parent", "author", "message", "forum", "thread", "created"},
+		pgx.CopyFromSlice(len(posts), func(ind int) ([]any, error) {
+			post := &posts[ind]
+			return []any{post.Id, post.Parent, post.Author, post.Message, post.Forum, post.Thread, post.Created}, nil
+		}),
+	)
 
 	return err
 }
 
 func (a *PostPostgresRepo) Create(ctx context.Context, threadSlugOrId string, posts *[]models.PostCreate) (*[]models.Post, error) {
-	var thread models.Thread
-	id, err := strconv.Atoi(threadSlugOrId)
+	tx, err := a.Db.Begin(ctx)
 	if err != nil {
-		err = a.Db.QueryRow(ctx, GetThreadBySlugCommand, threadSlugOrId).Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &thread.Slug, &thread.Created)
-	} else {
-		err = a.Db.QueryRow(ctx, GetThreadByIdCommand, id).Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &thread.Slug, &thread.Created)
+		return nil, fmt.Errorf("begin create posts: %w", err)
 	}
+	// после Commit откат ничего не делает
+	defer func() { _ = tx.Rollback(ctx) }()
 
+	// ветка читается в транзакции, а не через кэш: состояние и форум должны быть те, с которыми вставляются посты
+	thread, err := shareThread(ctx, tx, threadSlugOrId)
 	if err != nil {
-		return nil, ErrorThreadDoesNotExist
+		return nil, err
+	}
+	if err = domain.CheckWritable(thread.State, false); err != nil {
+		return nil, err
 	}
 
 	if len(*posts) == 0 {
@@ -122,57 +345,88 @@ func (a *PostPostgresRepo) Create(ctx context.Context, threadSlugOrId string, po
 		return &postsToRet, nil
 	}
 
-	if len(*posts) == 0 {
-		emptyPosts := make([]models.Post, 0)
-		return &emptyPosts, nil
-	}
-
-	if a.CheckParentAndAuthor(ctx, &(*posts)[0]) != nil {
-		return nil, ErrorParentPostDoesNotExist
+	if err = checkParentsAndAuthors(ctx, tx, thread, *posts); err != nil {
+		return nil, err
 	}
 
-	command := strings.Builder{}
-	command.WriteString("INSERT INTO Posts (parent, author, message, forum, thread, created) VALUES ")
-
-	argsForCommand := make([]interface{}, 0, len(*posts))
 	postsToReturn := make([]models.Post, 0, len(*posts))
 	createdTime := time.Unix(0, time.Now().UnixNano()/1e6*1e6)
-	for ind, post := range *posts {
-		if post.Parent != 0 {
-			var parentPost models.Post
-			err = a.Db.QueryRow(ctx, GetPostCommand, post.Parent).Scan(&parentPost.Id, &parentPost.Parent, &parentPost.Author, &parentPost.Message, &parentPost.IsEdited, &parentPost.Forum, &parentPost.Thread, &parentPost.Created)
-			if err != nil || parentPost.Thread != thread.Id {
-				return nil, ErrorParentPostDoesNotExist
-			}
-		}
-		var author models.User
-		err = a.Db.QueryRow(ctx, GetUserByNicknameCommand, post.Author).Scan(&author.Nickname, &author.Fullname, &author.About, &author.Email)
-		if err != nil {
-			return nil, ErrorAuthorDoesNotExist
-		}
-		sixInd := ind * 6
-
+	for _, post := range *posts {
 		postsToReturn = append(postsToReturn, models.Post{Parent: post.Parent, Author: post.Author, Message: post.Message, Forum: thread.Forum, Thread: thread.Id, Created: createdTime})
-		fmt.Fprintf(&command, "($%d, $%d, $%d, $%d, $%d, $%d),", sixInd+1, sixInd+2, sixInd+3, sixInd+4, sixInd+5, sixInd+6)
-		argsForCommand = append(argsForCommand, post.Parent, post.Author, post.Message, thread.Forum, thread.Id, createdTime)
 	}
 
-	qs := command.String()
-	qs = qs[:len(qs)-1] + " RETURNING id"
+	if len(postsToReturn) >= CopyPostsThreshold {
+		err = copyPosts(ctx, tx, postsToReturn)
+	} else {
+		err = insertPosts(ctx, tx, postsToReturn)
+	}
+	if err != nil {
+		return nil, classifyCreatePostsError(err)
+	}
+	if err = createPostsMentions(ctx, tx, postsToReturn); err != nil {
+		return nil, err
+	}
+	if err = createNotifications(ctx, tx, postsToReturn); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, classifyCreatePostsError(err)
+	}
+
+	return &postsToReturn, nil
+}
 
-	rows, err := a.Db.Query(ctx, qs, argsForCommand...)
+// Delete мягко удаляет пост (стирает текст, оставляя его место в дереве) или, если Hard, удаляет его вместе с поддеревом.
+// Счётчик постов форума уменьшается только на ещё не удалённые посты
+func (a *PostPostgresRepo) Delete(ctx context.Context, id int64, deleteSettings *models.PostDeleteRequest) (*models.PostDeleteResult, error) {
+	tx, err := a.Db.Begin(ctx)
 	if err != nil {
-		return nil, ErrorAuthorDoesNotExist
+		return nil, fmt.Errorf("begin delete post: %w", err)
 	}
-	defer rows.Close()
+	defer func() { _ = tx.Rollback(ctx) }()
 
-	for ind := 0; rows.Next(); ind++ {
-		err = rows.Scan(&postsToReturn[ind].Id)
+	var post models.Post
+	var parentPath []int64
+	err = tx.QueryRow(ctx, LockPostCommand, id).Scan(&post.Id, &post.Parent, &post.Author, &post.Message, &post.IsEdited, &post.Forum, &post.Thread, &post.Created, &post.IsDeleted, &post.Votes, &parentPath)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrorPostDoesNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get post: %w", err)
+	}
 
+	result := &models.PostDeleteResult{}
+	if deleteSettings.Hard {
+		err = tx.QueryRow(ctx, HardDeletePostsCommand, post.Thread, len(parentPath), parentPath).Scan(&result.Deleted)
 		if err != nil {
-			return nil, ErrorAuthorDoesNotExist
+			return nil, fmt.Errorf("delete posts: %w", err)
 		}
+	} else {
+		if !post.IsDeleted {
+			if _, err = tx.Exec(ctx, SoftDeletePostCommand, id); err != nil {
+				return nil, fmt.Errorf("delete post: %w", err)
+			}
+			// у стёртого текста упоминаний нет
+			if err = setPostMentions(ctx, tx, &post, ""); err != nil {
+				return nil, err
+			}
+			result.Deleted = 1
+		}
+		post.Message = ""
+		post.IsDeleted = true
+		result.Post = &post
 	}
 
-	return &postsToReturn, nil
+	if result.Deleted > 0 {
+		if _, err = tx.Exec(ctx, DecrementForumPostsCommand, result.Deleted, post.Forum); err != nil {
+			return nil, fmt.Errorf("update forum counter: %w", err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit delete post: %w", err)
+	}
+
+	return result, nil
 }
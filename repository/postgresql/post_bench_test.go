@@ -0,0 +1,91 @@
+package postgresql
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"os"
+	"technopark-db-semester-project/db/migrations"
+	"technopark-db-semester-project/domain/models"
+	"testing"
+)
+
+// EnvTestDSN - база для бенчмарков. Бенчмарк очищает её целиком, поэтому рабочую базу сюда указывать нельзя
+const EnvTestDSN = "FORUM_TEST_DSN"
+
+// benchPostBatchSizes - размеры пачек по обе стороны от CopyPostsThreshold: ниже него посты идут одним INSERT, начиная с него - через COPY
+var benchPostBatchSizes = []int{100, CopyPostsThreshold - 1, CopyPostsThreshold, 5 * CopyPostsThreshold}
+
+// openBenchDb подключается к тестовой базе, накатывает миграции и очищает её. Без FORUM_TEST_DSN бенчмарк пропускается
+func openBenchDb(b *testing.B) *pgxpool.Pool {
+	dsn := os.Getenv(EnvTestDSN)
+	if dsn == "" {
+		b.Skipf("%s is not set", EnvTestDSN)
+	}
+
+	ctx := context.Background()
+	poolConfig, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		b.Fatal("parse dsn:", err)
+	}
+	poolConfig.AfterConnect = RegisterTypes
+	db, err := pgxpool.ConnectConfig(ctx, poolConfig)
+	if err != nil {
+		b.Fatal("connect:", err)
+	}
+	b.Cleanup(db.Close)
+
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		b.Fatal("load migrations:", err)
+	}
+	if _, err = migrator.Up(ctx); err != nil {
+		b.Fatal("migrate up:", err)
+	}
+	if err = NewServicePostgresRepo(db).Clear(ctx); err != nil {
+		b.Fatal("clear:", err)
+	}
+
+	return db
+}
+
+func BenchmarkPostPostgresRepoCreate(b *testing.B) {
+	db := openBenchDb(b)
+	ctx := context.Background()
+
+	resolver := &Resolver{}
+	users, forums := NewUserPostgresRepo(db), NewForumPostgresRepo(db)
+	threads := NewThreadPostgresRepo(db, resolver)
+	resolver.Threads, resolver.Users, resolver.Forums = threads, users, forums
+	repo := NewPostPostgresRepo(db, resolver)
+
+	if _, err := users.Create(ctx, &models.User{Nickname: "bench", Fullname: "Bench", Email: "bench@example.com"}); err != nil {
+		b.Fatal("create user:", err)
+	}
+	if _, err := forums.Create(ctx, &models.ForumCreate{Title: "Bench", User: "bench", Slug: "bench"}); err != nil {
+		b.Fatal("create forum:", err)
+	}
+
+	for _, size := range benchPostBatchSizes {
+		// b.Run вызывает функцию несколько раз, подбирая b.N, поэтому ветка создаётся один раз на размер
+		thread, err := threads.Create(ctx, "bench", &models.ThreadCreate{Title: "Bench", Author: "bench", Message: "bench", Slug: fmt.Sprintf("bench-%d", size)})
+		if err != nil {
+			b.Fatal("create thread:", err)
+		}
+		slug := thread.Slug
+
+		posts := make([]models.PostCreate, size)
+		for ind := range posts {
+			posts[ind] = models.PostCreate{Author: "bench", Message: fmt.Sprintf("post %d", ind)}
+		}
+
+		b.Run(fmt.Sprintf("posts=%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := repo.Create(ctx, slug, &posts); err != nil {
+					b.Fatal("create posts:", err)
+				}
+			}
+			b.ReportMetric(float64(size), "posts/op")
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package postgresql
+
+import "technopark-db-semester-project/domain"
+
+// Resolver - через него репозитории находят ветки, пользователей и форумы, на которые ссылаются посты и голоса.
+// Сначала это сами репозитории Postgres, а с кэшем system.InitRepos подставляет кэширующие, и пути чтения
+// (посты ветки, пост со связанными записями) не ходят в базу за известными записями. Запись через Resolver не проверяется:
+// состояние ветки, её форум и авторы должны читаться в транзакции записи
+type Resolver struct {
+	Threads domain.ThreadRepo
+	Users   domain.UserRepo
+	Forums  domain.ForumRepo
+}
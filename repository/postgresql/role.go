@@ -0,0 +1,109 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+)
+
+const (
+	GetForumRoleCommand    = "SELECT f.\"user\" = $2::citext, r.role FROM Forums f LEFT JOIN ForumRoles r ON r.forum = f.slug AND r.nickname = $2::citext WHERE f.slug = $1;"
+	GetForumRolesCommand   = "SELECT \"user\", 'owner' FROM Forums WHERE slug = $1 UNION ALL SELECT nickname, role FROM ForumRoles WHERE forum = $1 ORDER BY 1;"
+	SetForumRoleCommand    = "INSERT INTO ForumRoles (forum, nickname, role) SELECT f.slug, u.nickname, $3 FROM Forums f, Users u WHERE f.slug = $1 AND u.nickname = $2 ON CONFLICT (forum, nickname) DO UPDATE SET role = excluded.role RETURNING nickname;"
+	HasBannedAuthorCommand = "SELECT EXISTS (SELECT 1 FROM ForumRoles WHERE forum = $1 AND nickname = ANY($2::text[]::citext[]) AND role = 'banned');"
+)
+
+type RolePostgresRepo struct {
+	Db *pgxpool.Pool
+}
+
+func NewRolePostgresRepo(db *pgxpool.Pool) domain.RoleRepo {
+	return &RolePostgresRepo{Db: db}
+}
+
+func (a *RolePostgresRepo) Get(ctx context.Context, forumSlug string, nickname string) (string, error) {
+	var isOwner bool
+	var role *string
+	err := a.Db.QueryRow(ctx, GetForumRoleCommand, forumSlug, nickname).Scan(&isOwner, &role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", domain.ErrorForumDoesNotExist
+	}
+	if err != nil {
+		return "", fmt.Errorf("get forum role: %w", err)
+	}
+
+	switch {
+	case isOwner:
+		return models.RoleOwner, nil
+	case role != nil:
+		return *role, nil
+	default:
+		return models.RoleMember, nil
+	}
+}
+
+func (a *RolePostgresRepo) GetAll(ctx context.Context, forumSlug string) (*[]models.ForumRole, error) {
+	rows, err := a.Db.Query(ctx, GetForumRolesCommand, forumSlug)
+	if err != nil {
+		return nil, fmt.Errorf("get forum roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := make([]models.ForumRole, 0)
+	for rows.Next() {
+		var role models.ForumRole
+		if err = rows.Scan(&role.Nickname, &role.Role); err != nil {
+			return nil, fmt.Errorf("scan forum role: %w", err)
+		}
+		roles = append(roles, role)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("get forum roles: %w", err)
+	}
+
+	// у существующего форума всегда есть владелец
+	if len(roles) == 0 {
+		return nil, domain.ErrorForumDoesNotExist
+	}
+
+	return &roles, nil
+}
+
+func (a *RolePostgresRepo) Set(ctx context.Context, forumSlug string, role *models.ForumRole) (*models.ForumRole, error) {
+	var nickname string
+	err := a.Db.QueryRow(ctx, SetForumRoleCommand, forumSlug, role.Nickname, role.Role).Scan(&nickname)
+	if errors.Is(err, pgx.ErrNoRows) {
+		var forum models.Forum
+		if err = a.Db.QueryRow(ctx, GetForumCommand, forumSlug).Scan(&forum.Title, &forum.User, &forum.Slug, &forum.Posts, &forum.Threads); err != nil {
+			return nil, noRows(err, domain.ErrorForumDoesNotExist)
+		}
+		return nil, domain.ErrorUserDoesNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("set forum role: %w", err)
+	}
+
+	return &models.ForumRole{Nickname: nickname, Role: role.Role}, nil
+}
+
+// queryRower - общее у пула и транзакции
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// checkNotBanned возвращает domain.ErrorUserBanned, если кто-то из authors забанен в форуме
+func checkNotBanned(ctx context.Context, q queryRower, forumSlug string, authors []string) error {
+	var banned bool
+	if err := q.QueryRow(ctx, HasBannedAuthorCommand, forumSlug, authors).Scan(&banned); err != nil {
+		return fmt.Errorf("check banned authors: %w", err)
+	}
+	if banned {
+		return domain.ErrorUserBanned
+	}
+
+	return nil
+}
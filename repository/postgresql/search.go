@@ -0,0 +1,170 @@
+package postgresql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"strconv"
+	"strings"
+	"technopark-db-semester-project/domain"
+	"technopark-db-semester-project/domain/models"
+)
+
+const (
+	SearchHeadlineOptions = "StartSel=<b>, StopSel=</b>, MaxWords=25, MinWords=10, MaxFragments=2"
+
+	GetThreadIdBySlugCommand = "SELECT id FROM Threads WHERE slug = $1;"
+	GetThreadIdByIdCommand   = "SELECT id FROM Threads WHERE id = $1;"
+	CheckForumCommand        = "SELECT slug FROM Forums WHERE slug = $1;"
+)
+
+type SearchPostgresRepo struct {
+	Db *pgxpool.Pool
+}
+
+func NewSearchPostgresRepo(db *pgxpool.Pool) domain.SearchRepo {
+	return &SearchPostgresRepo{Db: db}
+}
+
+// searchQuery собирает WHERE и аргументы запроса по мере добавления фильтров
+type searchQuery struct {
+	args []any
+}
+
+func (a *searchQuery) arg(value any) string {
+	a.args = append(a.args, value)
+	return "$" + strconv.Itoa(len(a.args))
+}
+
+func (a *searchQuery) filters(request *models.SearchRequest, threadId int32, forumColumn string, threadColumn string, authorColumn string, createdColumn string) string {
+	conditions := strings.Builder{}
+	if request.Forum != "" {
+		fmt.Fprintf(&conditions, " AND %s = %s", forumColumn, a.arg(request.Forum))
+	}
+	if threadId != 0 {
+		fmt.Fprintf(&conditions, " AND %s = %s", threadColumn, a.arg(threadId))
+	}
+	if request.Author != "" {
+		fmt.Fprintf(&conditions, " AND %s = %s", authorColumn, a.arg(request.Author))
+	}
+	if request.Since != nil {
+		fmt.Fprintf(&conditions, " AND %s >= %s", createdColumn, a.arg(*request.Since))
+	}
+	if request.Until != nil {
+		fmt.Fprintf(&conditions, " AND %s <= %s", createdColumn, a.arg(*request.Until))
+	}
+
+	return conditions.String()
+}
+
+func (a *SearchPostgresRepo) resolveScope(ctx context.Context, request *models.SearchRequest) (int32, error) {
+	if request.Forum != "" {
+		var slug string
+		err := a.Db.QueryRow(ctx, CheckForumCommand, request.Forum).Scan(&slug)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, domain.ErrorForumDoesNotExist
+		}
+		if err != nil {
+			return 0, fmt.Errorf("check forum: %w", err)
+		}
+	}
+
+	if request.Thread == "" {
+		return 0, nil
+	}
+
+	var threadId int32
+	var err error
+	id, convErr := strconv.Atoi(request.Thread)
+	if convErr != nil {
+		err = a.Db.QueryRow(ctx, GetThreadIdBySlugCommand, request.Thread).Scan(&threadId)
+	} else {
+		err = a.Db.QueryRow(ctx, GetThreadIdByIdCommand, id).Scan(&threadId)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, domain.ErrorThreadDoesNotExist
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get thread: %w", err)
+	}
+
+	return threadId, nil
+}
+
+func (a *SearchPostgresRepo) Search(ctx context.Context, request *models.SearchRequest) (*models.SearchResult, error) {
+	if strings.TrimSpace(request.Query) == "" {
+		return nil, domain.ErrorEmptySearchQuery
+	}
+
+	threadId, err := a.resolveScope(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	query := &searchQuery{}
+	tsQuery := query.arg(request.Query)
+
+	parts := make([]string, 0, 2)
+	if request.Type == "" || request.Type == models.SearchTypePost {
+		parts = append(parts, "SELECT 'post'::text AS type, p.id, p.thread, p.forum::text AS forum, p.author::text AS author, ''::text AS title, p.message AS body, ts_rank(p.search, q.query) AS rank, p.created "+
+			"FROM Posts p, q WHERE p.search @@ q.query"+query.filters(request, threadId, "p.forum", "p.thread", "p.author", "p.created"))
+	}
+	if request.Type == "" || request.Type == models.SearchTypeThread {
+		parts = append(parts, "SELECT 'thread'::text AS type, t.id, t.id::integer AS thread, t.forum::text AS forum, t.author::text AS author, t.title, t.message AS body, ts_rank(t.search, q.query) AS rank, t.created "+
+			"FROM Threads t, q WHERE t.search @@ q.query"+query.filters(request, threadId, "t.forum", "t.id", "t.author", "t.created"))
+	}
+
+	// все ключи сортируются по убыванию, поэтому курсор сравнивается одним row-сравнением
+	orderBy := "rank DESC, created DESC, id DESC, type DESC"
+	cursorCondition := ""
+	if request.Sort == models.SearchSortDate {
+		orderBy = "created DESC, id DESC, type DESC"
+	}
+	if request.Cursor != nil {
+		if request.Sort == models.SearchSortDate {
+			cursorCondition = fmt.Sprintf("WHERE (created, id, type) < (%s, %s, %s)",
+				query.arg(request.Cursor.Created), query.arg(request.Cursor.Id), query.arg(request.Cursor.Type))
+		} else {
+			cursorCondition = fmt.Sprintf("WHERE (rank, created, id, type) < (%s::real, %s, %s, %s)",
+				query.arg(request.Cursor.Rank), query.arg(request.Cursor.Created), query.arg(request.Cursor.Id), query.arg(request.Cursor.Type))
+		}
+	}
+
+	// берём на одну запись больше, чтобы понять, есть ли следующая страница.
+	// ts_headline дорогой, поэтому считается только для попавших в страницу строк
+	sql := fmt.Sprintf("WITH q AS (SELECT websearch_to_tsquery('simple', %s) AS query) "+
+		"SELECT type, id, thread, forum, author, title, ts_headline('simple', body, (SELECT query FROM q), '%s'), rank, created FROM ("+
+		"SELECT * FROM (%s) AS hits %s ORDER BY %s LIMIT %s"+
+		") AS page ORDER BY %s;",
+		tsQuery, SearchHeadlineOptions, strings.Join(parts, " UNION ALL "), cursorCondition, orderBy, query.arg(request.Limit+1), orderBy)
+
+	rows, err := a.Db.Query(ctx, sql, query.args...)
+	if err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+	defer rows.Close()
+
+	hits := make([]models.SearchHit, 0, request.Limit)
+	for rows.Next() {
+		hit := models.SearchHit{}
+		err = rows.Scan(&hit.Type, &hit.Id, &hit.Thread, &hit.Forum, &hit.Author, &hit.Title, &hit.Snippet, &hit.Rank, &hit.Created)
+		if err != nil {
+			return nil, fmt.Errorf("search: %w", err)
+		}
+		hits = append(hits, hit)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+
+	result := &models.SearchResult{Hits: hits}
+	if len(hits) > int(request.Limit) {
+		result.Hits = hits[:request.Limit]
+		last := result.Hits[len(result.Hits)-1]
+		result.Next = &models.SearchCursor{Rank: last.Rank, Created: last.Created, Id: last.Id, Type: last.Type}
+	}
+
+	return result, nil
+}
@@ -2,14 +2,15 @@ package postgresql
 
 import (
 	"context"
+	"fmt"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"technopark-db-semester-project/domain"
 	"technopark-db-semester-project/domain/models"
 )
 
 const (
-	DeleteTablesCommand    = "TRUNCATE TABLE Users, Forums, Threads, Posts, ForumUsers, Votes CASCADE;"
-	GetCountRecordsCommand = "SELECT (SELECT count(*) FROM Users), (SELECT count(*) FROM Forums), (SELECT count(*) FROM Threads), (SELECT count(*) FROM Posts);"
+	DeleteTablesCommand    = "TRUNCATE TABLE Users, Forums, Threads, Posts, PostRevisions, ForumUsers, ForumRoles, Sessions, PasswordResets, Votes, PostVotes, ThreadSubscriptions, ForumSubscriptions, Notifications, Mentions CASCADE;"
+	GetCountRecordsCommand = "SELECT (SELECT count(*) FROM Users), (SELECT count(*) FROM Forums), (SELECT count(*) FROM Threads), (SELECT count(*) FROM Posts WHERE NOT isDeleted);"
 )
 
 type ServicePostgresRepo struct {
@@ -22,13 +23,18 @@ func NewServicePostgresRepo(db *pgxpool.Pool) domain.ServiceRepo {
 
 func (a *ServicePostgresRepo) GetInfo(ctx context.Context) (*models.Service, error) {
 	var result models.Service
-	_ = a.Db.QueryRow(ctx, GetCountRecordsCommand).Scan(&result.User, &result.Forum, &result.Thread, &result.Post)
+	err := a.Db.QueryRow(ctx, GetCountRecordsCommand).Scan(&result.User, &result.Forum, &result.Thread, &result.Post)
+	if err != nil {
+		return nil, fmt.Errorf("count records: %w", err)
+	}
 
 	return &result, nil
 }
 
 func (a *ServicePostgresRepo) Clear(ctx context.Context) error {
-	_, _ = a.Db.Exec(ctx, DeleteTablesCommand)
+	if _, err := a.Db.Exec(ctx, DeleteTablesCommand); err != nil {
+		return fmt.Errorf("clear tables: %w", err)
+	}
 
 	return nil
 }
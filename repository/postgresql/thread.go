@@ -3,6 +3,7 @@ package postgresql
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"strconv"
@@ -11,67 +12,117 @@ import (
 )
 
 type ThreadPostgresRepo struct {
-	Db *pgxpool.Pool
+	Db       *pgxpool.Pool
+	resolver *Resolver
 }
 
 const (
 	CreateThreadCommand     = "INSERT INTO Threads (title, author, message, created, slug, forum) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id;"
-	GetThreadByIdCommand    = "SELECT id, title, author, forum, message, votes, slug, created FROM Threads WHERE id = $1;"
-	GetThreadBySlugCommand  = "SELECT id, title, author, forum, message, votes, slug, created FROM Threads WHERE slug = $1;"
+	GetThreadByIdCommand    = "SELECT id, title, author, forum, message, votes, slug, created, state, pinned, announcement FROM Threads WHERE id = $1;"
+	GetThreadBySlugCommand  = "SELECT id, title, author, forum, message, votes, slug, created, state, pinned, announcement FROM Threads WHERE slug = $1;"
 	UpdateThreadByIdCommand = "UPDATE Threads SET (title, message) = ($1, $2) WHERE id = $3;"
+	SetThreadStateCommand   = "UPDATE Threads SET state = $1 WHERE id = $2;"
+	SetThreadPinCommand     = "UPDATE Threads SET (pinned, announcement) = ($1, $2) WHERE id = $3;"
 
-	GetPostsOnThreadFlatCommand                    = "SELECT id, parent, author, message, isEdited, forum, thread, created FROM Posts WHERE thread = $1 AND id > $2 ORDER BY created, id LIMIT $3;"
-	GetPostsOnThreadFlatDescCommand                = "SELECT id, parent, author, message, isEdited, forum, thread, created FROM Posts WHERE thread = $1 AND id < $2 ORDER BY created DESC, id DESC LIMIT $3;"
-	GetPostsOnThreadTreeCommand                    = "SELECT id, parent, author, message, isEdited, forum, thread, created FROM Posts WHERE thread = $1 AND parent_path > (SELECT parent_path FROM Posts WHERE id = $2) ORDER BY parent_path, id LIMIT $3;"
-	GetPostsOnThreadTreeDescCommand                = "SELECT id, parent, author, message, isEdited, forum, thread, created FROM Posts WHERE thread = $1 AND parent_path < (SELECT parent_path FROM Posts WHERE id = $2) ORDER BY parent_path DESC LIMIT $3;"
-	GetPostsOnThreadParentTreeCommand              = "SELECT id, parent, author, message, isEdited, forum, thread, created FROM Posts WHERE parent_path[1] IN (SELECT id FROM Posts WHERE thread = $1 AND parent = 0 AND id > (SELECT parent_path[1] FROM Posts WHERE id = $2) ORDER BY id LIMIT $3) ORDER BY parent_path, id;"
-	GetPostsOnThreadParentTreeDescWithSinceCommand = "SELECT id, parent, author, message, isEdited, forum, thread, created FROM Posts WHERE parent_path[1] IN (SELECT id FROM Posts WHERE thread = $1 AND parent = 0 AND id < (SELECT parent_path[1] FROM Posts WHERE id = $2) ORDER BY id DESC LIMIT $3) ORDER BY parent_path[1] DESC, parent_path, id;"
-
-	GetPostsOnThreadFlatWithoutSinceCommand           = "SELECT id, parent, author, message, isEdited, forum, thread, created FROM Posts WHERE thread = $1 ORDER BY created, id LIMIT $2;"
-	GetPostsOnThreadFlatDescWithoutSinceCommand       = "SELECT id, parent, author, message, isEdited, forum, thread, created FROM Posts WHERE thread = $1 ORDER BY created DESC, id DESC LIMIT $2;"
-	GetPostsOnThreadTreeWithoutSinceCommand           = "SELECT id, parent, author, message, isEdited, forum, thread, created FROM Posts WHERE thread = $1 ORDER BY parent_path, id LIMIT $2;"
-	GetPostsOnThreadTreeDescWithoutSinceCommand       = "SELECT id, parent, author, message, isEdited, forum, thread, created FROM Posts WHERE thread = $1 ORDER BY parent_path DESC LIMIT $2;"
-	GetPostsOnThreadParentTreeWithoutSinceCommand     = "SELECT id, parent, author, message, isEdited, forum, thread, created FROM Posts WHERE parent_path[1] IN (SELECT id FROM Posts WHERE thread = $1 AND parent = 0 ORDER BY id LIMIT $2) ORDER BY parent_path, id;"
-	GetPostsOnThreadParentTreeDescWithoutSinceCommand = "SELECT id, parent, author, message, isEdited, forum, thread, created FROM Posts WHERE parent_path[1] IN (SELECT id FROM Posts WHERE thread = $1 AND parent = 0 ORDER BY id DESC LIMIT $2) ORDER BY parent_path[1] DESC, parent_path, id;"
-)
+	GetPostThreadIdCommand     = "SELECT thread FROM Posts WHERE id = $1;"
+	LockThreadForUpdateCommand = "SELECT id, title, author, forum, message, votes, slug, created, state, pinned, announcement FROM Threads WHERE id = $1 FOR UPDATE;"
+	ShareThreadByIdCommand     = "SELECT id, title, author, forum, message, votes, slug, created, state, pinned, announcement FROM Threads WHERE id = $1 FOR KEY SHARE;"
+	ShareThreadBySlugCommand   = "SELECT id, title, author, forum, message, votes, slug, created, state, pinned, announcement FROM Threads WHERE slug = $1 FOR KEY SHARE;"
+	LockForumCommand           = "SELECT title, \"user\", slug, posts, threads FROM Forums WHERE slug = $1 FOR UPDATE;"
+	MoveThreadCommand          = "UPDATE Threads SET forum = $1 WHERE id = $2;"
+	MoveThreadPostsCommand     = "WITH moved AS (UPDATE Posts SET forum = $1 WHERE thread = $2 RETURNING author, isDeleted) SELECT count(*) FILTER (WHERE NOT isDeleted), coalesce(array_agg(DISTINCT author::text), '{}') FROM moved;"
+	MergeThreadPostsCommand    = "WITH moved AS (UPDATE Posts SET (thread, forum, parent, parent_path) = ($1, $2, CASE WHEN parent = 0 THEN $3::integer ELSE parent END, $3::bigint || parent_path) WHERE thread = $4 RETURNING author, isDeleted) SELECT count(*) FILTER (WHERE NOT isDeleted), coalesce(array_agg(DISTINCT author::text), '{}') FROM moved;"
+	SplitThreadPostsCommand    = "UPDATE Posts SET (thread, parent, parent_path) = ($1, CASE WHEN parent = $2 THEN 0 ELSE parent END, parent_path[$3 + 1:]) WHERE thread = $4 AND parent_path[1:$3] = $5 AND id != $2;"
+	InsertThreadPostCommand    = "INSERT INTO Posts (parent, author, message, forum, thread, created) VALUES (0, $1, $2, $3, $4, $5) RETURNING id;"
+	DeletePostCommand          = "DELETE FROM Posts WHERE id = $1;"
+	DeleteThreadVotesCommand   = "DELETE FROM Votes WHERE thread = $1;"
+	DeleteThreadCommand        = "DELETE FROM Threads WHERE id = $1;"
+	ChangeForumCountersCommand = "UPDATE Forums SET (threads, posts) = (threads + $1, posts + $2) WHERE slug = $3;"
+	AddForumUsersCommand       = "INSERT INTO ForumUsers (nickname, fullname, about, email, forum) SELECT nickname, fullname, about, email, $1 FROM Users WHERE nickname = ANY($2::text[]::citext[]) ON CONFLICT DO NOTHING;"
+	RemoveForumUsersCommand    = "DELETE FROM ForumUsers fu WHERE fu.forum = $1 AND fu.nickname = ANY($2::text[]::citext[]) AND NOT EXISTS (SELECT 1 FROM Threads t WHERE t.forum = $1 AND t.author = fu.nickname) AND NOT EXISTS (SELECT 1 FROM Posts p WHERE p.forum = $1 AND p.author = fu.nickname);"
+
+	GetPostsOnThreadFlatCommand                    = "SELECT id, parent, author, message, isEdited, forum, thread, created, isDeleted, votes, parent_path FROM Posts WHERE thread = $1 AND id > $2 ORDER BY created, id LIMIT $3;"
+	GetPostsOnThreadFlatDescCommand                = "SELECT id, parent, author, message, isEdited, forum, thread, created, isDeleted, votes, parent_path FROM Posts WHERE thread = $1 AND id < $2 ORDER BY created DESC, id DESC LIMIT $3;"
+	GetPostsOnThreadTreeCommand                    = "SELECT id, parent, author, message, isEdited, forum, thread, created, isDeleted, votes, parent_path FROM Posts WHERE thread = $1 AND parent_path > (SELECT parent_path FROM Posts WHERE id = $2) ORDER BY parent_path, id LIMIT $3;"
+	GetPostsOnThreadTreeDescCommand                = "SELECT id, parent, author, message, isEdited, forum, thread, created, isDeleted, votes, parent_path FROM Posts WHERE thread = $1 AND parent_path < (SELECT parent_path FROM Posts WHERE id = $2) ORDER BY parent_path DESC LIMIT $3;"
+	GetPostsOnThreadFlatAfterCommand               = "SELECT id, parent, author, message, isEdited, forum, thread, created, isDeleted, votes, parent_path FROM Posts WHERE thread = $1 AND (created, id) > ($2, $3) ORDER BY created, id LIMIT $4;"
+	GetPostsOnThreadFlatAfterDescCommand           = "SELECT id, parent, author, message, isEdited, forum, thread, created, isDeleted, votes, parent_path FROM Posts WHERE thread = $1 AND (created, id) < ($2, $3) ORDER BY created DESC, id DESC LIMIT $4;"
+	GetPostsOnThreadTreeAfterCommand               = "SELECT id, parent, author, message, isEdited, forum, thread, created, isDeleted, votes, parent_path FROM Posts WHERE thread = $1 AND parent_path > $2 ORDER BY parent_path, id LIMIT $3;"
+	GetPostsOnThreadTreeAfterDescCommand           = "SELECT id, parent, author, message, isEdited, forum, thread, created, isDeleted, votes, parent_path FROM Posts WHERE thread = $1 AND parent_path < $2 ORDER BY parent_path DESC LIMIT $3;"
+	GetPostsOnThreadParentTreeCommand              = "SELECT id, parent, author, message, isEdited, forum, thread, created, isDeleted, votes, parent_path FROM Posts WHERE parent_path[1] IN (SELECT id FROM Posts WHERE thread = $1 AND parent = 0 AND id > (SELECT parent_path[1] FROM Posts WHERE id = $2) ORDER BY id LIMIT $3) ORDER BY parent_path, id;"
+	GetPostsOnThreadParentTreeDescWithSinceCommand = "SELECT id, parent, author, message, isEdited, forum, thread, created, isDeleted, votes, parent_path FROM Posts WHERE parent_path[1] IN (SELECT id FROM Posts WHERE thread = $1 AND parent = 0 AND id < (SELECT parent_path[1] FROM Posts WHERE id = $2) ORDER BY id DESC LIMIT $3) ORDER BY parent_path[1] DESC, parent_path, id;"
+
+	// ключ сортировки - путь из пар (±votes, id) от корня, поэтому ответы одного уровня упорядочены по рейтингу, а
+	// поддерево идёт сразу за своим корнем. $2 = 1 - по возрастанию рейтинга, -1 - по убыванию. $3 - id поста, после которого продолжить.
+	// Обход начинается не со всех корней ветки, а с корня поста $3 и не дальше $4 + 1 корней: каждый корень даёт странице хотя бы одну строку
+	GetPostsOnThreadScoreCommand = "WITH RECURSIVE cursor_root AS (" +
+		"SELECT r.id, $2 * r.votes::bigint AS score FROM Posts c JOIN Posts r ON r.id = c.parent_path[1] WHERE c.id = $3 AND c.thread = $1" +
+		"), roots AS (" +
+		"SELECT id FROM Posts WHERE thread = $1 AND parent = 0 AND (NOT EXISTS (SELECT 1 FROM cursor_root) OR ($2 * votes::bigint, id) >= (SELECT score, id FROM cursor_root)) " +
+		"ORDER BY $2 * votes::bigint, id LIMIT $4 + 1" +
+		"), tree AS (" +
+		"SELECT p.id, p.parent, p.author, p.message, p.isEdited, p.forum, p.thread, p.created, p.isDeleted, p.votes, p.parent_path, ARRAY [$2 * p.votes::bigint, p.id] AS score_path FROM Posts p JOIN roots r ON r.id = p.id " +
+		"UNION ALL " +
+		"SELECT p.id, p.parent, p.author, p.message, p.isEdited, p.forum, p.thread, p.created, p.isDeleted, p.votes, p.parent_path, t.score_path || ARRAY [$2 * p.votes::bigint, p.id] FROM Posts p JOIN tree t ON p.parent = t.id WHERE p.thread = $1" +
+		") SELECT id, parent, author, message, isEdited, forum, thread, created, isDeleted, votes, parent_path FROM tree " +
+		"WHERE score_path > coalesce((SELECT score_path FROM tree WHERE id = $3), '{}') ORDER BY score_path LIMIT $4;"
 
-var (
-	ErrorNoAuthorOrForum    = errors.New("author or forum does not exist")
-	ErrorThreadAlreadyExist = errors.New("thread already exist")
-	ErrorThreadDoesNotExist = errors.New("thread does not exist")
+	GetPostsOnThreadFlatWithoutSinceCommand           = "SELECT id, parent, author, message, isEdited, forum, thread, created, isDeleted, votes, parent_path FROM Posts WHERE thread = $1 ORDER BY created, id LIMIT $2;"
+	GetPostsOnThreadFlatDescWithoutSinceCommand       = "SELECT id, parent, author, message, isEdited, forum, thread, created, isDeleted, votes, parent_path FROM Posts WHERE thread = $1 ORDER BY created DESC, id DESC LIMIT $2;"
+	GetPostsOnThreadTreeWithoutSinceCommand           = "SELECT id, parent, author, message, isEdited, forum, thread, created, isDeleted, votes, parent_path FROM Posts WHERE thread = $1 ORDER BY parent_path, id LIMIT $2;"
+	GetPostsOnThreadTreeDescWithoutSinceCommand       = "SELECT id, parent, author, message, isEdited, forum, thread, created, isDeleted, votes, parent_path FROM Posts WHERE thread = $1 ORDER BY parent_path DESC LIMIT $2;"
+	GetPostsOnThreadParentTreeWithoutSinceCommand     = "SELECT id, parent, author, message, isEdited, forum, thread, created, isDeleted, votes, parent_path FROM Posts WHERE parent_path[1] IN (SELECT id FROM Posts WHERE thread = $1 AND parent = 0 ORDER BY id LIMIT $2) ORDER BY parent_path, id;"
+	GetPostsOnThreadParentTreeDescWithoutSinceCommand = "SELECT id, parent, author, message, isEdited, forum, thread, created, isDeleted, votes, parent_path FROM Posts WHERE parent_path[1] IN (SELECT id FROM Posts WHERE thread = $1 AND parent = 0 ORDER BY id DESC LIMIT $2) ORDER BY parent_path[1] DESC, parent_path, id;"
 )
 
-func NewThreadPostgresRepo(db *pgxpool.Pool) domain.ThreadRepo {
-	return &ThreadPostgresRepo{Db: db}
+func NewThreadPostgresRepo(db *pgxpool.Pool, resolver *Resolver) domain.ThreadRepo {
+	return &ThreadPostgresRepo{Db: db, resolver: resolver}
 }
 
 func (a *ThreadPostgresRepo) Create(ctx context.Context, forumSlug string, thread *models.ThreadCreate) (*models.Thread, error) {
 	var forum models.Forum
 	err := a.Db.QueryRow(ctx, GetForumCommand, forumSlug).Scan(&forum.Title, &forum.User, &forum.Slug, &forum.Posts, &forum.Threads)
 	if err != nil {
-		return nil, ErrorNoAuthorOrForum
+		return nil, noRows(err, domain.ErrorNoAuthorOrForum)
 	}
 
 	var user models.User
 	err = a.Db.QueryRow(ctx, GetUserByNicknameCommand, thread.Author).Scan(&user.Nickname, &user.Fullname, &user.About, &user.Email)
 	if err != nil {
-		return nil, ErrorNoAuthorOrForum
+		return nil, noRows(err, domain.ErrorNoAuthorOrForum)
 	}
 	thread.Forum = forum.Slug
 
+	if err = checkNotBanned(ctx, a.Db, forum.Slug, []string{thread.Author}); err != nil {
+		return nil, err
+	}
+
 	if thread.Slug != "" {
 		var threadAlreadyExist models.Thread
-		err = a.Db.QueryRow(ctx, GetThreadBySlugCommand, thread.Slug).Scan(&threadAlreadyExist.Id, &threadAlreadyExist.Title, &threadAlreadyExist.Author, &threadAlreadyExist.Forum, &threadAlreadyExist.Message, &threadAlreadyExist.Votes, &threadAlreadyExist.Slug, &threadAlreadyExist.Created)
+		err = a.Db.QueryRow(ctx, GetThreadBySlugCommand, thread.Slug).Scan(&threadAlreadyExist.Id, &threadAlreadyExist.Title, &threadAlreadyExist.Author, &threadAlreadyExist.Forum, &threadAlreadyExist.Message, &threadAlreadyExist.Votes, &threadAlreadyExist.Slug, &threadAlreadyExist.Created, &threadAlreadyExist.State, &threadAlreadyExist.Pinned, &threadAlreadyExist.Announcement)
 		if err == nil {
-			return &threadAlreadyExist, ErrorThreadAlreadyExist
+			return &threadAlreadyExist, domain.ErrorThreadAlreadyExist
 		}
 	}
 
+	// ветка и упоминания в её тексте создаются вместе
+	tx, err := a.Db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin create thread: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
 	var id int32
-	err = a.Db.QueryRow(ctx, CreateThreadCommand, thread.Title, thread.Author, thread.Message, thread.Created, thread.Slug, thread.Forum).Scan(&id)
+	err = tx.QueryRow(ctx, CreateThreadCommand, thread.Title, thread.Author, thread.Message, thread.Created, thread.Slug, thread.Forum).Scan(&id)
+	if isUniqueViolation(err) {
+		threadAlreadyExist, err := a.Get(ctx, thread.Slug)
+		if err != nil {
+			return nil, err
+		}
+		return threadAlreadyExist, domain.ErrorThreadAlreadyExist
+	}
 	if err != nil {
-		threadAlreadyExist, _ := a.Get(ctx, thread.Slug)
-		return threadAlreadyExist, ErrorThreadAlreadyExist
+		return nil, fmt.Errorf("create thread: %w", err)
 	}
 
 	threadToReturn := &models.Thread{
@@ -83,6 +134,14 @@ func (a *ThreadPostgresRepo) Create(ctx context.Context, forumSlug string, threa
 		Votes:   0,
 		Slug:    thread.Slug,
 		Created: thread.Created,
+		State:   models.ThreadOpen,
+	}
+	if err = addThreadMentions(ctx, tx, threadToReturn); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit create thread: %w", err)
 	}
 
 	return threadToReturn, nil
@@ -93,13 +152,13 @@ func (a *ThreadPostgresRepo) Get(ctx context.Context, threadSlugOrId string) (*m
 	id, err := strconv.Atoi(threadSlugOrId)
 
 	if err != nil {
-		err = a.Db.QueryRow(ctx, GetThreadBySlugCommand, threadSlugOrId).Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &thread.Slug, &thread.Created)
+		err = a.Db.QueryRow(ctx, GetThreadBySlugCommand, threadSlugOrId).Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &thread.Slug, &thread.Created, &thread.State, &thread.Pinned, &thread.Announcement)
 	} else {
-		err = a.Db.QueryRow(ctx, GetThreadByIdCommand, id).Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &thread.Slug, &thread.Created)
+		err = a.Db.QueryRow(ctx, GetThreadByIdCommand, id).Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &thread.Slug, &thread.Created, &thread.State, &thread.Pinned, &thread.Announcement)
 	}
 
 	if err != nil {
-		return nil, ErrorThreadDoesNotExist
+		return nil, noRows(err, domain.ErrorThreadDoesNotExist)
 	}
 
 	return &thread, nil
@@ -108,9 +167,10 @@ func (a *ThreadPostgresRepo) Get(ctx context.Context, threadSlugOrId string) (*m
 func (a *ThreadPostgresRepo) Update(ctx context.Context, threadSlugOrId string, updateData *models.ThreadUpdate) (*models.Thread, error) {
 	thread, err := a.Get(ctx, threadSlugOrId)
 	if err != nil {
-		return nil, ErrorThreadDoesNotExist
+		return nil, err
 	}
 
+	messageChanged := updateData.Message != "" && updateData.Message != thread.Message
 	if updateData.Message == "" {
 		updateData.Message = thread.Message
 	} else {
@@ -122,61 +182,146 @@ func (a *ThreadPostgresRepo) Update(ctx context.Context, threadSlugOrId string,
 		thread.Title = updateData.Title
 	}
 
-	_, _ = a.Db.Exec(ctx, UpdateThreadByIdCommand, updateData.Title, updateData.Message, thread.Id)
+	if !messageChanged {
+		if _, err = a.Db.Exec(ctx, UpdateThreadByIdCommand, updateData.Title, updateData.Message, thread.Id); err != nil {
+			return nil, fmt.Errorf("update thread: %w", err)
+		}
+		return thread, nil
+	}
+
+	// при правке текста упоминания обновляются в той же транзакции
+	tx, err := a.Db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin update thread: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err = tx.Exec(ctx, UpdateThreadByIdCommand, updateData.Title, updateData.Message, thread.Id); err != nil {
+		return nil, fmt.Errorf("update thread: %w", err)
+	}
+	if err = setThreadMentions(ctx, tx, thread); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit update thread: %w", err)
+	}
+
+	return thread, nil
+}
+
+func (a *ThreadPostgresRepo) SetState(ctx context.Context, threadSlugOrId string, stateUpdate *models.ThreadStateUpdate) (*models.Thread, error) {
+	thread, err := a.Get(ctx, threadSlugOrId)
+	if err != nil {
+		return nil, err
+	}
+
+	if thread.State == stateUpdate.State {
+		return thread, nil
+	}
+
+	if _, err = a.Db.Exec(ctx, SetThreadStateCommand, stateUpdate.State, thread.Id); err != nil {
+		return nil, fmt.Errorf("set thread state: %w", err)
+	}
+	thread.State = stateUpdate.State
+
+	return thread, nil
+}
+
+func (a *ThreadPostgresRepo) SetPin(ctx context.Context, threadSlugOrId string, pin *models.ThreadPin) (*models.Thread, error) {
+	thread, err := a.Get(ctx, threadSlugOrId)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = a.Db.Exec(ctx, SetThreadPinCommand, pin.Pinned, pin.Announcement, thread.Id); err != nil {
+		return nil, fmt.Errorf("set thread pin: %w", err)
+	}
+	thread.Pinned = pin.Pinned
+	thread.Announcement = pin.Announcement
 
 	return thread, nil
 }
 
 func (a *ThreadPostgresRepo) GetPosts(ctx context.Context, slugOrId string, getSettings *models.ThreadPostRequest) (*[]models.Post, error) {
-	thread, err := a.Get(ctx, slugOrId)
+	thread, err := a.resolver.Threads.Get(ctx, slugOrId)
 	if err != nil {
-		return nil, ErrorThreadDoesNotExist
+		return nil, err
 	}
 
 	var rows pgx.Rows
 
-	if getSettings.Sort == models.Flat {
+	// в курсоре parent_tree лежит id корневого поста, а в курсоре score - id поста, для них он работает как since
+	since, after := getSettings.Since, getSettings.After
+	if after != nil && (getSettings.Sort == models.ParentTree || getSettings.Sort == models.Score) {
+		since, after = after.Id, nil
+	}
+
+	if after != nil && getSettings.Sort == models.Flat {
 		if getSettings.Desc {
-			if getSettings.Since != -1 {
-				rows, _ = a.Db.Query(ctx, GetPostsOnThreadFlatDescCommand, thread.Id, getSettings.Since, getSettings.Limit)
+			rows, err = a.Db.Query(ctx, GetPostsOnThreadFlatAfterDescCommand, thread.Id, after.Created, after.Id, getSettings.Limit)
+		} else {
+			rows, err = a.Db.Query(ctx, GetPostsOnThreadFlatAfterCommand, thread.Id, after.Created, after.Id, getSettings.Limit)
+		}
+	} else if after != nil && getSettings.Sort == models.Tree {
+		if getSettings.Desc {
+			rows, err = a.Db.Query(ctx, GetPostsOnThreadTreeAfterDescCommand, thread.Id, after.Path, getSettings.Limit)
+		} else {
+			rows, err = a.Db.Query(ctx, GetPostsOnThreadTreeAfterCommand, thread.Id, after.Path, getSettings.Limit)
+		}
+	} else if getSettings.Sort == models.Flat {
+		if getSettings.Desc {
+			if since != -1 {
+				rows, err = a.Db.Query(ctx, GetPostsOnThreadFlatDescCommand, thread.Id, since, getSettings.Limit)
 			} else {
-				rows, _ = a.Db.Query(ctx, GetPostsOnThreadFlatDescWithoutSinceCommand, thread.Id, getSettings.Limit)
+				rows, err = a.Db.Query(ctx, GetPostsOnThreadFlatDescWithoutSinceCommand, thread.Id, getSettings.Limit)
 			}
 		} else {
-			if getSettings.Since != -1 {
-				rows, _ = a.Db.Query(ctx, GetPostsOnThreadFlatCommand, thread.Id, getSettings.Since, getSettings.Limit)
+			if since != -1 {
+				rows, err = a.Db.Query(ctx, GetPostsOnThreadFlatCommand, thread.Id, since, getSettings.Limit)
 			} else {
-				rows, _ = a.Db.Query(ctx, GetPostsOnThreadFlatWithoutSinceCommand, thread.Id, getSettings.Limit)
+				rows, err = a.Db.Query(ctx, GetPostsOnThreadFlatWithoutSinceCommand, thread.Id, getSettings.Limit)
 			}
 		}
 	} else if getSettings.Sort == models.Tree {
 		if getSettings.Desc {
-			if getSettings.Since != -1 {
-				rows, _ = a.Db.Query(ctx, GetPostsOnThreadTreeDescCommand, thread.Id, getSettings.Since, getSettings.Limit)
+			if since != -1 {
+				rows, err = a.Db.Query(ctx, GetPostsOnThreadTreeDescCommand, thread.Id, since, getSettings.Limit)
 			} else {
-				rows, _ = a.Db.Query(ctx, GetPostsOnThreadTreeDescWithoutSinceCommand, thread.Id, getSettings.Limit)
+				rows, err = a.Db.Query(ctx, GetPostsOnThreadTreeDescWithoutSinceCommand, thread.Id, getSettings.Limit)
 			}
 		} else {
-			if getSettings.Since != -1 {
-				rows, _ = a.Db.Query(ctx, GetPostsOnThreadTreeCommand, thread.Id, getSettings.Since, getSettings.Limit)
+			if since != -1 {
+				rows, err = a.Db.Query(ctx, GetPostsOnThreadTreeCommand, thread.Id, since, getSettings.Limit)
 			} else {
-				rows, _ = a.Db.Query(ctx, GetPostsOnThreadTreeWithoutSinceCommand, thread.Id, getSettings.Limit)
+				rows, err = a.Db.Query(ctx, GetPostsOnThreadTreeWithoutSinceCommand, thread.Id, getSettings.Limit)
 			}
 		}
 	} else if getSettings.Sort == models.ParentTree {
 		if getSettings.Desc {
-			if getSettings.Since > 0 {
-				rows, _ = a.Db.Query(ctx, GetPostsOnThreadParentTreeDescWithSinceCommand, thread.Id, getSettings.Since, getSettings.Limit)
+			if since > 0 {
+				rows, err = a.Db.Query(ctx, GetPostsOnThreadParentTreeDescWithSinceCommand, thread.Id, since, getSettings.Limit)
 			} else {
-				rows, _ = a.Db.Query(ctx, GetPostsOnThreadParentTreeDescWithoutSinceCommand, thread.Id, getSettings.Limit)
+				rows, err = a.Db.Query(ctx, GetPostsOnThreadParentTreeDescWithoutSinceCommand, thread.Id, getSettings.Limit)
 			}
 		} else {
-			if getSettings.Since != -1 {
-				rows, _ = a.Db.Query(ctx, GetPostsOnThreadParentTreeCommand, thread.Id, getSettings.Since, getSettings.Limit)
+			if since != -1 {
+				rows, err = a.Db.Query(ctx, GetPostsOnThreadParentTreeCommand, thread.Id, since, getSettings.Limit)
 			} else {
-				rows, _ = a.Db.Query(ctx, GetPostsOnThreadParentTreeWithoutSinceCommand, thread.Id, getSettings.Limit)
+				rows, err = a.Db.Query(ctx, GetPostsOnThreadParentTreeWithoutSinceCommand, thread.Id, getSettings.Limit)
 			}
 		}
+	} else if getSettings.Sort == models.Score {
+		direction := 1
+		if getSettings.Desc {
+			direction = -1
+		}
+		rows, err = a.Db.Query(ctx, GetPostsOnThreadScoreCommand, thread.Id, direction, since, getSettings.Limit)
+	} else {
+		return nil, fmt.Errorf("unknown posts sort %q", getSettings.Sort)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get thread posts: %w", err)
 	}
 	defer rows.Close()
 
@@ -184,9 +329,287 @@ func (a *ThreadPostgresRepo) GetPosts(ctx context.Context, slugOrId string, getS
 
 	for rows.Next() {
 		post := models.Post{}
-		_ = rows.Scan(&post.Id, &post.Parent, &post.Author, &post.Message, &post.IsEdited, &post.Forum, &post.Thread, &post.Created)
+		if err = rows.Scan(&post.Id, &post.Parent, &post.Author, &post.Message, &post.IsEdited, &post.Forum, &post.Thread, &post.Created, &post.IsDeleted, &post.Votes, &post.Path); err != nil {
+			return nil, fmt.Errorf("get thread posts: %w", err)
+		}
 		posts = append(posts, post)
 	}
 
-	return &posts, nil
+	return &posts, rows.Err()
+}
+
+// lockThread блокирует ветку до конца транзакции
+func lockThread(ctx context.Context, tx pgx.Tx, id int32) (*models.Thread, error) {
+	var thread models.Thread
+	err := tx.QueryRow(ctx, LockThreadForUpdateCommand, id).Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &thread.Slug, &thread.Created, &thread.State, &thread.Pinned, &thread.Announcement)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrorThreadDoesNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("lock thread: %w", err)
+	}
+
+	return &thread, nil
+}
+
+// shareThread читает ветку внутри транзакции записи и блокирует её FOR KEY SHARE до конца транзакции: перенос, слияние
+// и удаление ветки (FOR UPDATE) ждут её окончания, а состояние и форум проверяются по строке из той же транзакции, не из кэша
+func shareThread(ctx context.Context, tx pgx.Tx, threadSlugOrId string) (*models.Thread, error) {
+	var thread models.Thread
+	id, err := strconv.Atoi(threadSlugOrId)
+	if err != nil {
+		err = tx.QueryRow(ctx, ShareThreadBySlugCommand, threadSlugOrId).Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &thread.Slug, &thread.Created, &thread.State, &thread.Pinned, &thread.Announcement)
+	} else {
+		err = tx.QueryRow(ctx, ShareThreadByIdCommand, id).Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &thread.Slug, &thread.Created, &thread.State, &thread.Pinned, &thread.Announcement)
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrorThreadDoesNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get thread: %w", err)
+	}
+
+	return &thread, nil
+}
+
+// moveForumContent переносит счётчики форума from в форум to и пересчитывает ForumUsers обоих форумов для authors
+func moveForumContent(ctx context.Context, tx pgx.Tx, from, to string, threads, posts int64, authors []string) error {
+	if _, err := tx.Exec(ctx, ChangeForumCountersCommand, -threads, -posts, from); err != nil {
+		return fmt.Errorf("update forum counters: %w", err)
+	}
+	if _, err := tx.Exec(ctx, ChangeForumCountersCommand, threads, posts, to); err != nil {
+		return fmt.Errorf("update forum counters: %w", err)
+	}
+	if _, err := tx.Exec(ctx, AddForumUsersCommand, to, authors); err != nil {
+		return fmt.Errorf("add forum users: %w", err)
+	}
+	if _, err := tx.Exec(ctx, RemoveForumUsersCommand, from, authors); err != nil {
+		return fmt.Errorf("remove forum users: %w", err)
+	}
+
+	return nil
+}
+
+// Move переносит ветку со всеми сообщениями в другой форум
+func (a *ThreadPostgresRepo) Move(ctx context.Context, threadSlugOrId string, move *models.ThreadMove) (*models.Thread, error) {
+	found, err := a.Get(ctx, threadSlugOrId)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := a.Db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin move thread: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	thread, err := lockThread(ctx, tx, found.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	var forum models.Forum
+	err = tx.QueryRow(ctx, LockForumCommand, move.Forum).Scan(&forum.Title, &forum.User, &forum.Slug, &forum.Posts, &forum.Threads)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrorForumDoesNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get forum: %w", err)
+	}
+
+	if forum.Slug == thread.Forum {
+		return thread, nil
+	}
+
+	if _, err = tx.Exec(ctx, MoveThreadCommand, forum.Slug, thread.Id); err != nil {
+		return nil, fmt.Errorf("move thread: %w", err)
+	}
+
+	var posts int64
+	var authors []string
+	if err = tx.QueryRow(ctx, MoveThreadPostsCommand, forum.Slug, thread.Id).Scan(&posts, &authors); err != nil {
+		return nil, fmt.Errorf("move thread posts: %w", err)
+	}
+
+	authors = append(authors, thread.Author)
+	if err = moveForumContent(ctx, tx, thread.Forum, forum.Slug, 1, posts, authors); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit move thread: %w", err)
+	}
+	thread.Forum = forum.Slug
+
+	return thread, nil
+}
+
+// Merge переносит сообщения ветки в ветку merge.Into и удаляет её. Текст ветки становится корневым постом,
+// а её корневые посты - ответами на него, поэтому parent_path всех перенесённых постов получает новый префикс
+func (a *ThreadPostgresRepo) Merge(ctx context.Context, threadSlugOrId string, merge *models.ThreadMerge) (*models.Thread, error) {
+	foundSource, err := a.Get(ctx, threadSlugOrId)
+	if err != nil {
+		return nil, err
+	}
+	foundTarget, err := a.Get(ctx, merge.Into)
+	if err != nil {
+		return nil, err
+	}
+	if foundSource.Id == foundTarget.Id {
+		return nil, domain.ErrorMergeSameThread
+	}
+
+	tx, err := a.Db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin merge threads: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// ветки блокируются в порядке id, чтобы встречные слияния не взаимоблокировались
+	var source, target *models.Thread
+	if foundSource.Id < foundTarget.Id {
+		if source, err = lockThread(ctx, tx, foundSource.Id); err == nil {
+			target, err = lockThread(ctx, tx, foundTarget.Id)
+		}
+	} else {
+		if target, err = lockThread(ctx, tx, foundTarget.Id); err == nil {
+			source, err = lockThread(ctx, tx, foundSource.Id)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// триггеры сами выставят parent_path, счётчик постов и ForumUsers форума target
+	var rootId int64
+	err = tx.QueryRow(ctx, InsertThreadPostCommand, source.Author, source.Message, target.Forum, target.Id, source.Created).Scan(&rootId)
+	if err != nil {
+		return nil, fmt.Errorf("insert thread post: %w", err)
+	}
+
+	var posts int64
+	var authors []string
+	if err = tx.QueryRow(ctx, MergeThreadPostsCommand, target.Id, target.Forum, rootId, source.Id).Scan(&posts, &authors); err != nil {
+		return nil, fmt.Errorf("merge thread posts: %w", err)
+	}
+
+	if _, err = tx.Exec(ctx, DeleteThreadVotesCommand, source.Id); err != nil {
+		return nil, fmt.Errorf("delete thread votes: %w", err)
+	}
+	if _, err = tx.Exec(ctx, ThreadMentionsToPostCommand, rootId, source.Id); err != nil {
+		return nil, fmt.Errorf("move thread mentions: %w", err)
+	}
+	// подписки ветки удалятся вместе с ней каскадно, поэтому сначала копируются в target
+	if _, err = tx.Exec(ctx, MoveSubscriptionsCommand, target.Id, source.Id); err != nil {
+		return nil, fmt.Errorf("move thread subscriptions: %w", err)
+	}
+	if _, err = tx.Exec(ctx, DeleteThreadCommand, source.Id); err != nil {
+		return nil, fmt.Errorf("delete thread: %w", err)
+	}
+
+	authors = append(authors, source.Author)
+	if err = moveForumContent(ctx, tx, source.Forum, target.Forum, 0, posts, authors); err != nil {
+		return nil, err
+	}
+	if _, err = tx.Exec(ctx, ChangeForumCountersCommand, -1, 0, source.Forum); err != nil {
+		return nil, fmt.Errorf("update forum counters: %w", err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit merge threads: %w", err)
+	}
+
+	return target, nil
+}
+
+// SplitAttempts - сколько раз Split повторяется, если пост успели перенести в другую ветку
+const SplitAttempts = 3
+
+// Split выносит пост с его поддеревом в новую ветку того же форума. Текст поста становится текстом ветки,
+// ответы на него - корневыми постами, а parent_path поддерева теряет общий префикс
+func (a *ThreadPostgresRepo) Split(ctx context.Context, postId int64, split *models.ThreadSplit) (*models.Thread, error) {
+	for attempt := 1; ; attempt++ {
+		thread, err := a.split(ctx, postId, split)
+		if !errors.Is(err, domain.ErrorSplitPostMoving) || attempt == SplitAttempts {
+			return thread, err
+		}
+	}
+}
+
+// split делает одну попытку разделения. Если пост перенесли в другую ветку, пока его ветка блокировалась, возвращает ErrorSplitPostMoving
+func (a *ThreadPostgresRepo) split(ctx context.Context, postId int64, split *models.ThreadSplit) (*models.Thread, error) {
+	var threadId int32
+	err := a.Db.QueryRow(ctx, GetPostThreadIdCommand, postId).Scan(&threadId)
+	if err != nil {
+		return nil, noRows(err, domain.ErrorPostDoesNotExist)
+	}
+
+	tx, err := a.Db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin split thread: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// ветка блокируется раньше поста, в том же порядке, что и при создании постов
+	if _, err = lockThread(ctx, tx, threadId); err != nil {
+		return nil, err
+	}
+
+	var post models.Post
+	var parentPath []int64
+	err = tx.QueryRow(ctx, LockPostCommand, postId).Scan(&post.Id, &post.Parent, &post.Author, &post.Message, &post.IsEdited, &post.Forum, &post.Thread, &post.Created, &post.IsDeleted, &post.Votes, &parentPath)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrorPostDoesNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get post: %w", err)
+	}
+	if post.Thread != threadId {
+		// пост успели вынести в другую ветку, Split повторит попытку уже с ней
+		return nil, domain.ErrorSplitPostMoving
+	}
+
+	thread := &models.Thread{
+		Title:   split.Title,
+		Author:  post.Author,
+		Forum:   post.Forum,
+		Message: post.Message,
+		Slug:    split.Slug,
+		Created: post.Created,
+		State:   models.ThreadOpen,
+	}
+	// занятый slug отсекает уникальный индекс threads_slug_unique, в том числе при параллельных разделениях
+	err = tx.QueryRow(ctx, CreateThreadCommand, thread.Title, thread.Author, thread.Message, thread.Created, thread.Slug, thread.Forum).Scan(&thread.Id)
+	if isUniqueViolation(err) {
+		_ = tx.Rollback(ctx)
+		threadAlreadyExist, err := a.Get(ctx, split.Slug)
+		if err != nil {
+			return nil, err
+		}
+		return threadAlreadyExist, domain.ErrorThreadAlreadyExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create thread: %w", err)
+	}
+
+	if _, err = tx.Exec(ctx, SplitThreadPostsCommand, thread.Id, post.Id, len(parentPath), post.Thread, parentPath); err != nil {
+		return nil, fmt.Errorf("split thread posts: %w", err)
+	}
+	if _, err = tx.Exec(ctx, PostMentionsToThreadCommand, thread.Id, post.Id); err != nil {
+		return nil, fmt.Errorf("move post mentions: %w", err)
+	}
+	if _, err = tx.Exec(ctx, DeletePostCommand, post.Id); err != nil {
+		return nil, fmt.Errorf("delete post: %w", err)
+	}
+	if !post.IsDeleted {
+		if _, err = tx.Exec(ctx, DecrementForumPostsCommand, 1, post.Forum); err != nil {
+			return nil, fmt.Errorf("update forum counter: %w", err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit split thread: %w", err)
+	}
+
+	return thread, nil
 }
@@ -0,0 +1,24 @@
+package postgresql
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const GetCitextOidCommand = "SELECT to_regtype('citext')::oid;"
+
+// RegisterTypes регистрирует citext как текст, иначе pgx не сможет передавать строки в такие колонки через COPY.
+// Используется как AfterConnect пула; если расширение ещё не создано, ничего не делает
+func RegisterTypes(ctx context.Context, conn *pgx.Conn) error {
+	var oid *uint32
+	if err := conn.QueryRow(ctx, GetCitextOidCommand).Scan(&oid); err != nil {
+		return err
+	}
+
+	if oid != nil {
+		conn.TypeMap().RegisterType(&pgtype.Type{Name: "citext", OID: *oid, Codec: pgtype.TextCodec{}})
+	}
+
+	return nil
+}
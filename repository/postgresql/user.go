@@ -2,26 +2,20 @@ package postgresql
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"technopark-db-semester-project/domain"
 	"technopark-db-semester-project/domain/models"
 )
 
 const (
-	CreateUserCommand               = "INSERT INTO Users (nickname, fullname, about, email) VALUES ($1, $2, $3, $4);"
+	CreateUserCommand               = "INSERT INTO Users (nickname, fullname, about, email, password_hash) VALUES ($1, $2, $3, $4, $5);"
 	UpdateUserCommand               = "UPDATE Users SET (fullname, about, email) = ($1, $2, $3) WHERE nickname = $4;"
 	GetUserByNicknameCommand        = "SELECT nickname, fullname, about, email FROM Users WHERE nickname = $1;"
 	GetUserByEmailCommand           = "SELECT nickname, fullname, about, email FROM Users WHERE email = $1;"
 	GetUserByNicknameOrEmailCommand = "SELECT nickname, fullname, about, email FROM Users WHERE nickname = $1 OR email = $2;"
 )
 
-var (
-	ErrorUserAlreadyExist   = errors.New("user already exist")
-	ErrorUserDoesNotExist   = errors.New("user does not exist")
-	ErrorConflictUpdateUser = errors.New("data conflicts with existing users")
-)
-
 type UserPostgresRepo struct {
 	Db *pgxpool.Pool
 }
@@ -50,14 +44,16 @@ func (a *UserPostgresRepo) getUserByNicknameOrEmail(ctx context.Context, nicknam
 }
 
 func (a *UserPostgresRepo) Create(ctx context.Context, user *models.User) (*[]models.User, error) {
-	_, err := a.Db.Exec(ctx, CreateUserCommand, user.Nickname, user.Fullname, user.About, user.Email)
-	if err != nil {
+	_, err := a.Db.Exec(ctx, CreateUserCommand, user.Nickname, user.Fullname, user.About, user.Email, user.PasswordHash)
+	if isUniqueViolation(err) {
 		checkAlreadyExist, err := a.getUserByNicknameOrEmail(ctx, user.Nickname, user.Email)
-		if err == nil && len(*checkAlreadyExist) > 0 {
-			return checkAlreadyExist, ErrorUserAlreadyExist
-		} else {
-			return nil, ErrorUserAlreadyExist
+		if err != nil {
+			return nil, err
 		}
+		return checkAlreadyExist, domain.ErrorUserAlreadyExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create user: %w", err)
 	}
 
 	userToReturn := make([]models.User, 0, 1)
@@ -69,7 +65,7 @@ func (a *UserPostgresRepo) Create(ctx context.Context, user *models.User) (*[]mo
 func (a *UserPostgresRepo) Update(ctx context.Context, nickname string, updateData *models.UserUpdate) (*models.User, error) {
 	user, err := a.Get(ctx, nickname)
 	if err != nil {
-		return nil, ErrorUserDoesNotExist
+		return nil, err
 	}
 
 	if updateData.Fullname == "" {
@@ -90,8 +86,11 @@ func (a *UserPostgresRepo) Update(ctx context.Context, nickname string, updateDa
 
 	_, err = a.Db.Exec(ctx, UpdateUserCommand, updateData.Fullname, updateData.About, updateData.Email, nickname)
 
+	if isUniqueViolation(err) {
+		return nil, domain.ErrorConflictUpdateUser.Wrap(err)
+	}
 	if err != nil {
-		return nil, ErrorConflictUpdateUser
+		return nil, fmt.Errorf("update user: %w", err)
 	}
 
 	return user, nil
@@ -105,7 +104,7 @@ func (a *UserPostgresRepo) Get(ctx context.Context, nicknameOrEmail string) (*mo
 	}
 
 	if err != nil {
-		return nil, ErrorUserDoesNotExist
+		return nil, noRows(err, domain.ErrorUserDoesNotExist)
 	}
 
 	return &user, nil
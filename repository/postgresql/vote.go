@@ -2,6 +2,10 @@ package postgresql
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"strconv"
 	"technopark-db-semester-project/domain"
@@ -12,6 +16,24 @@ const (
 	GetVoteByNicknameAndThreadCommand = "SELECT nickname, thread, voice FROM Votes WHERE nickname = $1 AND thread = $2;"
 	CreateVoteCommand                 = "INSERT INTO Votes (nickname, thread, voice) VALUES ($1, $2, $3);"
 	UpdateVoteCommand                 = "UPDATE Votes SET voice = $1 WHERE nickname = $2 AND thread = $3 AND voice != $1;"
+	DeleteVoteCommand                 = "DELETE FROM Votes WHERE nickname = $1 AND thread = $2 RETURNING voice;"
+	GetThreadVotesCountCommand        = "SELECT votes FROM Threads WHERE id = $1;"
+
+	GetThreadVotesCommand                 = "SELECT nickname, thread, voice FROM Votes WHERE thread = $1 AND ($2::integer = 0 OR voice = $2) AND nickname > $3 ORDER BY nickname LIMIT $4;"
+	GetThreadVotesDescCommand             = "SELECT nickname, thread, voice FROM Votes WHERE thread = $1 AND ($2::integer = 0 OR voice = $2) AND nickname < $3 ORDER BY nickname DESC LIMIT $4;"
+	GetThreadVotesWithoutSinceCommand     = "SELECT nickname, thread, voice FROM Votes WHERE thread = $1 AND ($2::integer = 0 OR voice = $2) ORDER BY nickname LIMIT $3;"
+	GetThreadVotesWithoutSinceDescCommand = "SELECT nickname, thread, voice FROM Votes WHERE thread = $1 AND ($2::integer = 0 OR voice = $2) ORDER BY nickname DESC LIMIT $3;"
+	GetUserVotesCommand                   = "SELECT nickname, thread, voice FROM Votes WHERE nickname = $1 AND thread > $2 ORDER BY thread LIMIT $3;"
+	GetUserVotesDescCommand               = "SELECT nickname, thread, voice FROM Votes WHERE nickname = $1 AND ($2::integer = 0 OR thread < $2) ORDER BY thread DESC LIMIT $3;"
+
+	GetPostVoteTargetCommand = "SELECT p.id, p.parent, p.author, p.message, p.isEdited, p.forum, p.thread, p.created, p.isDeleted, p.votes, t.state FROM Posts p JOIN Threads t ON t.id = p.thread WHERE p.id = $1;"
+	UpsertPostVoteCommand    = "INSERT INTO PostVotes (post, nickname, voice) VALUES ($1, $2, $3) ON CONFLICT (post, nickname) DO UPDATE SET voice = excluded.voice WHERE PostVotes.voice != excluded.voice;"
+	DeletePostVoteCommand    = "DELETE FROM PostVotes WHERE post = $1 AND nickname = $2;"
+	GetPostVotesCommand      = "SELECT votes FROM Posts WHERE id = $1;"
+
+	VotesNicknameForeignKey     = "votes_nickname_fkey"
+	VotesThreadForeignKey       = "votes_thread_fkey"
+	PostVotesNicknameForeignKey = "postvotes_nickname_fkey"
 )
 
 type VotePostgresRepo struct {
@@ -22,33 +44,176 @@ func NewVotePostgresRepo(db *pgxpool.Pool) domain.VoteRepo {
 	return &VotePostgresRepo{Db: db}
 }
 
+// Create возвращает ветку с рейтингом, перечитанным после записи: его меняют триггеры, а параллельные голоса
+// делают расчёт от прочитанного до записи значения неверным
 func (a *VotePostgresRepo) Create(ctx context.Context, threadSlugOrId string, vote *models.VoteCreate) (*models.Thread, error) {
-	var thread models.Thread
-	id, err := strconv.Atoi(threadSlugOrId)
+	tx, err := a.Db.Begin(ctx)
 	if err != nil {
-		err = a.Db.QueryRow(ctx, GetThreadBySlugCommand, threadSlugOrId).Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &thread.Slug, &thread.Created)
+		return nil, fmt.Errorf("begin create vote: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	thread, err := shareThread(ctx, tx, threadSlugOrId)
+	if err != nil {
+		return nil, err
+	}
+	if err = domain.CheckWritable(thread.State, true); err != nil {
+		return nil, err
+	}
+	if err = checkNotBanned(ctx, tx, thread.Forum, []string{vote.Nickname}); err != nil {
+		return nil, err
+	}
+
+	if *vote.Voice == 0 {
+		// отзыв голоса: строку удаляем, счётчик ветки уменьшает триггер delete_thread_vote
+		_, err = tx.Exec(ctx, DeleteVoteCommand, vote.Nickname, thread.Id)
+		if err != nil {
+			return nil, fmt.Errorf("delete vote: %w", err)
+		}
 	} else {
-		err = a.Db.QueryRow(ctx, GetThreadByIdCommand, id).Scan(&thread.Id, &thread.Title, &thread.Author, &thread.Forum, &thread.Message, &thread.Votes, &thread.Slug, &thread.Created)
+		var checkVote models.Vote
+		err = tx.QueryRow(ctx, GetVoteByNicknameAndThreadCommand, vote.Nickname, thread.Id).Scan(&checkVote.Nickname, &checkVote.Thread, &checkVote.Voice)
+		if err != nil {
+			if _, err = tx.Exec(ctx, CreateVoteCommand, vote.Nickname, thread.Id, *vote.Voice); err != nil {
+				return nil, classifyCreateVoteError(err)
+			}
+		} else if _, err = tx.Exec(ctx, UpdateVoteCommand, *vote.Voice, vote.Nickname, thread.Id); err != nil {
+			return nil, fmt.Errorf("update vote: %w", err)
+		}
+	}
+
+	if err = tx.QueryRow(ctx, GetThreadVotesCountCommand, thread.Id).Scan(&thread.Votes); err != nil {
+		return nil, fmt.Errorf("get thread votes: %w", err)
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit create vote: %w", err)
+	}
+
+	return thread, nil
+}
+
+// classifyCreateVoteError переводит нарушение внешнего ключа при вставке голоса в ошибку репозитория, остальные ошибки оборачивает
+func classifyCreateVoteError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == ForeignKeyViolationCode {
+		switch pgErr.ConstraintName {
+		case VotesNicknameForeignKey:
+			return domain.ErrorUserDoesNotExist
+		case VotesThreadForeignKey:
+			return domain.ErrorThreadDoesNotExist
+		}
 	}
 
+	return fmt.Errorf("create vote: %w", err)
+}
+
+func (a *VotePostgresRepo) CreateForPost(ctx context.Context, postId int64, vote *models.VoteCreate) (*models.Post, error) {
+	var post models.Post
+	var state string
+	err := a.Db.QueryRow(ctx, GetPostVoteTargetCommand, postId).Scan(&post.Id, &post.Parent, &post.Author, &post.Message, &post.IsEdited, &post.Forum, &post.Thread, &post.Created, &post.IsDeleted, &post.Votes, &state)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrorPostDoesNotExist
+	}
 	if err != nil {
-		return nil, ErrorThreadDoesNotExist
+		return nil, fmt.Errorf("get post: %w", err)
+	}
+	if post.IsDeleted {
+		return nil, domain.ErrorPostIsDeleted
+	}
+	if err = domain.CheckWritable(state, true); err != nil {
+		return nil, err
+	}
+	if err = checkNotBanned(ctx, a.Db, post.Forum, []string{vote.Nickname}); err != nil {
+		return nil, err
 	}
 
-	var checkVote models.Vote
-	err = a.Db.QueryRow(ctx, GetVoteByNicknameAndThreadCommand, vote.Nickname, thread.Id).Scan(&checkVote.Nickname, &checkVote.Thread, &checkVote.Voice)
+	// счётчик Posts.votes меняют триггеры, поэтому после изменения перечитываем его
+	if *vote.Voice == 0 {
+		_, err = a.Db.Exec(ctx, DeletePostVoteCommand, post.Id, vote.Nickname)
+	} else {
+		_, err = a.Db.Exec(ctx, UpsertPostVoteCommand, post.Id, vote.Nickname, *vote.Voice)
+	}
 	if err != nil {
-		_, err = a.Db.Exec(ctx, CreateVoteCommand, vote.Nickname, thread.Id, vote.Voice)
-		if err != nil {
-			return nil, ErrorUserDoesNotExist
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == ForeignKeyViolationCode {
+			if pgErr.ConstraintName == PostVotesNicknameForeignKey {
+				return nil, domain.ErrorUserDoesNotExist
+			}
+			return nil, domain.ErrorPostDoesNotExist
 		}
-		thread.Votes += vote.Voice
+		return nil, fmt.Errorf("create post vote: %w", err)
+	}
+	if err = a.Db.QueryRow(ctx, GetPostVotesCommand, post.Id).Scan(&post.Votes); err != nil {
+		return nil, fmt.Errorf("get post votes: %w", err)
+	}
+
+	return &post, nil
+}
+
+func scanVotes(rows pgx.Rows) (*[]models.Vote, error) {
+	defer rows.Close()
+
+	votes := make([]models.Vote, 0)
+	for rows.Next() {
+		vote := models.Vote{}
+		if err := rows.Scan(&vote.Nickname, &vote.Thread, &vote.Voice); err != nil {
+			return nil, err
+		}
+		votes = append(votes, vote)
+	}
+
+	return &votes, rows.Err()
+}
+
+func (a *VotePostgresRepo) GetByThread(ctx context.Context, threadSlugOrId string, getSettings *models.GetThreadVotes) (*[]models.Vote, error) {
+	var threadId int32
+	id, err := strconv.Atoi(threadSlugOrId)
+	if err != nil {
+		err = a.Db.QueryRow(ctx, GetThreadIdBySlugCommand, threadSlugOrId).Scan(&threadId)
 	} else {
-		_, _ = a.Db.Exec(ctx, UpdateVoteCommand, vote.Voice, vote.Nickname, thread.Id)
-		if vote.Voice != checkVote.Voice {
-			thread.Votes += 2 * vote.Voice
+		err = a.Db.QueryRow(ctx, GetThreadIdByIdCommand, id).Scan(&threadId)
+	}
+	if err != nil {
+		return nil, noRows(err, domain.ErrorThreadDoesNotExist)
+	}
+
+	var rows pgx.Rows
+	if getSettings.Desc {
+		if getSettings.Since != "" {
+			rows, err = a.Db.Query(ctx, GetThreadVotesDescCommand, threadId, getSettings.Voice, getSettings.Since, getSettings.Limit)
+		} else {
+			rows, err = a.Db.Query(ctx, GetThreadVotesWithoutSinceDescCommand, threadId, getSettings.Voice, getSettings.Limit)
+		}
+	} else {
+		if getSettings.Since != "" {
+			rows, err = a.Db.Query(ctx, GetThreadVotesCommand, threadId, getSettings.Voice, getSettings.Since, getSettings.Limit)
+		} else {
+			rows, err = a.Db.Query(ctx, GetThreadVotesWithoutSinceCommand, threadId, getSettings.Voice, getSettings.Limit)
 		}
 	}
+	if err != nil {
+		return nil, fmt.Errorf("get thread votes: %w", err)
+	}
+
+	return scanVotes(rows)
+}
+
+func (a *VotePostgresRepo) GetByUser(ctx context.Context, nickname string, getSettings *models.GetUserVotes) (*[]models.Vote, error) {
+	var user models.User
+	err := a.Db.QueryRow(ctx, GetUserByNicknameCommand, nickname).Scan(&user.Nickname, &user.Fullname, &user.About, &user.Email)
+	if err != nil {
+		return nil, noRows(err, domain.ErrorUserDoesNotExist)
+	}
+
+	var rows pgx.Rows
+	if getSettings.Desc {
+		rows, err = a.Db.Query(ctx, GetUserVotesDescCommand, user.Nickname, getSettings.Since, getSettings.Limit)
+	} else {
+		rows, err = a.Db.Query(ctx, GetUserVotesCommand, user.Nickname, getSettings.Since, getSettings.Limit)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get user votes: %w", err)
+	}
 
-	return &thread, nil
+	return scanVotes(rows)
 }
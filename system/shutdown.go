@@ -0,0 +1,27 @@
+package system
+
+import (
+	"errors"
+	"github.com/valyala/fasthttp"
+	"time"
+)
+
+var ErrorShutdownTimeout = errors.New("shutdown timeout exceeded, some requests were not finished")
+
+// ShutdownServer перестаёт принимать новые соединения и ждёт завершения текущих запросов не дольше timeout
+func ShutdownServer(server *fasthttp.Server, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Shutdown()
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return ErrorShutdownTimeout
+	}
+}